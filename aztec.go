@@ -0,0 +1,267 @@
+package qrverify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+
+	"github.com/boombuler/barcode"
+	bcaztec "github.com/boombuler/barcode/aztec"
+	"github.com/makiuchi-d/gozxing/aztec"
+)
+
+// Aztec symbol limits (ISO/IEC 24778).
+const (
+	aztecMaxCompactLayers = 4
+	aztecMaxLayers        = 32
+	aztecDefaultECPercent = 33
+)
+
+// aztecQuietZone is the margin in modules around an Aztec symbol. Aztec
+// needs no quiet zone, as readers locate its central bullseye, but one
+// module keeps the outer layer clear of the image edge.
+const aztecQuietZone = 1
+
+// AztecFormat selects compact or full-range Aztec symbols.
+type AztecFormat int
+
+const (
+	AztecAuto      AztecFormat = iota // Smallest symbol of either format
+	AztecCompact                      // Compact symbols, 1 to 4 layers
+	AztecFullRange                    // Full-range symbols, 1 to 32 layers
+)
+
+// String returns the format name.
+func (f AztecFormat) String() string {
+	switch f {
+	case AztecAuto:
+		return "Auto"
+	case AztecCompact:
+		return "Compact"
+	case AztecFullRange:
+		return "FullRange"
+	default:
+		return "AztecFormat(unknown)"
+	}
+}
+
+// AztecOptions configures Aztec generation.
+// Zero values provide sensible defaults.
+type AztecOptions struct {
+	// Format restricts the symbol format.
+	// Zero value picks the smallest symbol of either format.
+	Format AztecFormat
+
+	// Layers selects a fixed number of data layers: 1 to 4 for compact
+	// symbols, 1 to 32 for full-range symbols. It requires Format to be
+	// AztecCompact or AztecFullRange. Data that does not fit is an error.
+	// Zero value picks the fewest layers that fit.
+	Layers int
+
+	// ECPercent is the minimum share of the symbol, in percent, used for
+	// error correction codewords, from 1 to 95.
+	// Zero value uses 33, the recommended default.
+	ECPercent int
+
+	// Size is the image width and height in pixels.
+	// Zero value uses 256.
+	Size int
+
+	// VerifyOutput additionally decodes the final PNG bytes.
+	VerifyOutput bool
+}
+
+// EncodeAztec generates a verified Aztec PNG image. Verification decodes
+// the symbol with an Aztec reader and compares the text byte for byte, as
+// Verify does for QR codes. Result.Symbol reports the symbol size, format
+// and layer count; Result.Recovery does not apply, see
+// AztecOptions.ECPercent.
+func EncodeAztec(data string, opts *AztecOptions) (*Result, error) {
+	return EncodeAztecContext(context.Background(), data, opts)
+}
+
+// EncodeAztecContext is like EncodeAztec but honors cancellation and
+// deadlines of ctx.
+func EncodeAztecContext(ctx context.Context, data string, opts *AztecOptions) (*Result, error) {
+	if opts == nil {
+		opts = &AztecOptions{}
+	}
+	size := 256
+	if opts.Size > 0 {
+		size = opts.Size
+	}
+
+	if err := canceled(ctx, "render"); err != nil {
+		return nil, err
+	}
+	modules, info, err := aztecModules(data, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	png, err := encodeSymbol(ctx, data, modules, aztecSymbology, size, size, opts.VerifyOutput, encodeHooks{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Image:  png,
+		Data:   data,
+		Size:   size,
+		Symbol: info,
+	}, nil
+}
+
+// VerifyAztec checks that qrImage (PNG bytes) holds an Aztec symbol that
+// decodes to expectedData.
+func VerifyAztec(qrImage []byte, expectedData string) error {
+	img, err := png.Decode(bytes.NewReader(qrImage))
+	if err != nil {
+		return fmt.Errorf("failed to decode PNG: %w", err)
+	}
+	return verifySymbol(context.Background(), img, expectedData, aztecSymbology, nil)
+}
+
+// aztecSymbology reads Aztec symbols. The reader returns binary data as
+// ISO-8859-1 text, which is mapped back to the original bytes so UTF-8
+// text compares byte for byte.
+var aztecSymbology = symbology{
+	name: "Aztec code",
+	decode: func(img image.Image) (string, error) {
+		result, err := readBarcode(img, aztec.NewAztecReader(), nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode Aztec code: %w", err)
+		}
+		return latin1Bytes(result.GetText()), nil
+	},
+}
+
+// latin1Bytes returns the bytes of text decoded as ISO-8859-1. Text with
+// characters beyond U+00FF is returned unchanged.
+func latin1Bytes(text string) string {
+	b := make([]byte, 0, len(text))
+	for _, r := range text {
+		if r > 0xff {
+			return text
+		}
+		b = append(b, byte(r))
+	}
+	return string(b)
+}
+
+// aztecModules encodes data as an Aztec symbol at one pixel per module,
+// surrounded by the quiet zone.
+func aztecModules(data string, opts *AztecOptions) (*image.Gray, *SymbolInfo, error) {
+	ecPercent := aztecDefaultECPercent
+	if opts.ECPercent != 0 {
+		if opts.ECPercent < 1 || opts.ECPercent > 95 {
+			return nil, nil, fmt.Errorf("failed to create Aztec code: error correction %d%% out of range 1-95", opts.ECPercent)
+		}
+		ecPercent = opts.ECPercent
+	}
+
+	// Candidate layer settings in the encoder's convention: zero picks the
+	// smallest symbol, negative values are compact layers.
+	var candidates []int
+	switch opts.Format {
+	case AztecAuto:
+		if opts.Layers != 0 {
+			return nil, nil, fmt.Errorf("failed to create Aztec code: %d layers require a compact or full-range format", opts.Layers)
+		}
+		candidates = []int{0}
+	case AztecCompact, AztecFullRange:
+		maxLayers, sign := aztecMaxLayers, 1
+		if opts.Format == AztecCompact {
+			maxLayers, sign = aztecMaxCompactLayers, -1
+		}
+		if opts.Layers < 0 || opts.Layers > maxLayers {
+			return nil, nil, fmt.Errorf("failed to create Aztec code: %v symbols have 1 to %d layers, not %d", opts.Format, maxLayers, opts.Layers)
+		}
+		if opts.Layers != 0 {
+			candidates = []int{sign * opts.Layers}
+		} else {
+			for l := 1; l <= maxLayers; l++ {
+				candidates = append(candidates, sign*l)
+			}
+		}
+	default:
+		return nil, nil, fmt.Errorf("failed to create Aztec code: unknown format %v", opts.Format)
+	}
+
+	var (
+		bc     barcode.Barcode
+		layers int
+		err    error
+	)
+	for _, layers = range candidates {
+		bc, err = bcaztec.Encode([]byte(data), ecPercent, layers)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		target := "largest"
+		if opts.Layers != 0 {
+			target = fmt.Sprintf("%d layer", opts.Layers)
+		}
+		return nil, nil, fmt.Errorf("data too large: %d bytes exceed the %s %v Aztec code at %d%% error correction",
+			len(data), target, opts.Format, ecPercent)
+	}
+
+	n := bc.Bounds().Dx()
+	compact := layers < 0
+	if layers == 0 {
+		compact, layers = aztecFormatOf(n)
+	}
+	if layers < 0 {
+		layers = -layers
+	}
+
+	img := image.NewGray(image.Rect(0, 0, n+2*aztecQuietZone, n+2*aztecQuietZone))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if r, _, _, _ := bc.At(x, y).RGBA(); r == 0 {
+				img.Pix[(y+aztecQuietZone)*img.Stride+x+aztecQuietZone] = 0
+			}
+		}
+	}
+
+	return img, &SymbolInfo{
+		Format:  "Aztec",
+		Rows:    n,
+		Columns: n,
+		Layers:  layers,
+		Compact: compact,
+	}, nil
+}
+
+// aztecFormatOf returns the format and layer count of an automatically
+// chosen symbol of n x n modules. The encoder prefers compact symbols up
+// to 4 layers and then full-range symbols from 4 layers, so the sizes are
+// unambiguous.
+func aztecFormatOf(n int) (compact bool, layers int) {
+	if l := (n - 11) / 4; l <= aztecMaxCompactLayers && aztecSize(l, true) == n {
+		return true, l
+	}
+	for l := aztecMaxCompactLayers; l <= aztecMaxLayers; l++ {
+		if aztecSize(l, false) == n {
+			return false, l
+		}
+	}
+	return false, 0
+}
+
+// aztecSize returns the width in modules of a symbol with the given layers,
+// including the reference grid lines of full-range symbols.
+func aztecSize(layers int, compact bool) int {
+	if compact {
+		return 11 + 4*layers
+	}
+	base := 14 + 4*layers
+	return base + 1 + 2*((base/2-1)/15)
+}
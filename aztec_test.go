@@ -0,0 +1,104 @@
+package qrverify
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncodeAztec(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		opts    *AztecOptions
+		size    int
+		layers  int
+		compact bool
+	}{
+		{"short", "TICKET-0001", nil, 15, 1, true},
+		{"compact largest", strings.Repeat("7", 80), nil, 27, 4, true},
+		{"full range", strings.Repeat("ZONE 1-3 ADULT ", 20), nil, 53, 9, false},
+		{"binary", "Fahrkarte Zürich → Genève 🚆", nil, 23, 3, true},
+		{"forced compact", "A", &AztecOptions{Format: AztecCompact}, 15, 1, true},
+		{"forced full range", "A", &AztecOptions{Format: AztecFullRange}, 19, 1, false},
+		{"fixed layers", "A", &AztecOptions{Format: AztecFullRange, Layers: 6}, 41, 6, false},
+		{"high error correction", strings.Repeat("7", 80), &AztecOptions{ECPercent: 95}, 31, 4, false},
+		{"verify output", "Platform 9", &AztecOptions{VerifyOutput: true, Size: 300}, 15, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := EncodeAztec(tt.data, tt.opts)
+			if err != nil {
+				t.Fatalf("EncodeAztec failed: %v", err)
+			}
+			want := SymbolInfo{Format: "Aztec", Rows: tt.size, Columns: tt.size, Layers: tt.layers, Compact: tt.compact}
+			if result.Symbol == nil || *result.Symbol != want {
+				t.Errorf("Symbol = %+v, want %+v", result.Symbol, want)
+			}
+			if result.Data != tt.data {
+				t.Errorf("Data = %q, want %q", result.Data, tt.data)
+			}
+			if err := VerifyAztec(result.Image, tt.data); err != nil {
+				t.Errorf("VerifyAztec failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestEncodeAztecInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		opts *AztecOptions
+	}{
+		{"layers without format", "A", &AztecOptions{Layers: 2}},
+		{"compact layers", "A", &AztecOptions{Format: AztecCompact, Layers: 5}},
+		{"full range layers", "A", &AztecOptions{Format: AztecFullRange, Layers: 33}},
+		{"negative layers", "A", &AztecOptions{Format: AztecCompact, Layers: -1}},
+		{"error correction", "A", &AztecOptions{ECPercent: 96}},
+		{"format", "A", &AztecOptions{Format: AztecFormat(9)}},
+		{"too large", strings.Repeat("x", 4000), nil},
+		{"too large for compact", strings.Repeat("x", 100), &AztecOptions{Format: AztecCompact}},
+		{"too large for layers", strings.Repeat("x", 100), &AztecOptions{Format: AztecFullRange, Layers: 2}},
+	}
+	for _, tt := range tests {
+		if _, err := EncodeAztec(tt.data, tt.opts); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestVerifyAztecMismatch(t *testing.T) {
+	result, err := EncodeAztec("ADULT", nil)
+	if err != nil {
+		t.Fatalf("EncodeAztec failed: %v", err)
+	}
+	var verr *VerificationError
+	if err := VerifyAztec(result.Image, "CHILD"); !errors.As(err, &verr) {
+		t.Errorf("VerifyAztec() = %v, want *VerificationError", err)
+	}
+
+	qr, err := Encode("ADULT", nil)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := VerifyAztec(qr, "ADULT"); err == nil {
+		t.Error("Expected error verifying a QR code as Aztec")
+	}
+}
+
+func TestAztecFormatOf(t *testing.T) {
+	for l := 1; l <= aztecMaxCompactLayers; l++ {
+		if compact, layers := aztecFormatOf(aztecSize(l, true)); !compact || layers != l {
+			t.Errorf("aztecFormatOf(compact %d) = %v, %d", l, compact, layers)
+		}
+	}
+	for l := aztecMaxCompactLayers; l <= aztecMaxLayers; l++ {
+		if compact, layers := aztecFormatOf(aztecSize(l, false)); compact || layers != l {
+			t.Errorf("aztecFormatOf(full %d) = %v, %d", l, compact, layers)
+		}
+	}
+	if got := aztecSize(32, false); got != 151 {
+		t.Errorf("aztecSize(32) = %d, want 151", got)
+	}
+}
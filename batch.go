@@ -0,0 +1,176 @@
+package qrverify
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// BatchItem is a single input to EncodeBatch or EncodeStream.
+type BatchItem struct {
+	Data string         // Data to encode
+	Opts *EncodeOptions // Per-item options, nil uses defaults
+}
+
+// BatchResult is the outcome of encoding a single BatchItem.
+// Exactly one of Result and Err is non-nil.
+type BatchResult struct {
+	Index  int     // Position of the item in the input
+	Result *Result // Verified QR code on success
+	Err    error   // Encode or verification error on failure
+}
+
+// BatchOptions configures EncodeBatch and EncodeStream.
+// Zero values provide sensible defaults.
+type BatchOptions struct {
+	// Workers is the number of concurrent encoders.
+	// Zero value uses runtime.GOMAXPROCS(0).
+	Workers int
+
+	// Progress, if set, is called after each item completes with the number
+	// of completed items and the total. Total is zero when unknown (EncodeStream).
+	// Calls are serialized and happen in completion order.
+	Progress func(done, total int)
+}
+
+// BatchError reports the items that failed in a batch.
+type BatchError struct {
+	Failed []BatchResult // Failed items in input order
+	Total  int           // Number of items in the batch
+}
+
+// Error returns a summary without exposing data content.
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch encode: %d of %d items failed", len(e.Failed), e.Total)
+}
+
+// Unwrap returns the per-item errors for use with errors.Is and errors.As.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, r := range e.Failed {
+		errs[i] = r.Err
+	}
+	return errs
+}
+
+// workers returns the worker pool size for opts.
+func (o *BatchOptions) workers() int {
+	if o != nil && o.Workers > 0 {
+		return o.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// EncodeBatch encodes and verifies items across a bounded worker pool.
+// Results are returned in input order. A failing item does not abort the
+// batch; if any item fails, the returned error is a *BatchError.
+// Items not started before ctx is done fail with an error wrapping ctx.Err().
+func EncodeBatch(ctx context.Context, items []BatchItem, opts *BatchOptions) ([]BatchResult, error) {
+	in := make(chan BatchItem)
+	go func() {
+		defer close(in)
+		for _, item := range items {
+			in <- item
+		}
+	}()
+
+	results := make([]BatchResult, 0, len(items))
+	var failed []BatchResult
+	for r := range encodeStream(ctx, in, len(items), opts) {
+		results = append(results, r)
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+
+	if len(failed) > 0 {
+		return results, &BatchError{Failed: failed, Total: len(items)}
+	}
+	return results, nil
+}
+
+// EncodeStream encodes and verifies items received from in across a bounded
+// worker pool. Results are sent in input order on the returned channel, which
+// is closed after in is closed and all items are processed. Per-item errors
+// are reported in BatchResult.Err. The caller must drain the returned channel.
+func EncodeStream(ctx context.Context, in <-chan BatchItem, opts *BatchOptions) <-chan BatchResult {
+	return encodeStream(ctx, in, 0, opts)
+}
+
+// indexedItem pairs a BatchItem with its input position.
+type indexedItem struct {
+	index int
+	item  BatchItem
+}
+
+// encodeStream implements EncodeBatch and EncodeStream. total is only used
+// for progress reporting.
+func encodeStream(ctx context.Context, in <-chan BatchItem, total int, opts *BatchOptions) <-chan BatchResult {
+	n := opts.workers()
+	var progress func(done, total int)
+	if opts != nil {
+		progress = opts.Progress
+	}
+
+	// window bounds the number of items dispatched but not yet emitted,
+	// which bounds memory used for reordering.
+	window := make(chan struct{}, 2*n)
+	jobs := make(chan indexedItem)
+	done := make(chan BatchResult)
+	out := make(chan BatchResult)
+
+	// Dispatcher
+	go func() {
+		defer close(jobs)
+		i := 0
+		for item := range in {
+			window <- struct{}{}
+			jobs <- indexedItem{index: i, item: item}
+			i++
+		}
+	}()
+
+	// Workers
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				result, err := EncodeDetailedContext(ctx, job.item.Data, job.item.Opts)
+				done <- BatchResult{Index: job.index, Result: result, Err: err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Collector restores input order
+	go func() {
+		defer close(out)
+		pending := make(map[int]BatchResult)
+		next, completed := 0, 0
+		for r := range done {
+			completed++
+			if progress != nil {
+				progress(completed, total)
+			}
+			pending[r.Index] = r
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				out <- r
+				<-window
+				next++
+			}
+		}
+	}()
+
+	return out
+}
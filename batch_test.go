@@ -0,0 +1,140 @@
+package qrverify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEncodeBatch(t *testing.T) {
+	items := make([]BatchItem, 20)
+	for i := range items {
+		items[i] = BatchItem{Data: fmt.Sprintf("ticket-%03d", i)}
+	}
+	items[3].Opts = &EncodeOptions{Recovery: High, Size: 128}
+
+	var mu sync.Mutex
+	var calls []int
+	opts := &BatchOptions{
+		Workers: 4,
+		Progress: func(done, total int) {
+			mu.Lock()
+			defer mu.Unlock()
+			if total != len(items) {
+				t.Errorf("Progress total = %d, want %d", total, len(items))
+			}
+			calls = append(calls, done)
+		},
+	}
+
+	results, err := EncodeBatch(context.Background(), items, opts)
+	if err != nil {
+		t.Fatalf("EncodeBatch failed: %v", err)
+	}
+
+	if len(results) != len(items) {
+		t.Fatalf("Expected %d results, got %d", len(items), len(results))
+	}
+
+	for i, r := range results {
+		if r.Index != i {
+			t.Errorf("results[%d].Index = %d", i, r.Index)
+		}
+		if r.Err != nil {
+			t.Errorf("results[%d].Err = %v", i, r.Err)
+			continue
+		}
+		if r.Result.Data != items[i].Data {
+			t.Errorf("results[%d].Data = %q, want %q", i, r.Result.Data, items[i].Data)
+		}
+		if err := Verify(r.Result.Image, items[i].Data); err != nil {
+			t.Errorf("Verification of results[%d] failed: %v", i, err)
+		}
+	}
+
+	if results[3].Result.Recovery != High || results[3].Result.Size != 128 {
+		t.Errorf("Per-item options not applied: %+v", results[3].Result)
+	}
+
+	if len(calls) != len(items) || calls[len(calls)-1] != len(items) {
+		t.Errorf("Expected %d progress calls ending at %d, got %v", len(items), len(items), calls)
+	}
+}
+
+func TestEncodeBatchItemErrors(t *testing.T) {
+	items := []BatchItem{
+		{Data: "ok-1"},
+		{Data: strings.Repeat("x", MaxBytesLow+1), Opts: &EncodeOptions{Recovery: Low}},
+		{Data: "ok-2"},
+	}
+
+	results, err := EncodeBatch(context.Background(), items, &BatchOptions{Workers: 2})
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected BatchError, got: %v", err)
+	}
+	if len(batchErr.Failed) != 1 || batchErr.Failed[0].Index != 1 || batchErr.Total != 3 {
+		t.Errorf("Unexpected BatchError: %+v", batchErr)
+	}
+
+	if results[0].Err != nil || results[2].Err != nil {
+		t.Errorf("Valid items should not fail: %v, %v", results[0].Err, results[2].Err)
+	}
+	if results[1].Err == nil || results[1].Result != nil {
+		t.Errorf("Oversized item should fail: %+v", results[1])
+	}
+}
+
+func TestEncodeBatchCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	items := []BatchItem{{Data: "a"}, {Data: "b"}}
+	results, err := EncodeBatch(ctx, items, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got: %v", err)
+	}
+	if len(results) != len(items) {
+		t.Errorf("Expected %d results, got %d", len(items), len(results))
+	}
+}
+
+func TestEncodeStream(t *testing.T) {
+	in := make(chan BatchItem)
+	go func() {
+		defer close(in)
+		for i := 0; i < 10; i++ {
+			in <- BatchItem{Data: fmt.Sprintf("stream-%d", i)}
+		}
+	}()
+
+	i := 0
+	for r := range EncodeStream(context.Background(), in, &BatchOptions{Workers: 3}) {
+		if r.Index != i {
+			t.Errorf("Out of order result: got index %d, want %d", r.Index, i)
+		}
+		if r.Err != nil {
+			t.Errorf("Item %d failed: %v", r.Index, r.Err)
+		}
+		i++
+	}
+	if i != 10 {
+		t.Errorf("Expected 10 results, got %d", i)
+	}
+}
+
+func BenchmarkEncodeBatch(b *testing.B) {
+	items := make([]BatchItem, 64)
+	for i := range items {
+		items[i] = BatchItem{Data: fmt.Sprintf("benchmark-%d", i)}
+	}
+	for i := 0; i < b.N; i++ {
+		if _, err := EncodeBatch(context.Background(), items, nil); err != nil {
+			b.Fatalf("EncodeBatch failed: %v", err)
+		}
+	}
+}
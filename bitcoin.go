@@ -0,0 +1,309 @@
+package qrverify
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MaxSatoshis is the Bitcoin supply limit in satoshis.
+const MaxSatoshis = 21_000_000 * 100_000_000
+
+// BitcoinPayment is a Bitcoin payment request in the BIP-21 bitcoin: URI
+// format.
+type BitcoinPayment struct {
+	Address    string // Base58Check or bech32/bech32m address, required
+	AmountSats int64  // Amount in satoshis, zero omits the amount
+	Label      string // Recipient name
+	Message    string // Payment description
+}
+
+// Encode returns the bitcoin: URI with the amount in BTC.
+func (p *BitcoinPayment) Encode(limit int) (string, error) {
+	if err := validateBitcoinAddress(p.Address); err != nil {
+		return "", fmt.Errorf("invalid Bitcoin payment: %w", err)
+	}
+	if p.AmountSats < 0 || p.AmountSats > MaxSatoshis {
+		return "", errors.New("invalid Bitcoin payment: amount out of range")
+	}
+
+	var params []string
+	if p.AmountSats > 0 {
+		params = append(params, "amount="+formatSats(p.AmountSats))
+	}
+	if p.Label != "" {
+		params = append(params, "label="+uriEscape(p.Label))
+	}
+	if p.Message != "" {
+		params = append(params, "message="+uriEscape(p.Message))
+	}
+
+	s := "bitcoin:" + p.Address
+	if len(params) > 0 {
+		s += "?" + strings.Join(params, "&")
+	}
+	return checkLimit("bitcoin URI", s, limit)
+}
+
+// Check parses decoded as a bitcoin: URI and compares its fields with p.
+// Bech32 addresses compare case-insensitively.
+func (p *BitcoinPayment) Check(decoded string) error {
+	got, err := ParseBitcoinPayment(decoded)
+	if err != nil {
+		return err
+	}
+
+	fields := []struct {
+		name           string
+		original, read string
+	}{
+		{"BitcoinPayment.Address", canonicalBitcoinAddress(p.Address), canonicalBitcoinAddress(got.Address)},
+		{"BitcoinPayment.AmountSats", strconv.FormatInt(p.AmountSats, 10), strconv.FormatInt(got.AmountSats, 10)},
+		{"BitcoinPayment.Label", p.Label, got.Label},
+		{"BitcoinPayment.Message", p.Message, got.Message},
+	}
+	for _, f := range fields {
+		if err := checkField(f.name, f.original, f.read); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseBitcoinPayment parses a BIP-21 bitcoin: URI. Unknown parameters are
+// ignored unless prefixed with req-, as BIP-21 requires.
+func ParseBitcoinPayment(s string) (*BitcoinPayment, error) {
+	body, ok := cutScheme(s, "bitcoin")
+	if !ok {
+		return nil, errors.New("invalid Bitcoin payment: missing bitcoin: scheme")
+	}
+	address, query, _ := strings.Cut(body, "?")
+
+	p := &BitcoinPayment{Address: address}
+	if err := validateBitcoinAddress(address); err != nil {
+		return nil, fmt.Errorf("invalid Bitcoin payment: %w", err)
+	}
+
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, errors.New("invalid Bitcoin payment: malformed query")
+	}
+	for key := range q {
+		switch key {
+		case "amount", "label", "message":
+		default:
+			if strings.HasPrefix(key, "req-") {
+				return nil, errors.New("invalid Bitcoin payment: unsupported required parameter")
+			}
+		}
+	}
+	if amount := q.Get("amount"); amount != "" {
+		if p.AmountSats, err = parseSats(amount); err != nil {
+			return nil, fmt.Errorf("invalid Bitcoin payment: %w", err)
+		}
+	}
+	p.Label = q.Get("label")
+	p.Message = q.Get("message")
+	return p, nil
+}
+
+// formatSats formats sats as BTC without trailing zeros.
+func formatSats(sats int64) string {
+	s := strconv.FormatInt(sats/100_000_000, 10)
+	if frac := strings.TrimRight(fmt.Sprintf("%08d", sats%100_000_000), "0"); frac != "" {
+		s += "." + frac
+	}
+	return s
+}
+
+// parseSats parses a BTC amount with at most eight decimals into satoshis.
+func parseSats(s string) (int64, error) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if !isDigits(whole) || len(frac) > 8 || (frac != "" && !isDigits(frac)) {
+		return 0, errors.New("malformed amount")
+	}
+	frac += strings.Repeat("0", 8-len(frac))
+	sats, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil || sats > MaxSatoshis {
+		return 0, errors.New("amount out of range")
+	}
+	return sats, nil
+}
+
+// canonicalBitcoinAddress lowercases bech32 addresses, which are
+// case-insensitive, and returns Base58Check addresses unchanged.
+func canonicalBitcoinAddress(addr string) string {
+	if hrp, _, _ := strings.Cut(strings.ToLower(addr), "1"); hrp == "bc" || hrp == "tb" || hrp == "bcrt" {
+		return strings.ToLower(addr)
+	}
+	return addr
+}
+
+// validateBitcoinAddress checks the checksum and format of a mainnet or
+// testnet address: Base58Check P2PKH and P2SH, bech32 segwit v0 and
+// bech32m segwit v1 and later (BIP-173, BIP-350).
+func validateBitcoinAddress(addr string) error {
+	if addr == "" {
+		return errors.New("address is required")
+	}
+	lower := strings.ToLower(addr)
+	if strings.HasPrefix(lower, "bc1") || strings.HasPrefix(lower, "tb1") || strings.HasPrefix(lower, "bcrt1") {
+		return validateSegwitAddress(addr)
+	}
+
+	payload, err := base58CheckDecode(addr)
+	if err != nil {
+		return err
+	}
+	if len(payload) != 21 {
+		return errors.New("address has invalid length")
+	}
+	switch payload[0] {
+	case 0x00, 0x05, 0x6f, 0xc4: // P2PKH and P2SH on mainnet and testnet
+		return nil
+	default:
+		return errors.New("address has unknown version")
+	}
+}
+
+// base58Alphabet is the Bitcoin Base58 alphabet.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58CheckDecode decodes s and verifies its four byte double SHA-256
+// checksum, returning the payload without the checksum.
+func base58CheckDecode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(base58Alphabet, s[i])
+		if d < 0 {
+			return nil, errors.New("address has invalid Base58 character")
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(d)))
+	}
+
+	// Each leading '1' encodes a zero byte
+	zeros := len(s) - len(strings.TrimLeft(s, "1"))
+	b := append(make([]byte, zeros), n.Bytes()...)
+	if len(b) < 5 {
+		return nil, errors.New("address is too short")
+	}
+
+	payload, checksum := b[:len(b)-4], b[len(b)-4:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if string(second[:4]) != string(checksum) {
+		return nil, errors.New("address checksum mismatch")
+	}
+	return payload, nil
+}
+
+// bech32Charset is the bech32 data character set.
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32 checksum constants (BIP-173, BIP-350).
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+// validateSegwitAddress checks a bech32 or bech32m segwit address,
+// including that the checksum variant matches the witness version.
+func validateSegwitAddress(addr string) error {
+	if len(addr) > 90 {
+		return errors.New("address is too long")
+	}
+	if strings.ToLower(addr) != addr && strings.ToUpper(addr) != addr {
+		return errors.New("address has mixed case")
+	}
+	addr = strings.ToLower(addr)
+
+	sep := strings.LastIndexByte(addr, '1')
+	hrp, data := addr[:sep], addr[sep+1:]
+	if hrp != "bc" && hrp != "tb" && hrp != "bcrt" {
+		return errors.New("address has unknown network prefix")
+	}
+	if len(data) < 7 {
+		return errors.New("address is too short")
+	}
+	values := make([]byte, len(data))
+	for i := 0; i < len(data); i++ {
+		d := strings.IndexByte(bech32Charset, data[i])
+		if d < 0 {
+			return errors.New("address has invalid bech32 character")
+		}
+		values[i] = byte(d)
+	}
+
+	version := values[0]
+	want := uint32(bech32Const)
+	if version > 0 {
+		want = bech32mConst
+	}
+	if bech32Polymod(hrp, values) != want {
+		return errors.New("address checksum mismatch")
+	}
+	if version > 16 {
+		return errors.New("address has invalid witness version")
+	}
+
+	program, err := convertBits(values[1:len(values)-6], 5, 8)
+	if err != nil {
+		return err
+	}
+	if len(program) < 2 || len(program) > 40 || (version == 0 && len(program) != 20 && len(program) != 32) {
+		return errors.New("address has invalid witness program length")
+	}
+	return nil
+}
+
+// bech32Polymod returns the bech32 checksum of the expanded hrp and
+// values. It equals the variant constant for a valid string.
+func bech32Polymod(hrp string, values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	step := func(v byte) {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if top>>i&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	for i := 0; i < len(hrp); i++ {
+		step(hrp[i] >> 5)
+	}
+	step(0)
+	for i := 0; i < len(hrp); i++ {
+		step(hrp[i] & 31)
+	}
+	for _, v := range values {
+		step(v)
+	}
+	return chk
+}
+
+// convertBits regroups values of from bits into values of to bits,
+// rejecting non-zero or excess padding.
+func convertBits(values []byte, from, to uint) ([]byte, error) {
+	var out []byte
+	acc, bits := uint32(0), uint(0)
+	for _, v := range values {
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&(1<<to-1)))
+		}
+	}
+	if bits >= from || acc&(1<<bits-1) != 0 {
+		return nil, errors.New("address has invalid padding")
+	}
+	return out, nil
+}
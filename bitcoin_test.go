@@ -0,0 +1,149 @@
+package qrverify
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBitcoinPaymentEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		payment BitcoinPayment
+		want    string
+	}{
+		{
+			name:    "P2PKH address only",
+			payment: BitcoinPayment{Address: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"},
+			want:    "bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+		},
+		{
+			name:    "P2SH with amount",
+			payment: BitcoinPayment{Address: "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", AmountSats: 2_000_000},
+			want:    "bitcoin:3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy?amount=0.02",
+		},
+		{
+			name: "bech32 with label and message",
+			payment: BitcoinPayment{
+				Address:    "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+				AmountSats: 150_000_001,
+				Label:      "Open Source Fund",
+				Message:    "Donation & thanks",
+			},
+			want: "bitcoin:bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4?amount=1.50000001&label=Open%20Source%20Fund&message=Donation%20%26%20thanks",
+		},
+		{
+			name:    "bech32m uppercase",
+			payment: BitcoinPayment{Address: "BC1P0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQZK5JJ0", AmountSats: 100_000_000},
+			want:    "bitcoin:BC1P0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQZK5JJ0?amount=1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.payment.Encode(MaxBytesMedium)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+
+			parsed, err := ParseBitcoinPayment(got)
+			if err != nil {
+				t.Fatalf("ParseBitcoinPayment failed: %v", err)
+			}
+			if *parsed != tt.payment {
+				t.Errorf("ParseBitcoinPayment() = %+v, want %+v", *parsed, tt.payment)
+			}
+		})
+	}
+}
+
+func TestValidateBitcoinAddress(t *testing.T) {
+	valid := []string{
+		"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+		"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+		"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7",
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0",
+		"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs",
+	}
+	for _, addr := range valid {
+		if err := validateBitcoinAddress(addr); err != nil {
+			t.Errorf("validateBitcoinAddress(%q) failed: %v", addr, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN3", // Base58Check checksum
+		"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN0", // Invalid Base58 character
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5",                     // bech32 checksum
+		"bc1Qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",                     // Mixed case
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", // v1 with bech32 checksum
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh",                     // v0 with bech32m checksum
+		"ltc1qw508d6qejxtdg4y5r3zarvary0c5xw7kgmn4n9",                    // Other network
+	}
+	for _, addr := range invalid {
+		if err := validateBitcoinAddress(addr); err == nil {
+			t.Errorf("Expected error for %q", addr)
+		}
+	}
+}
+
+func TestParseBitcoinPayment(t *testing.T) {
+	p, err := ParseBitcoinPayment("BITCOIN:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?amount=20.3&somethingyoudontunderstand=50")
+	if err != nil {
+		t.Fatalf("ParseBitcoinPayment failed: %v", err)
+	}
+	if p.AmountSats != 2_030_000_000 {
+		t.Errorf("AmountSats = %d, want 2030000000", p.AmountSats)
+	}
+
+	invalid := []string{
+		"bitcoin:",
+		"litecoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+		"bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?req-somethingyoudontunderstand=50",
+		"bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?amount=0.000000001",
+		"bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?amount=1e3",
+		"bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?amount=21000001",
+	}
+	for _, s := range invalid {
+		if _, err := ParseBitcoinPayment(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestBitcoinPaymentCheck(t *testing.T) {
+	p := &BitcoinPayment{Address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", AmountSats: 5000}
+
+	if err := p.Check("bitcoin:BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4?amount=0.00005"); err != nil {
+		t.Errorf("Check failed for uppercase address: %v", err)
+	}
+
+	err := p.Check("bitcoin:bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4?amount=0.0005")
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "BitcoinPayment.AmountSats" {
+		t.Fatalf("Expected FieldError for BitcoinPayment.AmountSats, got: %v", err)
+	}
+}
+
+func TestEncodePayloadBitcoin(t *testing.T) {
+	p := &BitcoinPayment{Address: "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", AmountSats: 12345, Label: "Donations"}
+
+	result, err := EncodePayload(p, nil)
+	if err != nil {
+		t.Fatalf("EncodePayload failed: %v", err)
+	}
+	if err := VerifyPayload(result.Image, p); err != nil {
+		t.Errorf("VerifyPayload failed: %v", err)
+	}
+
+	other := *p
+	other.AmountSats = 12346
+	if err := VerifyPayload(result.Image, &other); err == nil || !strings.Contains(err.Error(), "AmountSats") {
+		t.Errorf("Expected AmountSats mismatch, got: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package qrverify
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrCacheMiss is returned by Cache.Get when no image is stored for a key.
+var ErrCacheMiss = errors.New("qrverify: cache miss")
+
+// Cache stores verified QR code PNG images by key.
+// EncodeDetailed only puts images that passed verification.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the image stored for key, or ErrCacheMiss.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Put stores image for key.
+	Put(ctx context.Context, key string, image []byte) error
+}
+
+// cacheKey returns the content-addressed key for data encoded with the
+// normalized recovery and size. variant identifies image decorations.
+func cacheKey(data string, recovery Recovery, size int, variant string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "qrverify/v1\x00%d\x00%d\x00%s\x00", recovery, size, variant)
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// LRUCache is an in-memory Cache that evicts the least recently used
+// image when full.
+type LRUCache struct {
+	mu      sync.Mutex
+	max     int
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+// lruEntry is a key and image stored in LRUCache.
+type lruEntry struct {
+	key   string
+	image []byte
+}
+
+// NewLRUCache returns an LRUCache holding at most maxEntries images.
+// If maxEntries is zero or negative, the cache is unbounded.
+func NewLRUCache(maxEntries int) *LRUCache {
+	return &LRUCache{
+		max:     maxEntries,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the image stored for key, or ErrCacheMiss.
+func (c *LRUCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	c.ll.MoveToFront(e)
+	return bytes.Clone(e.Value.(*lruEntry).image), nil
+}
+
+// Put stores image for key, evicting the least recently used image if full.
+func (c *LRUCache) Put(ctx context.Context, key string, image []byte) error {
+	image = bytes.Clone(image)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.ll.MoveToFront(e)
+		e.Value.(*lruEntry).image = image
+		return nil
+	}
+
+	c.entries[key] = c.ll.PushFront(&lruEntry{key: key, image: image})
+	if c.max > 0 && c.ll.Len() > c.max {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// Len returns the number of cached images.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// DirCache is a Cache that stores images as files in a directory.
+// The directory is created on first Put if it does not exist.
+type DirCache string
+
+// Get returns the image stored for key, or ErrCacheMiss.
+func (d DirCache) Get(ctx context.Context, key string) ([]byte, error) {
+	image, err := os.ReadFile(d.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache file: %w", err)
+	}
+	return image, nil
+}
+
+// Put stores image for key. The file is written atomically so concurrent
+// readers never see a partial image.
+func (d DirCache) Put(ctx context.Context, key string, image []byte) error {
+	if err := os.MkdirAll(string(d), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(string(d), "tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(image); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), d.path(key)); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	return nil
+}
+
+// path returns the file name for key.
+func (d DirCache) path(key string) string {
+	return filepath.Join(string(d), filepath.Base(key)+".png")
+}
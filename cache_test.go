@@ -0,0 +1,177 @@
+package qrverify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheKey(t *testing.T) {
+	base := cacheKey("data", Medium, 256, "")
+
+	if got := cacheKey("data", Medium, 256, ""); got != base {
+		t.Errorf("cacheKey not deterministic: %s != %s", got, base)
+	}
+
+	others := []string{
+		cacheKey("other", Medium, 256, ""),
+		cacheKey("data", High, 256, ""),
+		cacheKey("data", Medium, 512, ""),
+		cacheKey("data", Medium, 256, "decorated"),
+	}
+	for _, k := range others {
+		if k == base {
+			t.Errorf("cacheKey collision for different inputs: %s", k)
+		}
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRUCache(2)
+
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("Expected ErrCacheMiss, got: %v", err)
+	}
+
+	_ = c.Put(ctx, "a", []byte("A"))
+	_ = c.Put(ctx, "b", []byte("B"))
+
+	// Touch a so b becomes least recently used
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Fatalf("Get(a) failed: %v", err)
+	}
+	_ = c.Put(ctx, "c", []byte("C"))
+
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Expected b to be evicted, got: %v", err)
+	}
+
+	img, err := c.Get(ctx, "a")
+	if err != nil || string(img) != "A" {
+		t.Errorf("Get(a) = %q, %v", img, err)
+	}
+
+	// Returned images must not alias cached storage
+	img[0] = 'X'
+	if img, _ := c.Get(ctx, "a"); string(img) != "A" {
+		t.Errorf("Cached image modified through returned slice: %q", img)
+	}
+}
+
+func TestDirCache(t *testing.T) {
+	ctx := context.Background()
+	dir := DirCache(filepath.Join(t.TempDir(), "cache"))
+
+	if _, err := dir.Get(ctx, "k"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("Expected ErrCacheMiss, got: %v", err)
+	}
+
+	if err := dir.Put(ctx, "k", []byte("image")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	img, err := dir.Get(ctx, "k")
+	if err != nil || string(img) != "image" {
+		t.Errorf("Get(k) = %q, %v", img, err)
+	}
+}
+
+func TestEncodeDetailedCache(t *testing.T) {
+	data := "https://example.com/product/123"
+	cache := NewLRUCache(0)
+	opts := &EncodeOptions{Cache: cache}
+
+	first, err := EncodeDetailed(data, opts)
+	if err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+	if first.Cached {
+		t.Error("First encode should not be a cache hit")
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("Expected 1 cached image, got %d", cache.Len())
+	}
+
+	second, err := EncodeDetailed(data, opts)
+	if err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+	if !second.Cached {
+		t.Error("Second encode should be a cache hit")
+	}
+	if !bytes.Equal(first.Image, second.Image) {
+		t.Error("Cached image differs from encoded image")
+	}
+
+	// Different options must not share an entry
+	third, err := EncodeDetailed(data, &EncodeOptions{Cache: cache, Size: 512})
+	if err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+	if third.Cached || third.Size != 512 {
+		t.Errorf("Unexpected result for different size: Cached=%v Size=%d", third.Cached, third.Size)
+	}
+}
+
+func TestEncodeDetailedVerifyCached(t *testing.T) {
+	data := "integrity check"
+	dir := DirCache(t.TempDir())
+	opts := &EncodeOptions{Cache: dir, VerifyCached: true}
+
+	if _, err := EncodeDetailed(data, opts); err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+
+	// Corrupt the stored image
+	key := cacheKey(data, Medium, 256, "")
+	if err := os.WriteFile(dir.path(key), []byte("corrupt"), 0644); err != nil {
+		t.Fatalf("failed to corrupt cache file: %v", err)
+	}
+
+	result, err := EncodeDetailed(data, opts)
+	if err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+	if result.Cached {
+		t.Error("Corrupt cached image should not be used")
+	}
+	if err := Verify(result.Image, data); err != nil {
+		t.Errorf("Verification failed: %v", err)
+	}
+
+	// The corrupt entry is replaced by the re-encoded image
+	img, err := dir.Get(context.Background(), key)
+	if err != nil || !bytes.Equal(img, result.Image) {
+		t.Errorf("Cache entry not replaced: %v", err)
+	}
+}
+
+// rejectPayload is a payload whose semantic check always fails.
+type rejectPayload struct{ text string }
+
+func (p *rejectPayload) Encode(limit int) (string, error) { return p.text, nil }
+
+func (p *rejectPayload) Check(decoded string) error {
+	return &FieldError{Field: "rejectPayload.text", Original: p.text, Decoded: decoded}
+}
+
+func TestEncodePayloadCacheCheck(t *testing.T) {
+	data := "cached payload"
+	opts := &EncodeOptions{Cache: NewLRUCache(0)}
+	if _, err := EncodeDetailed(data, opts); err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+
+	// The cached image of the same text must not bypass the payload check
+	var fieldErr *FieldError
+	if _, err := EncodePayload(&rejectPayload{text: data}, opts); !errors.As(err, &fieldErr) {
+		t.Errorf("Expected FieldError on cache hit, got: %v", err)
+	}
+}
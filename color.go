@@ -0,0 +1,189 @@
+package qrverify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+)
+
+// colorLayers names the channels of a color QR code in layer order.
+var colorLayers = [3]string{"red", "green", "blue"}
+
+// EncodeColor generates an experimental color QR code holding up to three
+// times the data of a single code. data is split at character boundaries
+// into up to three parts, each encoded as an independent QR code in the
+// red, green and blue channel of one image. Unused channels are left
+// white. The image is verified by decoding each channel like Encode; each
+// layer must equal its part of data byte for byte. opts
+// are handled like EncodeDetailed, except that Kanji is ignored.
+//
+// Ordinary scanners can not read color codes; use DecodeColor. Cameras
+// need good color separation, so this is intended for screen-to-camera
+// transfers.
+func EncodeColor(data string, opts *EncodeOptions) (*Result, error) {
+	return EncodeColorContext(context.Background(), data, opts)
+}
+
+// EncodeColorContext is like EncodeColor but honors cancellation and
+// deadlines of ctx.
+func EncodeColorContext(ctx context.Context, data string, opts *EncodeOptions) (*Result, error) {
+	return encodeDetailed(ctx, data, opts, encodeHooks{variant: "rgb", color: true})
+}
+
+// checkColorCapacity returns an error if a layer of data does not fit the
+// largest QR code at recovery.
+func checkColorCapacity(data string, recovery Recovery) error {
+	for i, layer := range splitLayers(data, len(colorLayers)) {
+		if err := checkCapacity(layer, recovery); err != nil {
+			return fmt.Errorf("%s layer: %w", colorLayers[i], err)
+		}
+	}
+	return nil
+}
+
+// encodeColor is like encodeAndVerify with each layer of data rendered
+// into its channel.
+func encodeColor(ctx context.Context, data string, recovery Recovery, size int, verifyOutput bool, hooks encodeHooks) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	for c, layer := range splitLayers(data, len(colorLayers)) {
+		if err := canceled(ctx, "render"); err != nil {
+			return nil, err
+		}
+		bc, err := qr.EncodeWithColor(layer, recoveryLevel(recovery), qr.Auto, barcode.ColorScheme8)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s layer: %w", colorLayers[c], err)
+		}
+		gray, err := render(bc, size, size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scale %s layer: %w", colorLayers[c], err)
+		}
+		for i, v := range gray.Pix {
+			img.Pix[i*4+c] = v
+		}
+		putGray(gray)
+	}
+
+	return encodeImage(ctx, data, img, colorSymbology(ctx, splitLayers(data, len(colorLayers))), verifyOutput, hooks)
+}
+
+// DecodeColor reads a color QR code (PNG bytes) generated by EncodeColor
+// and returns the joined text of its layers.
+func DecodeColor(qrImage []byte) (string, error) {
+	img, err := png.Decode(bytes.NewReader(qrImage))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode PNG: %w", err)
+	}
+	return colorSymbology(context.Background(), nil).decode(img)
+}
+
+// VerifyColor checks that qrImage (PNG bytes) is a color QR code whose
+// layers equal expectedData split as EncodeColor splits it.
+func VerifyColor(qrImage []byte, expectedData string) error {
+	ctx := context.Background()
+	sym := colorSymbology(ctx, splitLayers(expectedData, len(colorLayers)))
+	return verifySymbolReader(ctx, bytes.NewReader(qrImage), expectedData, sym, nil)
+}
+
+// colorSymbology reads color QR codes as the joined text of their layers.
+// If want is set, each layer must equal the part of want in its channel.
+func colorSymbology(ctx context.Context, want []string) symbology {
+	return symbology{
+		name: "color QR code",
+		decode: func(img image.Image) (string, error) {
+			layers, err := decodeColor(ctx, img)
+			if err != nil {
+				return "", err
+			}
+			if want != nil {
+				if err := checkLayers(layers, want); err != nil {
+					return "", err
+				}
+			}
+			return strings.Join(layers, ""), nil
+		},
+	}
+}
+
+// checkLayers returns a VerificationError for the first channel whose
+// decoded layer differs from want.
+func checkLayers(layers, want []string) error {
+	for c := range colorLayers {
+		var got, exp string
+		if c < len(layers) {
+			got = layers[c]
+		}
+		if c < len(want) {
+			exp = want[c]
+		}
+		if got != exp {
+			return fmt.Errorf("%s layer: %w", colorLayers[c], &VerificationError{Original: exp, Decoded: got})
+		}
+	}
+	return nil
+}
+
+// decodeColor splits img into its red, green and blue channels and decodes
+// each as a QR code. A uniform channel holds no layer and ends the list.
+func decodeColor(ctx context.Context, img image.Image) ([]string, error) {
+	b := img.Bounds()
+	var layers []string
+	for c := range colorLayers {
+		if err := canceled(ctx, "decode"); err != nil {
+			return nil, err
+		}
+
+		channel := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+		uniform := true
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, bl, _ := img.At(x, y).RGBA()
+				v := byte([3]uint32{r, g, bl}[c] >> 8)
+				channel.Pix[(y-b.Min.Y)*channel.Stride+x-b.Min.X] = v
+				uniform = uniform && v == channel.Pix[0]
+			}
+		}
+		if uniform {
+			break
+		}
+
+		decoded, err := decode(channel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s layer: %w", colorLayers[c], err)
+		}
+		layers = append(layers, decoded)
+	}
+	if len(layers) == 0 {
+		return nil, errors.New("failed to read color QR code: no layers")
+	}
+	return layers, nil
+}
+
+// splitLayers splits data into at most n non-empty parts of roughly equal
+// byte length, never inside a UTF-8 sequence.
+func splitLayers(data string, n int) []string {
+	var layers []string
+	for i := n; i > 0 && data != ""; i-- {
+		cut := (len(data) + i - 1) / i
+		for cut < len(data) && !utf8.RuneStart(data[cut]) {
+			cut++
+		}
+		layers = append(layers, data[:cut])
+		data = data[cut:]
+	}
+	if layers == nil {
+		layers = []string{""}
+	}
+	return layers
+}
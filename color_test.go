@@ -0,0 +1,164 @@
+package qrverify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestEncodeColor(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		layers int
+	}{
+		{"three layers", strings.Repeat("0123456789abcdef", 150), 3},
+		{"beyond single code", strings.Repeat("x", MaxBytesMedium+500), 3},
+		{"multibyte", strings.Repeat("日本語のテキスト🙂", 40), 3},
+		{"two characters", "ab", 2},
+		{"one character", "a", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := EncodeColor(tt.data, &EncodeOptions{Size: 600})
+			if err != nil {
+				t.Fatalf("EncodeColor failed: %v", err)
+			}
+			if result.Data != tt.data || result.Size != 600 || result.Recovery != Medium {
+				t.Errorf("Result = %+v", result)
+			}
+
+			got, err := DecodeColor(result.Image)
+			if err != nil {
+				t.Fatalf("DecodeColor failed: %v", err)
+			}
+			if got != tt.data {
+				t.Errorf("DecodeColor() = %q, want %q", got, tt.data)
+			}
+			if err := VerifyColor(result.Image, tt.data); err != nil {
+				t.Errorf("VerifyColor failed: %v", err)
+			}
+
+			img, err := png.Decode(bytes.NewReader(result.Image))
+			if err != nil {
+				t.Fatalf("png.Decode failed: %v", err)
+			}
+			layers, err := decodeColor(context.Background(), img)
+			if err != nil {
+				t.Fatalf("decodeColor failed: %v", err)
+			}
+			if len(layers) != tt.layers {
+				t.Errorf("got %d layers, want %d", len(layers), tt.layers)
+			}
+		})
+	}
+}
+
+func TestEncodeColorTooLarge(t *testing.T) {
+	if _, err := EncodeColor(strings.Repeat("a", 3*MaxBytesMedium+1), nil); err == nil {
+		t.Error("Expected error for data beyond three layers")
+	}
+}
+
+func TestEncodeColorCache(t *testing.T) {
+	cache := NewLRUCache(4)
+	data := strings.Repeat("0123456789abcdef", 20)
+	if _, err := EncodeDetailed(data, &EncodeOptions{Cache: cache}); err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+
+	opts := &EncodeOptions{Cache: cache, VerifyCached: true}
+	first, err := EncodeColor(data, opts)
+	if err != nil {
+		t.Fatalf("EncodeColor failed: %v", err)
+	}
+	if first.Cached {
+		t.Error("color mode was served the cached single code image")
+	}
+
+	second, err := EncodeColor(data, opts)
+	if err != nil {
+		t.Fatalf("EncodeColor failed: %v", err)
+	}
+	if !second.Cached || !bytes.Equal(second.Image, first.Image) {
+		t.Error("Expected the rechecked color image from the cache")
+	}
+}
+
+func TestVerifyColorMismatch(t *testing.T) {
+	result, err := EncodeColor("red green blue", nil)
+	if err != nil {
+		t.Fatalf("EncodeColor failed: %v", err)
+	}
+	var verr *VerificationError
+	if err := VerifyColor(result.Image, "red green BLUE"); !errors.As(err, &verr) {
+		t.Errorf("VerifyColor() = %v, want *VerificationError", err)
+	}
+}
+
+func TestVerifyColorLayerMismatch(t *testing.T) {
+	// "ab" in the red channel alone joins to the expected text, but
+	// EncodeColor splits "ab" into a red and a green layer.
+	result, err := EncodeDetailed("ab", &EncodeOptions{Size: 128})
+	if err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+	gray, err := png.Decode(bytes.NewReader(result.Image))
+	if err != nil {
+		t.Fatalf("png.Decode failed: %v", err)
+	}
+	b := gray.Bounds()
+	img := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, _, _, _ := gray.At(x, y).RGBA()
+			img.Set(x, y, color.RGBA{byte(r >> 8), 0xff, 0xff, 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode failed: %v", err)
+	}
+
+	if got, err := DecodeColor(buf.Bytes()); err != nil || got != "ab" {
+		t.Fatalf("DecodeColor() = %q, %v", got, err)
+	}
+	var verr *VerificationError
+	if err := VerifyColor(buf.Bytes(), "ab"); !errors.As(err, &verr) || !strings.Contains(err.Error(), "red layer") {
+		t.Errorf("VerifyColor() = %v, want red layer *VerificationError", err)
+	}
+}
+
+func TestDecodeColorBlank(t *testing.T) {
+	var buf bytes.Buffer
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode failed: %v", err)
+	}
+	if _, err := DecodeColor(buf.Bytes()); err == nil {
+		t.Error("Expected error for blank image")
+	}
+}
+
+func TestSplitLayers(t *testing.T) {
+	tests := []struct {
+		data string
+		want []string
+	}{
+		{"", []string{""}},
+		{"abcdefg", []string{"abc", "de", "fg"}},
+		{"ééé", []string{"é", "é", "é"}},
+		{"aé", []string{"a", "é"}},
+	}
+	for _, tt := range tests {
+		got := splitLayers(tt.data, 3)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("splitLayers(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,508 @@
+package qrverify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ContactFormat specifies the text format of a Contact payload.
+type ContactFormat int
+
+const (
+	ContactAuto  ContactFormat = iota // Most compact format that holds all fields (default)
+	MeCardFormat                      // DoCoMo MECARD
+	VCard3                            // vCard 3.0 (RFC 2426)
+	VCard4                            // vCard 4.0 (RFC 6350)
+)
+
+// String returns the format name.
+func (f ContactFormat) String() string {
+	switch f {
+	case ContactAuto:
+		return "Auto"
+	case MeCardFormat:
+		return "MeCard"
+	case VCard3:
+		return "vCard 3.0"
+	case VCard4:
+		return "vCard 4.0"
+	default:
+		return "ContactFormat(unknown)"
+	}
+}
+
+// Address is a postal address.
+type Address struct {
+	Street     string
+	City       string
+	Region     string
+	PostalCode string
+	Country    string
+}
+
+// isZero reports whether no address fields are set.
+func (a Address) isZero() bool {
+	return a == Address{}
+}
+
+// components returns the address fields in vCard ADR order, without the
+// post office box and extended address.
+func (a Address) components() []string {
+	return []string{a.Street, a.City, a.Region, a.PostalCode, a.Country}
+}
+
+// Contact is a business card payload encoded as vCard or MeCard.
+type Contact struct {
+	GivenName    string
+	FamilyName   string
+	Organization string
+	Title        string // Not supported by MeCard
+	Phones       []string
+	Emails       []string
+	URL          string
+	Address      Address
+	Note         string
+
+	// Format selects the text format.
+	// Zero value picks the most compact format that holds all fields.
+	Format ContactFormat
+}
+
+// Encode returns the contact in c.Format. vCard lines are folded at 75
+// octets. With ContactAuto, the shortest valid encoding is returned.
+func (c *Contact) Encode(limit int) (string, error) {
+	if err := c.validate(); err != nil {
+		return "", err
+	}
+
+	var candidates []string
+	switch c.Format {
+	case ContactAuto:
+		if c.Title == "" {
+			candidates = append(candidates, c.meCard())
+		}
+		candidates = append(candidates, c.vCard(VCard3))
+	case MeCardFormat:
+		if c.Title != "" {
+			return "", errors.New("invalid Contact payload: MeCard does not support Title")
+		}
+		candidates = append(candidates, c.meCard())
+	case VCard3, VCard4:
+		candidates = append(candidates, c.vCard(c.Format))
+	default:
+		return "", fmt.Errorf("invalid Contact payload: unknown format %d", c.Format)
+	}
+
+	shortest := candidates[0]
+	for _, s := range candidates[1:] {
+		if len(s) < len(shortest) {
+			shortest = s
+		}
+	}
+	if len(shortest) > limit {
+		return "", fmt.Errorf("data too large: %v contact is %d bytes, exceeds %d byte limit",
+			c.Format, len(shortest), limit)
+	}
+	return shortest, nil
+}
+
+// Check parses decoded as a vCard or MeCard and compares its fields with c.
+func (c *Contact) Check(decoded string) error {
+	got, err := ParseContact(decoded)
+	if err != nil {
+		return err
+	}
+
+	if err := checkField("Contact.GivenName", c.GivenName, got.GivenName); err != nil {
+		return err
+	}
+	if err := checkField("Contact.FamilyName", c.FamilyName, got.FamilyName); err != nil {
+		return err
+	}
+	if err := checkField("Contact.Organization", c.Organization, got.Organization); err != nil {
+		return err
+	}
+	if err := checkField("Contact.Title", c.Title, got.Title); err != nil {
+		return err
+	}
+	if err := checkList("Contact.Phones", c.Phones, got.Phones); err != nil {
+		return err
+	}
+	if err := checkList("Contact.Emails", c.Emails, got.Emails); err != nil {
+		return err
+	}
+	if err := checkField("Contact.URL", c.URL, got.URL); err != nil {
+		return err
+	}
+	if err := checkList("Contact.Address", c.Address.components(), got.Address.components()); err != nil {
+		return err
+	}
+	return checkField("Contact.Note", c.Note, got.Note)
+}
+
+// validate checks that c can be encoded.
+func (c *Contact) validate() error {
+	if c.GivenName == "" && c.FamilyName == "" && c.Organization == "" {
+		return errors.New("invalid Contact payload: a name or organization is required")
+	}
+
+	fields := []string{c.GivenName, c.FamilyName, c.Organization, c.Title, c.URL, c.Note}
+	fields = append(fields, c.Phones...)
+	fields = append(fields, c.Emails...)
+	fields = append(fields, c.Address.components()...)
+	for _, f := range fields {
+		if !utf8.ValidString(f) {
+			return errors.New("invalid Contact payload: fields must be valid UTF-8")
+		}
+		// vCard stores line breaks as \n, which decodes as LF
+		if strings.Contains(f, "\r") {
+			return errors.New("invalid Contact payload: fields must not contain carriage returns, use LF line breaks")
+		}
+	}
+
+	for _, p := range c.Phones {
+		if p == "" || strings.Trim(p, "0123456789+-(). #*") != "" {
+			return errors.New("invalid Contact payload: phone numbers may only contain digits and +-(). #*")
+		}
+	}
+	for _, e := range c.Emails {
+		if !strings.Contains(e, "@") {
+			return errors.New("invalid Contact payload: invalid email address")
+		}
+	}
+	return nil
+}
+
+// displayName returns the formatted name for vCard FN.
+func (c *Contact) displayName() string {
+	if name := strings.TrimSpace(c.GivenName + " " + c.FamilyName); name != "" {
+		return name
+	}
+	return c.Organization
+}
+
+// meCard returns c as a MECARD: payload.
+func (c *Contact) meCard() string {
+	var b strings.Builder
+	b.WriteString("MECARD:")
+	field := func(key, value string) {
+		b.WriteString(key)
+		b.WriteString(":")
+		b.WriteString(value)
+		b.WriteString(";")
+	}
+
+	if c.GivenName != "" || c.FamilyName != "" {
+		field("N", wifiEscape(c.FamilyName)+","+wifiEscape(c.GivenName))
+	}
+	if c.Organization != "" {
+		field("ORG", wifiEscape(c.Organization))
+	}
+	for _, p := range c.Phones {
+		field("TEL", wifiEscape(p))
+	}
+	for _, e := range c.Emails {
+		field("EMAIL", wifiEscape(e))
+	}
+	if c.URL != "" {
+		field("URL", wifiEscape(c.URL))
+	}
+	if !c.Address.isZero() {
+		parts := c.Address.components()
+		for i, p := range parts {
+			parts[i] = wifiEscape(p)
+		}
+		field("ADR", strings.Join(parts, ","))
+	}
+	if c.Note != "" {
+		field("NOTE", wifiEscape(c.Note))
+	}
+	b.WriteString(";")
+	return b.String()
+}
+
+// vCardEscaper escapes vCard text values.
+var vCardEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\n", `\n`)
+
+// vCard returns c as a vCard of the given version with folded lines.
+func (c *Contact) vCard(version ContactFormat) string {
+	var lines []string
+	add := func(line string) {
+		lines = append(lines, line)
+	}
+	esc := vCardEscaper.Replace
+
+	add("BEGIN:VCARD")
+	if version == VCard4 {
+		add("VERSION:4.0")
+	} else {
+		add("VERSION:3.0")
+	}
+	// N is required by vCard 3.0
+	if c.GivenName != "" || c.FamilyName != "" || version == VCard3 {
+		add("N:" + esc(c.FamilyName) + ";" + esc(c.GivenName) + ";;;")
+	}
+	add("FN:" + esc(c.displayName()))
+	if c.Organization != "" {
+		add("ORG:" + esc(c.Organization))
+	}
+	if c.Title != "" {
+		add("TITLE:" + esc(c.Title))
+	}
+	for _, p := range c.Phones {
+		if version == VCard4 && strings.Trim(p, "0123456789+-().") == "" {
+			add("TEL;VALUE=uri:tel:" + p)
+		} else if version == VCard4 {
+			add("TEL;VALUE=text:" + esc(p))
+		} else {
+			add("TEL:" + esc(p))
+		}
+	}
+	for _, e := range c.Emails {
+		add("EMAIL:" + esc(e))
+	}
+	if c.URL != "" {
+		add("URL:" + c.URL)
+	}
+	if !c.Address.isZero() {
+		parts := c.Address.components()
+		for i, p := range parts {
+			parts[i] = esc(p)
+		}
+		add("ADR:;;" + strings.Join(parts, ";"))
+	}
+	if c.Note != "" {
+		add("NOTE:" + esc(c.Note))
+	}
+	add("END:VCARD")
+
+	for i, l := range lines {
+		lines[i] = foldLine(l, 75)
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// foldLine splits line into CRLF-space continuation lines of at most max
+// octets without splitting UTF-8 sequences (RFC 6350 section 3.2).
+func foldLine(line string, max int) string {
+	if len(line) <= max {
+		return line
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range line {
+		size := utf8.RuneLen(r)
+		if n+size > max {
+			b.WriteString("\r\n ")
+			// Continuation lines include the leading space
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
+
+// unfold removes line folding and normalizes line endings to LF.
+func unfold(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\n ", "")
+	return strings.ReplaceAll(s, "\n\t", "")
+}
+
+// ParseContact parses a vCard 3.0/4.0 or MECARD: payload.
+func ParseContact(s string) (*Contact, error) {
+	if strings.HasPrefix(s, "MECARD:") {
+		return parseMeCard(s)
+	}
+	if strings.HasPrefix(s, "BEGIN:VCARD") {
+		return parseVCard(s)
+	}
+	return nil, errors.New("invalid Contact payload: not a vCard or MeCard")
+}
+
+// parseMeCard parses a MECARD: payload.
+func parseMeCard(s string) (*Contact, error) {
+	fields, err := splitEscaped(strings.TrimPrefix(s, "MECARD:"), ';')
+	if err != nil {
+		return nil, fmt.Errorf("invalid MeCard payload: %w", err)
+	}
+
+	c := &Contact{Format: MeCardFormat}
+	for _, f := range fields {
+		if f == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(f, ":")
+		if !ok {
+			return nil, errors.New("invalid MeCard payload: field without key")
+		}
+
+		switch key {
+		case "N":
+			parts, err := splitEscaped(value, ',')
+			if err != nil {
+				return nil, fmt.Errorf("invalid MeCard payload: %w", err)
+			}
+			c.FamilyName = unescapeBackslash(parts[0])
+			if len(parts) > 1 {
+				c.GivenName = unescapeBackslash(parts[1])
+			}
+		case "ORG":
+			c.Organization = unescapeBackslash(value)
+		case "TEL":
+			c.Phones = append(c.Phones, unescapeBackslash(value))
+		case "EMAIL":
+			c.Emails = append(c.Emails, unescapeBackslash(value))
+		case "URL":
+			c.URL = unescapeBackslash(value)
+		case "ADR":
+			parts, err := splitEscaped(value, ',')
+			if err != nil {
+				return nil, fmt.Errorf("invalid MeCard payload: %w", err)
+			}
+			c.Address = addressFrom(parts, unescapeBackslash)
+		case "NOTE":
+			c.Note = unescapeBackslash(value)
+		}
+	}
+	return c, nil
+}
+
+// parseVCard parses a vCard 3.0 or 4.0 payload.
+func parseVCard(s string) (*Contact, error) {
+	lines := strings.Split(unfold(s), "\n")
+	if len(lines) < 2 || strings.TrimSpace(lines[0]) != "BEGIN:VCARD" {
+		return nil, errors.New("invalid vCard payload: missing BEGIN:VCARD")
+	}
+
+	c := &Contact{}
+	ended := false
+	for _, line := range lines[1:] {
+		if line == "" {
+			continue
+		}
+		if line == "END:VCARD" {
+			ended = true
+			break
+		}
+
+		prop, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, errors.New("invalid vCard payload: property without value")
+		}
+		name, params, _ := strings.Cut(prop, ";")
+
+		switch strings.ToUpper(name) {
+		case "VERSION":
+			switch value {
+			case "3.0":
+				c.Format = VCard3
+			case "4.0":
+				c.Format = VCard4
+			default:
+				return nil, errors.New("invalid vCard payload: unsupported version")
+			}
+		case "N":
+			parts, err := splitEscaped(value, ';')
+			if err != nil {
+				return nil, fmt.Errorf("invalid vCard payload: %w", err)
+			}
+			c.FamilyName = unescapeVCard(parts[0])
+			if len(parts) > 1 {
+				c.GivenName = unescapeVCard(parts[1])
+			}
+		case "ORG":
+			c.Organization = unescapeVCard(value)
+		case "TITLE":
+			c.Title = unescapeVCard(value)
+		case "TEL":
+			if strings.Contains(strings.ToUpper(params), "VALUE=URI") {
+				c.Phones = append(c.Phones, strings.TrimPrefix(value, "tel:"))
+			} else {
+				c.Phones = append(c.Phones, unescapeVCard(value))
+			}
+		case "EMAIL":
+			c.Emails = append(c.Emails, unescapeVCard(value))
+		case "URL":
+			c.URL = value
+		case "ADR":
+			parts, err := splitEscaped(value, ';')
+			if err != nil {
+				return nil, fmt.Errorf("invalid vCard payload: %w", err)
+			}
+			if len(parts) > 2 {
+				parts = parts[2:]
+			}
+			c.Address = addressFrom(parts, unescapeVCard)
+		case "NOTE":
+			c.Note = unescapeVCard(value)
+		}
+	}
+
+	if !ended {
+		return nil, errors.New("invalid vCard payload: missing END:VCARD")
+	}
+	if c.Format == ContactAuto {
+		return nil, errors.New("invalid vCard payload: missing VERSION")
+	}
+	return c, nil
+}
+
+// addressFrom builds an Address from components in vCard ADR order,
+// starting at the street address.
+func addressFrom(parts []string, unescape func(string) string) Address {
+	get := func(i int) string {
+		if i < len(parts) {
+			return unescape(parts[i])
+		}
+		return ""
+	}
+	return Address{
+		Street:     get(0),
+		City:       get(1),
+		Region:     get(2),
+		PostalCode: get(3),
+		Country:    get(4),
+	}
+}
+
+// unescapeVCard removes vCard text escapes from s.
+func unescapeVCard(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' || s[i] == 'N' {
+				b.WriteByte('\n')
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// checkList returns a FieldError if original and decoded differ in length
+// or in any element.
+func checkList(field string, original, decoded []string) error {
+	if len(original) != len(decoded) {
+		return &FieldError{
+			Field:    field,
+			Original: strings.Join(original, ", "),
+			Decoded:  strings.Join(decoded, ", "),
+		}
+	}
+	for i := range original {
+		if err := checkField(fmt.Sprintf("%s[%d]", field, i), original[i], decoded[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
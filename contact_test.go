@@ -0,0 +1,159 @@
+package qrverify
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// testContact returns a contact with every field set.
+func testContact() Contact {
+	return Contact{
+		GivenName:    "Ana María",
+		FamilyName:   "O'Neil; Jr.",
+		Organization: "Example, Inc.",
+		Phones:       []string{"+1 555-0100", "+1 (555) 0199"},
+		Emails:       []string{"ana@example.com"},
+		URL:          "https://example.com/ana?x=1;y=2",
+		Address: Address{
+			Street:     "1 Main St, Suite 2",
+			City:       "Springfield",
+			PostalCode: "12345",
+			Country:    "USA",
+		},
+		Note: "Line one\nLine two: \\ backslash",
+	}
+}
+
+func TestContactRoundTrip(t *testing.T) {
+	for _, format := range []ContactFormat{MeCardFormat, VCard3, VCard4} {
+		t.Run(format.String(), func(t *testing.T) {
+			c := testContact()
+			c.Format = format
+
+			text, err := c.Encode(MaxBytesMedium)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+
+			got, err := ParseContact(text)
+			if err != nil {
+				t.Fatalf("ParseContact failed: %v", err)
+			}
+			if got.Format != format {
+				t.Errorf("Format = %v, want %v", got.Format, format)
+			}
+
+			if err := c.Check(text); err != nil {
+				t.Errorf("Check failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestContactAutoFormat(t *testing.T) {
+	c := testContact()
+	text, err := c.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.HasPrefix(text, "MECARD:") {
+		t.Errorf("Expected MeCard as most compact form, got %q", text)
+	}
+
+	// Title is not representable in MeCard
+	c.Title = "Engineer"
+	text, err = c.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.HasPrefix(text, "BEGIN:VCARD\r\nVERSION:3.0") {
+		t.Errorf("Expected vCard 3.0 when Title is set, got %q", text)
+	}
+
+	c.Format = MeCardFormat
+	if _, err := c.Encode(MaxBytesMedium); err == nil {
+		t.Error("Expected error for Title in MeCard format")
+	}
+}
+
+func TestContactFolding(t *testing.T) {
+	c := Contact{
+		GivenName: "Zoë",
+		Note:      strings.Repeat("ü", 60),
+		Format:    VCard4,
+	}
+
+	folded, err := c.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	for _, line := range strings.Split(folded, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Line exceeds 75 octets: %d", len(line))
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("Line splits a UTF-8 sequence: %q", line)
+		}
+	}
+	if err := c.Check(folded); err != nil {
+		t.Errorf("Check of folded card failed: %v", err)
+	}
+
+	// Cards are never left unfolded to fit
+	if _, err := c.Encode(len(folded) - 1); err == nil || !strings.Contains(err.Error(), "data too large") {
+		t.Errorf("Expected capacity error when the folded card does not fit, got: %v", err)
+	}
+}
+
+func TestContactInvalid(t *testing.T) {
+	invalid := []Contact{
+		{},
+		{GivenName: "A", Phones: []string{"call me"}},
+		{GivenName: "A", Emails: []string{"nobody"}},
+		{GivenName: "\xff"},
+		{GivenName: "A", Note: "line one\r\nline two"},
+		{GivenName: "A", Format: ContactFormat(9)},
+	}
+	for _, c := range invalid {
+		if _, err := c.Encode(MaxBytesMedium); err == nil {
+			t.Errorf("Expected error for %+v", c)
+		}
+	}
+
+	for _, s := range []string{"", "BEGIN:VCARD\r\nFN:x\r\n", "BEGIN:VCARD\r\nFN:x\r\nEND:VCARD\r\n", "MECARD:N\\"} {
+		if _, err := ParseContact(s); err == nil {
+			t.Errorf("Expected error parsing %q", s)
+		}
+	}
+}
+
+func TestContactCheckMismatch(t *testing.T) {
+	c := testContact()
+	other := testContact()
+	other.Phones = other.Phones[:1]
+
+	text, err := other.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	var fieldErr *FieldError
+	if err := c.Check(text); !errors.As(err, &fieldErr) || fieldErr.Field != "Contact.Phones" {
+		t.Errorf("Expected FieldError for Contact.Phones, got: %v", err)
+	}
+}
+
+func TestEncodePayloadContact(t *testing.T) {
+	c := testContact()
+	c.Format = VCard3
+
+	result, err := EncodePayload(&c, &EncodeOptions{Size: 512})
+	if err != nil {
+		t.Fatalf("EncodePayload failed: %v", err)
+	}
+	if err := VerifyPayload(result.Image, &c); err != nil {
+		t.Errorf("VerifyPayload failed: %v", err)
+	}
+}
@@ -0,0 +1,330 @@
+package qrverify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/datamatrix"
+	"github.com/makiuchi-d/gozxing/datamatrix/encoder"
+)
+
+// Data Matrix ASCII encodation codewords (ISO/IEC 16022).
+const (
+	dmPad        = 129
+	dmDigitPair  = 130 // Plus the value 00-99 of two digits
+	dmFNC1       = 232
+	dmUpperShift = 235
+)
+
+// dmQuietZone is the quiet zone in modules around a Data Matrix symbol.
+const dmQuietZone = 1
+
+// dmMaxSize is the largest supported symbol, 132x132 modules. The reader
+// does not decode 144x144 symbols, with their uneven interleaving, so
+// they can not be verified.
+const dmMaxSize = 132
+
+// dmMaxDimension bounds symbol selection to dmMaxSize.
+var dmMaxDimension, _ = gozxing.NewDimension(dmMaxSize, dmMaxSize)
+
+// gs1Separator is the ASCII group separator that delimits variable length
+// GS1 element strings; it is encoded as FNC1.
+const gs1Separator = "\x1d"
+
+// DataMatrixShape selects square or rectangular Data Matrix symbols.
+type DataMatrixShape int
+
+const (
+	DataMatrixAuto      DataMatrixShape = iota // Smallest symbol of either shape
+	DataMatrixSquare                           // Square symbols, 10x10 to 132x132
+	DataMatrixRectangle                        // Rectangular symbols, 8x18 to 16x48
+)
+
+// String returns the shape name.
+func (s DataMatrixShape) String() string {
+	switch s {
+	case DataMatrixAuto:
+		return "Auto"
+	case DataMatrixSquare:
+		return "Square"
+	case DataMatrixRectangle:
+		return "Rectangle"
+	default:
+		return "DataMatrixShape(unknown)"
+	}
+}
+
+// DataMatrixOptions configures Data Matrix generation.
+// Zero values provide sensible defaults.
+type DataMatrixOptions struct {
+	// Shape restricts the symbol shape.
+	// Zero value picks the smallest symbol of either shape.
+	Shape DataMatrixShape
+
+	// Rows and Columns select a fixed symbol size in modules, such as
+	// 16x48. Data that does not fit is an error.
+	// Zero values pick the smallest symbol that fits.
+	Rows, Columns int
+
+	// GS1 encodes data as a GS1 element string: the symbol starts with
+	// FNC1 and each ASCII group separator (0x1D) in data becomes FNC1.
+	GS1 bool
+
+	// Size is the image width in pixels. The height follows the symbol's
+	// aspect ratio.
+	// Zero value uses 256.
+	Size int
+
+	// VerifyOutput additionally decodes the final PNG bytes.
+	VerifyOutput bool
+}
+
+// EncodeDataMatrix generates a verified ECC 200 Data Matrix PNG image.
+// Data is stored in ASCII encodation with digit pairs packed into single
+// codewords; bytes outside ASCII use upper shift, so UTF-8 text is stored
+// as its bytes. Verification decodes the symbol with a Data Matrix reader
+// and compares the text byte for byte, and for GS1 symbols also checks the
+// FNC1 symbology identifier. Result.Symbol reports the symbol size;
+// Result.Recovery does not apply, as ECC 200 error correction is fixed
+// per symbol size.
+func EncodeDataMatrix(data string, opts *DataMatrixOptions) (*Result, error) {
+	return EncodeDataMatrixContext(context.Background(), data, opts)
+}
+
+// EncodeDataMatrixContext is like EncodeDataMatrix but honors cancellation
+// and deadlines of ctx.
+func EncodeDataMatrixContext(ctx context.Context, data string, opts *DataMatrixOptions) (*Result, error) {
+	if opts == nil {
+		opts = &DataMatrixOptions{}
+	}
+	size := 256
+	if opts.Size > 0 {
+		size = opts.Size
+	}
+
+	if err := canceled(ctx, "render"); err != nil {
+		return nil, err
+	}
+	modules, info, err := dataMatrixModules(data, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	b := modules.Bounds()
+	height := size
+	if b.Dx() != b.Dy() {
+		height = size * b.Dy() / b.Dx()
+	}
+	sym := dataMatrixSymbology(opts.GS1)
+	png, err := encodeSymbol(ctx, data, modules, sym, size, height, opts.VerifyOutput, encodeHooks{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Image:  png,
+		Data:   data,
+		Size:   size,
+		Symbol: info,
+	}, nil
+}
+
+// VerifyDataMatrix checks that qrImage (PNG bytes) holds a Data Matrix
+// symbol that decodes to expectedData. opts.GS1 selects whether a GS1
+// symbol is expected; the other options are ignored.
+func VerifyDataMatrix(qrImage []byte, expectedData string, opts *DataMatrixOptions) error {
+	img, err := png.Decode(bytes.NewReader(qrImage))
+	if err != nil {
+		return fmt.Errorf("failed to decode PNG: %w", err)
+	}
+	gs1 := opts != nil && opts.GS1
+	return verifySymbol(context.Background(), img, expectedData, dataMatrixSymbology(gs1), nil)
+}
+
+// dataMatrixSymbology reads Data Matrix symbols. A GS1 symbol must carry
+// the FNC1 symbology identifier ]d2 and its leading separator is removed;
+// any other symbol must not.
+func dataMatrixSymbology(gs1 bool) symbology {
+	return symbology{
+		name: "Data Matrix",
+		decode: func(img image.Image) (string, error) {
+			result, err := readBarcode(img, datamatrix.NewDataMatrixReader(), nil)
+			if err != nil {
+				return "", fmt.Errorf("failed to decode Data Matrix: %w", err)
+			}
+			text := result.GetText()
+			id, _ := result.GetResultMetadata()[gozxing.ResultMetadataType_SYMBOLOGY_IDENTIFIER].(string)
+			switch {
+			case gs1 && id != "]d2":
+				return "", errors.New("verification failed: Data Matrix is not a GS1 symbol")
+			case !gs1 && id == "]d2":
+				return "", errors.New("verification failed: Data Matrix is an unexpected GS1 symbol")
+			case gs1:
+				text = strings.TrimPrefix(text, gs1Separator)
+			}
+			return text, nil
+		},
+	}
+}
+
+// dataMatrixModules encodes data as an ECC 200 symbol at one pixel per
+// module, surrounded by the quiet zone.
+func dataMatrixModules(data string, opts *DataMatrixOptions) (*image.Gray, *SymbolInfo, error) {
+	if data == "" {
+		return nil, nil, errors.New("failed to create Data Matrix: data is empty")
+	}
+	shape := encoder.SymbolShapeHint_FORCE_NONE
+	switch opts.Shape {
+	case DataMatrixAuto:
+	case DataMatrixSquare:
+		shape = encoder.SymbolShapeHint_FORCE_SQUARE
+	case DataMatrixRectangle:
+		shape = encoder.SymbolShapeHint_FORCE_RECTANGLE
+	default:
+		return nil, nil, fmt.Errorf("failed to create Data Matrix: unknown shape %v", opts.Shape)
+	}
+
+	var minSize *gozxing.Dimension
+	maxSize := dmMaxDimension
+	if opts.Rows != 0 || opts.Columns != 0 {
+		dim, err := gozxing.NewDimension(opts.Columns, opts.Rows)
+		if err != nil || opts.Rows > dmMaxSize || opts.Columns > dmMaxSize {
+			return nil, nil, fmt.Errorf("failed to create Data Matrix: invalid size %dx%d", opts.Rows, opts.Columns)
+		}
+		if s, _ := encoder.SymbolInfo_Lookup(0, shape, dim, dim, false); s == nil {
+			return nil, nil, fmt.Errorf("failed to create Data Matrix: no %v symbol is %dx%d", opts.Shape, opts.Rows, opts.Columns)
+		}
+		minSize, maxSize = dim, dim
+	}
+
+	if opts.GS1 {
+		if err := validateGS1Data(data); err != nil {
+			return nil, nil, err
+		}
+	}
+	codewords := dataMatrixCodewords(data, opts.GS1)
+
+	info, err := encoder.SymbolInfo_Lookup(len(codewords), shape, minSize, maxSize, false)
+	if err != nil || info == nil {
+		target := "largest"
+		if minSize != nil {
+			target = fmt.Sprintf("%dx%d", opts.Rows, opts.Columns)
+		}
+		return nil, nil, fmt.Errorf("data too large: %d codewords exceed the %s %v Data Matrix", len(codewords), target, opts.Shape)
+	}
+	codewords = dataMatrixPad(codewords, info.GetDataCapacity())
+	codewords, err = encoder.ErrorCorrection_EncodeECC200(codewords, info)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create Data Matrix: %w", err)
+	}
+
+	placement := encoder.NewDefaultPlacement(codewords, info.GetSymbolDataWidth(), info.GetSymbolDataHeight())
+	placement.Place()
+
+	cols, rows := info.GetSymbolWidth(), info.GetSymbolHeight()
+	img := image.NewGray(image.Rect(0, 0, cols+2*dmQuietZone, rows+2*dmQuietZone))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	dark := func(x, y int) {
+		img.Pix[(y+dmQuietZone)*img.Stride+x+dmQuietZone] = 0
+	}
+
+	// Each data region has a solid L on its left and bottom edges and an
+	// alternating clock track on its top and right edges.
+	regionW, regionH := info.GetMatrixWidth(), info.GetMatrixHeight()
+	for y := 0; y < rows; y++ {
+		ry, inY := y/(regionH+2), y%(regionH+2)
+		for x := 0; x < cols; x++ {
+			rx, inX := x/(regionW+2), x%(regionW+2)
+			var on bool
+			switch {
+			case inX == 0 || inY == regionH+1:
+				on = true
+			case inY == 0:
+				on = inX%2 == 0
+			case inX == regionW+1:
+				on = inY%2 == 1
+			default:
+				on = placement.GetBit(rx*regionW+inX-1, ry*regionH+inY-1)
+			}
+			if on {
+				dark(x, y)
+			}
+		}
+	}
+
+	return img, &SymbolInfo{
+		Format:         "Data Matrix",
+		Rows:           rows,
+		Columns:        cols,
+		DataCodewords:  info.GetDataCapacity(),
+		ErrorCodewords: info.GetErrorCodewords(),
+	}, nil
+}
+
+// dataMatrixCodewords returns the ASCII encodation of data. For GS1 data
+// the first codeword is FNC1 and group separators become FNC1.
+func dataMatrixCodewords(data string, gs1 bool) []byte {
+	var out []byte
+	if gs1 {
+		out = append(out, dmFNC1)
+	}
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case isDigit(c) && i+1 < len(data) && isDigit(data[i+1]):
+			out = append(out, dmDigitPair+(c-'0')*10+data[i+1]-'0')
+			i++
+		case gs1 && c == gs1Separator[0]:
+			out = append(out, dmFNC1)
+		case c >= 128:
+			out = append(out, dmUpperShift, c-127)
+		default:
+			out = append(out, c+1)
+		}
+	}
+	return out
+}
+
+// dataMatrixPad fills codewords to capacity with the pad codeword and the
+// 253-state randomized pads that follow it.
+func dataMatrixPad(codewords []byte, capacity int) []byte {
+	if len(codewords) < capacity {
+		codewords = append(codewords, dmPad)
+	}
+	for len(codewords) < capacity {
+		r := 149*(len(codewords)+1)%253 + 1
+		pad := dmPad + r
+		if pad > 254 {
+			pad -= 254
+		}
+		codewords = append(codewords, byte(pad))
+	}
+	return codewords
+}
+
+// validateGS1Data checks data is printable ASCII element strings separated
+// by group separators, starting with an application identifier.
+func validateGS1Data(data string) error {
+	if !isDigit(data[0]) {
+		return errors.New("invalid GS1 data: must start with an application identifier")
+	}
+	for i := 0; i < len(data); i++ {
+		if c := data[i]; (c < 0x20 || c > 0x7e) && c != gs1Separator[0] {
+			return errors.New("invalid GS1 data: only printable ASCII and group separators are allowed")
+		}
+	}
+	return nil
+}
+
+// isDigit reports whether c is an ASCII digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
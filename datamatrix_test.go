@@ -0,0 +1,139 @@
+package qrverify
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDataMatrix(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		opts       *DataMatrixOptions
+		rows, cols int
+	}{
+		{"smallest", "123456", nil, 10, 10},
+		{"digit pairs", "0123456789012345", nil, 14, 14},
+		{"text", "PART-7781/REV C", nil, 12, 26},
+		{"square", "Hello", &DataMatrixOptions{Shape: DataMatrixSquare}, 12, 12},
+		{"rectangle", "Hello", &DataMatrixOptions{Shape: DataMatrixRectangle}, 8, 18},
+		{"auto picks rectangle", "ABCDEFGHI", nil, 8, 32},
+		{"fixed size", "Hello", &DataMatrixOptions{Rows: 16, Columns: 48}, 16, 48},
+		{"fixed square", "Hi", &DataMatrixOptions{Rows: 32, Columns: 32}, 32, 32},
+		{"multiple regions", strings.Repeat("X", 100), nil, 40, 40},
+		{"largest", strings.Repeat("7", 2600), &DataMatrixOptions{Size: 600}, 132, 132},
+		{"UTF-8", "Größe 10 µm", nil, 20, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := EncodeDataMatrix(tt.data, tt.opts)
+			if err != nil {
+				t.Fatalf("EncodeDataMatrix failed: %v", err)
+			}
+			s := result.Symbol
+			if s == nil || s.Format != "Data Matrix" || s.Rows != tt.rows || s.Columns != tt.cols {
+				t.Fatalf("Symbol = %+v, want %dx%d Data Matrix", s, tt.rows, tt.cols)
+			}
+			if s.DataCodewords == 0 || s.ErrorCodewords == 0 {
+				t.Errorf("Symbol = %+v, want codeword counts", s)
+			}
+			if err := VerifyDataMatrix(result.Image, tt.data, tt.opts); err != nil {
+				t.Errorf("VerifyDataMatrix failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestEncodeDataMatrixGS1(t *testing.T) {
+	data := "0109501101530003" + "17261231" + "10ABC123" + gs1Separator + "21SN42"
+	opts := &DataMatrixOptions{GS1: true, VerifyOutput: true}
+	result, err := EncodeDataMatrix(data, opts)
+	if err != nil {
+		t.Fatalf("EncodeDataMatrix failed: %v", err)
+	}
+	if err := VerifyDataMatrix(result.Image, data, opts); err != nil {
+		t.Errorf("VerifyDataMatrix failed: %v", err)
+	}
+	// A GS1 symbol is not the same as plain text with the same characters
+	if err := VerifyDataMatrix(result.Image, data, nil); err == nil {
+		t.Error("Expected error verifying a GS1 symbol as plain text")
+	}
+
+	plain, err := EncodeDataMatrix(data, nil)
+	if err != nil {
+		t.Fatalf("EncodeDataMatrix failed: %v", err)
+	}
+	if err := VerifyDataMatrix(plain.Image, data, opts); err == nil {
+		t.Error("Expected error verifying plain text as a GS1 symbol")
+	}
+}
+
+func TestEncodeDataMatrixRectangleImage(t *testing.T) {
+	result, err := EncodeDataMatrix("Hello", &DataMatrixOptions{Rows: 12, Columns: 36, Size: 380})
+	if err != nil {
+		t.Fatalf("EncodeDataMatrix failed: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(result.Image))
+	if err != nil {
+		t.Fatalf("png.Decode failed: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 380 || b.Dy() != 380*14/38 {
+		t.Errorf("image is %dx%d, want 380x%d", b.Dx(), b.Dy(), 380*14/38)
+	}
+}
+
+func TestEncodeDataMatrixInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		opts *DataMatrixOptions
+	}{
+		{"empty", "", nil},
+		{"too large", strings.Repeat("x", 3000), nil},
+		{"needs 144x144", strings.Repeat("7", 3000), nil},
+		{"fixed 144x144", "x", &DataMatrixOptions{Rows: 144, Columns: 144}},
+		{"too large for fixed size", strings.Repeat("x", 20), &DataMatrixOptions{Rows: 10, Columns: 10}},
+		{"too large for rectangle", strings.Repeat("x", 50), &DataMatrixOptions{Shape: DataMatrixRectangle}},
+		{"no such size", "x", &DataMatrixOptions{Rows: 11, Columns: 11}},
+		{"size does not match shape", "x", &DataMatrixOptions{Rows: 8, Columns: 18, Shape: DataMatrixSquare}},
+		{"unknown shape", "x", &DataMatrixOptions{Shape: 9}},
+		{"GS1 without AI", "ABC", &DataMatrixOptions{GS1: true}},
+		{"GS1 non-ASCII", "10Größe", &DataMatrixOptions{GS1: true}},
+	}
+	for _, tt := range tests {
+		if _, err := EncodeDataMatrix(tt.data, tt.opts); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestVerifyDataMatrixMismatch(t *testing.T) {
+	result, err := EncodeDataMatrix("LOT 42", nil)
+	if err != nil {
+		t.Fatalf("EncodeDataMatrix failed: %v", err)
+	}
+	var verr *VerificationError
+	if err := VerifyDataMatrix(result.Image, "LOT 43", nil); !errors.As(err, &verr) {
+		t.Errorf("VerifyDataMatrix() = %v, want *VerificationError", err)
+	}
+
+	// A QR code is not a Data Matrix symbol
+	qr, err := Encode("LOT 42", nil)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := VerifyDataMatrix(qr, "LOT 42", nil); err == nil {
+		t.Error("Expected error for a QR code")
+	}
+}
+
+func TestDataMatrixCodewords(t *testing.T) {
+	got := dataMatrixCodewords("A12\x1d3é", true)
+	want := []byte{dmFNC1, 'A' + 1, dmDigitPair + 12, dmFNC1, '3' + 1, dmUpperShift, 0xc3 - 127, dmUpperShift, 0xa9 - 127}
+	if string(got) != string(want) {
+		t.Errorf("dataMatrixCodewords() = %v, want %v", got, want)
+	}
+}
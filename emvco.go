@@ -0,0 +1,343 @@
+package qrverify
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// EMVField is a TLV data object in an EMVCo merchant-presented QR payload.
+// A field with Fields set is a template whose value is the encoded
+// nested fields.
+type EMVField struct {
+	ID     string     // Two digit ID
+	Value  string     // Primitive value, ignored if Fields is set
+	Fields []EMVField // Nested fields of a template
+}
+
+// EMVCoPayload is an EMVCo merchant-presented mode (MPM) QR payload.
+// Encode adds the payload format indicator (ID 00) first and the
+// CRC-16/CCITT checksum (ID 63) last, so Fields must contain neither.
+type EMVCoPayload struct {
+	Fields []EMVField
+}
+
+// Encode returns the TLV payload with its CRC.
+func (p *EMVCoPayload) Encode(limit int) (string, error) {
+	for _, f := range p.Fields {
+		if f.ID == "00" || f.ID == "63" {
+			return "", fmt.Errorf("invalid EMVCo payload: field %s is added automatically", f.ID)
+		}
+		// Readers only parse templates one level deep at template IDs
+		if f.Fields != nil && !isEMVTemplate(f.ID) {
+			return "", fmt.Errorf("invalid EMVCo payload: field %s is not a template and can not hold fields", f.ID)
+		}
+		for _, nested := range f.Fields {
+			if nested.Fields != nil {
+				return "", fmt.Errorf("invalid EMVCo payload: field %s in template %s can not hold fields", nested.ID, f.ID)
+			}
+		}
+	}
+
+	body, err := encodeEMVFields(p.Fields)
+	if err != nil {
+		return "", err
+	}
+
+	s := "000201" + body + "6304"
+	s += fmt.Sprintf("%04X", crc16CCITT([]byte(s)))
+	if len(s) > limit {
+		return "", fmt.Errorf("data too large: EMVCo payload is %d bytes, exceeds %d byte limit", len(s), limit)
+	}
+	return s, nil
+}
+
+// Check parses decoded as an EMVCo payload, validates its CRC and compares
+// its fields with p.
+func (p *EMVCoPayload) Check(decoded string) error {
+	got, err := ParseEMVCo(decoded)
+	if err != nil {
+		return err
+	}
+	return checkEMVFields("EMVCo", p.Fields, got.Fields)
+}
+
+// checkEMVFields compares original and decoded fields recursively.
+func checkEMVFields(path string, original, decoded []EMVField) error {
+	if len(original) != len(decoded) {
+		return &FieldError{
+			Field:    path,
+			Original: strconv.Itoa(len(original)) + " fields",
+			Decoded:  strconv.Itoa(len(decoded)) + " fields",
+		}
+	}
+	for i, o := range original {
+		d := decoded[i]
+		name := path + "[" + o.ID + "]"
+		if err := checkField(name+".ID", o.ID, d.ID); err != nil {
+			return err
+		}
+		if o.Fields != nil {
+			if err := checkEMVFields(name, o.Fields, d.Fields); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// A template may be given as a raw value
+		value := d.Value
+		if d.Fields != nil {
+			value, _ = encodeEMVFields(d.Fields)
+		}
+		if err := checkField(name, o.Value, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeEMVFields encodes fields as ID, two digit length and value.
+func encodeEMVFields(fields []EMVField) (string, error) {
+	var b strings.Builder
+	for _, f := range fields {
+		if len(f.ID) != 2 || !isDigits(f.ID) {
+			return "", errors.New("invalid EMVCo payload: field IDs must be two digits")
+		}
+
+		value := f.Value
+		if f.Fields != nil {
+			v, err := encodeEMVFields(f.Fields)
+			if err != nil {
+				return "", err
+			}
+			value = v
+		}
+
+		n := utf8.RuneCountInString(value)
+		if n == 0 || n > 99 {
+			return "", fmt.Errorf("invalid EMVCo payload: field %s length must be 1-99", f.ID)
+		}
+		b.WriteString(f.ID)
+		fmt.Fprintf(&b, "%02d", n)
+		b.WriteString(value)
+	}
+	return b.String(), nil
+}
+
+// isEMVTemplate reports whether id is a template holding nested fields
+// at the top level of an MPM payload.
+func isEMVTemplate(id string) bool {
+	n, _ := strconv.Atoi(id)
+	return (n >= 26 && n <= 51) || n == 62 || n == 64 || n >= 80
+}
+
+// emvKind describes the field kind isEMVTemplate expects for id.
+func emvKind(id string) string {
+	if isEMVTemplate(id) {
+		return "a template"
+	}
+	return "a primitive value"
+}
+
+// ParseEMVCo parses an EMVCo MPM payload, validating the payload format
+// indicator and the CRC. The returned payload omits fields 00 and 63.
+func ParseEMVCo(s string) (*EMVCoPayload, error) {
+	if !strings.HasPrefix(s, "000201") {
+		return nil, errors.New("invalid EMVCo payload: missing payload format indicator")
+	}
+	if len(s) < 14 || s[len(s)-8:len(s)-4] != "6304" {
+		return nil, errors.New("invalid EMVCo payload: CRC must be the last field")
+	}
+
+	crc, err := strconv.ParseUint(s[len(s)-4:], 16, 16)
+	if err != nil || fmt.Sprintf("%04X", crc) != s[len(s)-4:] {
+		return nil, errors.New("invalid EMVCo payload: malformed CRC")
+	}
+	if uint16(crc) != crc16CCITT([]byte(s[:len(s)-4])) {
+		return nil, errors.New("invalid EMVCo payload: CRC mismatch")
+	}
+
+	fields, err := parseEMVFields(s[6:len(s)-8], true)
+	if err != nil {
+		return nil, err
+	}
+	return &EMVCoPayload{Fields: fields}, nil
+}
+
+// parseEMVFields parses TLV fields. Templates are parsed recursively at
+// the top level.
+func parseEMVFields(s string, top bool) ([]EMVField, error) {
+	fields := []EMVField{}
+	for s != "" {
+		if len(s) < 4 || !isDigits(s[:4]) {
+			return nil, errors.New("invalid EMVCo payload: malformed field header")
+		}
+		id := s[:2]
+		n, _ := strconv.Atoi(s[2:4])
+		s = s[4:]
+
+		// Lengths count characters, not bytes
+		end := 0
+		for i := 0; i < n; i++ {
+			if end >= len(s) {
+				return nil, errors.New("invalid EMVCo payload: field exceeds payload")
+			}
+			_, size := utf8.DecodeRuneInString(s[end:])
+			end += size
+		}
+		value := s[:end]
+		s = s[end:]
+
+		f := EMVField{ID: id, Value: value}
+		if top && isEMVTemplate(id) {
+			nested, err := parseEMVFields(value, false)
+			if err != nil {
+				return nil, err
+			}
+			f = EMVField{ID: id, Fields: nested}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// crc16CCITT returns the CRC-16/CCITT-FALSE checksum (polynomial 0x1021,
+// initial value 0xFFFF) used by EMVCo.
+func crc16CCITT(data []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// EMVCoMerchant is a typed EMVCo MPM payload for common merchant fields.
+type EMVCoMerchant struct {
+	Dynamic      bool       // Point of initiation 12 (dynamic) instead of 11 (static)
+	Accounts     []EMVField // Merchant accounts: IDs 02-25 primitive, 26-51 templates
+	MCC          string     // Merchant category code, four digits
+	Currency     string     // ISO 4217 numeric currency code, three digits
+	Amount       string     // Transaction amount, optional, such as "12.50"
+	CountryCode  string     // ISO 3166-1 alpha-2 code
+	Name         string     // Merchant name, at most 25 characters
+	City         string     // Merchant city, at most 15 characters
+	PostalCode   string     // Optional, at most 10 characters
+	BillNumber   string     // Additional data field 01, optional
+	TerminalID   string     // Additional data field 07, optional
+	Unreserved   []EMVField // Unreserved templates, IDs 80-99, optional
+	LanguageInfo []EMVField // Merchant information language template fields, optional
+}
+
+// payload returns m as generic fields, validating required values.
+func (m *EMVCoMerchant) payload() (*EMVCoPayload, error) {
+	if len(m.Accounts) == 0 {
+		return nil, errors.New("invalid EMVCo payload: at least one merchant account is required")
+	}
+	for _, a := range m.Accounts {
+		n, err := strconv.Atoi(a.ID)
+		if err != nil || n < 2 || n > 51 {
+			return nil, errors.New("invalid EMVCo payload: merchant account IDs must be 02-51")
+		}
+		// Payment networks own the primitive IDs, the rest are templates
+		if (a.Fields != nil) != isEMVTemplate(a.ID) {
+			return nil, fmt.Errorf("invalid EMVCo payload: merchant account %s must be %s", a.ID, emvKind(a.ID))
+		}
+	}
+	for _, u := range m.Unreserved {
+		n, err := strconv.Atoi(u.ID)
+		if err != nil || n < 80 || n > 99 {
+			return nil, errors.New("invalid EMVCo payload: unreserved template IDs must be 80-99")
+		}
+		if u.Fields == nil {
+			return nil, fmt.Errorf("invalid EMVCo payload: unreserved template %s must be a template", u.ID)
+		}
+	}
+	if len(m.MCC) != 4 || !isDigits(m.MCC) {
+		return nil, errors.New("invalid EMVCo payload: MCC must be four digits")
+	}
+	if len(m.Currency) != 3 || !isDigits(m.Currency) {
+		return nil, errors.New("invalid EMVCo payload: currency must be a three digit ISO 4217 code")
+	}
+	if m.Amount != "" {
+		whole, frac, _ := strings.Cut(m.Amount, ".")
+		if len(m.Amount) > 13 || !isDigits(whole) || (strings.Contains(m.Amount, ".") && !isDigits(frac)) {
+			return nil, errors.New("invalid EMVCo payload: malformed amount")
+		}
+	}
+	if len(m.CountryCode) != 2 || !isUpperAlpha(m.CountryCode) {
+		return nil, errors.New("invalid EMVCo payload: country code must be two letters")
+	}
+	if m.Name == "" || utf8.RuneCountInString(m.Name) > 25 {
+		return nil, errors.New("invalid EMVCo payload: merchant name must be 1-25 characters")
+	}
+	if m.City == "" || utf8.RuneCountInString(m.City) > 15 {
+		return nil, errors.New("invalid EMVCo payload: merchant city must be 1-15 characters")
+	}
+
+	initiation := "11"
+	if m.Dynamic {
+		initiation = "12"
+	}
+	fields := []EMVField{{ID: "01", Value: initiation}}
+	fields = append(fields, m.Accounts...)
+	fields = append(fields,
+		EMVField{ID: "52", Value: m.MCC},
+		EMVField{ID: "53", Value: m.Currency},
+	)
+	if m.Amount != "" {
+		fields = append(fields, EMVField{ID: "54", Value: m.Amount})
+	}
+	fields = append(fields,
+		EMVField{ID: "58", Value: m.CountryCode},
+		EMVField{ID: "59", Value: m.Name},
+		EMVField{ID: "60", Value: m.City},
+	)
+	if m.PostalCode != "" {
+		fields = append(fields, EMVField{ID: "61", Value: m.PostalCode})
+	}
+
+	var additional []EMVField
+	if m.BillNumber != "" {
+		additional = append(additional, EMVField{ID: "01", Value: m.BillNumber})
+	}
+	if m.TerminalID != "" {
+		additional = append(additional, EMVField{ID: "07", Value: m.TerminalID})
+	}
+	if additional != nil {
+		fields = append(fields, EMVField{ID: "62", Fields: additional})
+	}
+	if m.LanguageInfo != nil {
+		fields = append(fields, EMVField{ID: "64", Fields: m.LanguageInfo})
+	}
+	fields = append(fields, m.Unreserved...)
+
+	return &EMVCoPayload{Fields: fields}, nil
+}
+
+// Encode returns the TLV payload with its CRC.
+func (m *EMVCoMerchant) Encode(limit int) (string, error) {
+	p, err := m.payload()
+	if err != nil {
+		return "", err
+	}
+	return p.Encode(limit)
+}
+
+// Check parses decoded as an EMVCo payload, validates its CRC and compares
+// its fields with m.
+func (m *EMVCoMerchant) Check(decoded string) error {
+	p, err := m.payload()
+	if err != nil {
+		return err
+	}
+	return p.Check(decoded)
+}
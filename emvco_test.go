@@ -0,0 +1,173 @@
+package qrverify
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// emvcoSample is the sample payload from the EMVCo MPM specification.
+const emvcoSample = "00020101021229300012D156000000000510A93FO3230Q31280012D15600000001030812345678520441115802CN5914BEST TRANSPORT6007BEIJING64200002ZH0104最佳运输0202北京540523.7253031565502016233030412340603***0708A60086670902ME91320016A0112233449988770708123456786304A13A"
+
+func TestCRC16CCITT(t *testing.T) {
+	if got := crc16CCITT([]byte("123456789")); got != 0x29B1 {
+		t.Errorf("crc16CCITT(123456789) = %04X, want 29B1", got)
+	}
+}
+
+func TestParseEMVCo(t *testing.T) {
+	p, err := ParseEMVCo(emvcoSample)
+	if err != nil {
+		t.Fatalf("ParseEMVCo failed: %v", err)
+	}
+
+	byID := make(map[string]EMVField)
+	for _, f := range p.Fields {
+		byID[f.ID] = f
+	}
+	if byID["59"].Value != "BEST TRANSPORT" {
+		t.Errorf("Merchant name = %q", byID["59"].Value)
+	}
+	if len(byID["64"].Fields) != 3 || byID["64"].Fields[1].Value != "最佳运输" {
+		t.Errorf("Language template = %+v", byID["64"])
+	}
+	if byID["29"].Fields[0].Value != "D15600000000" {
+		t.Errorf("Merchant account template = %+v", byID["29"])
+	}
+
+	// Re-encoding the parsed fields reproduces the sample
+	got, err := p.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if got != emvcoSample {
+		t.Errorf("Encode() = %q, want %q", got, emvcoSample)
+	}
+}
+
+func TestParseEMVCoInvalid(t *testing.T) {
+	invalid := map[string]string{
+		"bad CRC":           emvcoSample[:len(emvcoSample)-4] + "0000",
+		"tampered value":    strings.Replace(emvcoSample, "BEIJING", "BEIJINC", 1),
+		"missing indicator": emvcoSample[6:],
+		"missing CRC":       emvcoSample[:len(emvcoSample)-8],
+		"truncated field":   "000201" + "5910SHORT" + "6304",
+	}
+	for name, s := range invalid {
+		if _, err := ParseEMVCo(s); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+// testMerchant returns a merchant with required fields set.
+func testMerchant() EMVCoMerchant {
+	return EMVCoMerchant{
+		Accounts: []EMVField{{ID: "26", Fields: []EMVField{
+			{ID: "00", Value: "com.example.pay"},
+			{ID: "01", Value: "MERCHANT-42"},
+		}}},
+		MCC:         "5812",
+		Currency:    "840",
+		Amount:      "12.50",
+		CountryCode: "US",
+		Name:        "Joe's Diner",
+		City:        "Springfield",
+		BillNumber:  "INV-1001",
+	}
+}
+
+func TestEMVCoMerchant(t *testing.T) {
+	m := testMerchant()
+
+	text, err := m.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.HasPrefix(text, "00020101021126") || !strings.Contains(text, "6304") {
+		t.Errorf("Unexpected payload: %q", text)
+	}
+	if err := m.Check(text); err != nil {
+		t.Errorf("Check failed: %v", err)
+	}
+
+	other := testMerchant()
+	other.Amount = "99.00"
+	otherText, err := other.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	var fieldErr *FieldError
+	if err := m.Check(otherText); !errors.As(err, &fieldErr) || fieldErr.Field != "EMVCo[54]" {
+		t.Errorf("Expected FieldError for EMVCo[54], got: %v", err)
+	}
+}
+
+func TestEMVCoMerchantPrimitiveAccount(t *testing.T) {
+	m := testMerchant()
+	m.Accounts = append(m.Accounts, EMVField{ID: "04", Value: "4111111111111111"})
+	m.Unreserved = []EMVField{{ID: "80", Fields: []EMVField{{ID: "00", Value: "com.example.loyalty"}}}}
+
+	text, err := m.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := m.Check(text); err != nil {
+		t.Errorf("Check failed: %v", err)
+	}
+}
+
+func TestEMVCoMerchantInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(m *EMVCoMerchant)
+	}{
+		{"no accounts", func(m *EMVCoMerchant) { m.Accounts = nil }},
+		{"bad account ID", func(m *EMVCoMerchant) { m.Accounts[0].ID = "62" }},
+		{"primitive account template", func(m *EMVCoMerchant) { m.Accounts[0].ID = "04" }},
+		{"account template value", func(m *EMVCoMerchant) { m.Accounts[0] = EMVField{ID: "26", Value: "x"} }},
+		{"bad unreserved ID", func(m *EMVCoMerchant) { m.Unreserved = []EMVField{{ID: "65", Fields: m.Accounts[0].Fields}} }},
+		{"unreserved value", func(m *EMVCoMerchant) { m.Unreserved = []EMVField{{ID: "80", Value: "x"}} }},
+		{"bad MCC", func(m *EMVCoMerchant) { m.MCC = "58" }},
+		{"bad currency", func(m *EMVCoMerchant) { m.Currency = "USD" }},
+		{"bad amount", func(m *EMVCoMerchant) { m.Amount = "1,00" }},
+		{"name too long", func(m *EMVCoMerchant) { m.Name = strings.Repeat("x", 26) }},
+		{"missing city", func(m *EMVCoMerchant) { m.City = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMerchant()
+			tt.modify(&m)
+			if _, err := m.Encode(MaxBytesMedium); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+
+	p := &EMVCoPayload{Fields: []EMVField{{ID: "63", Value: "FFFF"}}}
+	if _, err := p.Encode(MaxBytesMedium); err == nil {
+		t.Error("Expected error for explicit CRC field")
+	}
+
+	nested := []EMVField{{ID: "00", Value: "com.example.pay"}}
+	p = &EMVCoPayload{Fields: []EMVField{{ID: "52", Fields: nested}}}
+	if _, err := p.Encode(MaxBytesMedium); err == nil || !strings.Contains(err.Error(), "not a template") {
+		t.Errorf("Expected error for fields under a primitive ID, got: %v", err)
+	}
+	p = &EMVCoPayload{Fields: []EMVField{{ID: "26", Fields: []EMVField{{ID: "01", Fields: nested}}}}}
+	if _, err := p.Encode(MaxBytesMedium); err == nil || !strings.Contains(err.Error(), "can not hold fields") {
+		t.Errorf("Expected error for a nested template, got: %v", err)
+	}
+}
+
+func TestEncodePayloadEMVCo(t *testing.T) {
+	m := testMerchant()
+
+	result, err := EncodePayload(&m, nil)
+	if err != nil {
+		t.Fatalf("EncodePayload failed: %v", err)
+	}
+	if err := VerifyPayload(result.Image, &m); err != nil {
+		t.Errorf("VerifyPayload failed: %v", err)
+	}
+}
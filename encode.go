@@ -2,16 +2,21 @@ package qrverify
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"image/png"
+	"image"
+	"image/color"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/boombuler/barcode"
 	"github.com/boombuler/barcode/qr"
 )
 
 // Maximum data capacity in bytes for QR Version 40 (largest standard QR code)
-// at each error correction level. Based on binary/byte mode encoding.
+// at each error correction level. Based on binary/byte mode encoding; text
+// of only digits or only alphanumeric characters holds more characters.
 // See Recovery type for error correction percentages.
 const (
 	MaxBytesLow     = 2953
@@ -34,6 +39,91 @@ func maxBytes(r Recovery) int {
 	}
 }
 
+// qrMode is the QR encoding mode chosen for a string.
+type qrMode int
+
+const (
+	modeNumeric      qrMode = iota // Digits only, 10 bits per 3 characters
+	modeAlphanumeric               // 0-9, A-Z, space and $%*+-./:, 11 bits per 2 characters
+	modeByte                       // Any bytes, 8 bits each
+)
+
+// String returns the mode name.
+func (m qrMode) String() string {
+	switch m {
+	case modeNumeric:
+		return "numeric"
+	case modeAlphanumeric:
+		return "alphanumeric"
+	default:
+		return "byte"
+	}
+}
+
+// alphanumericChars is the QR alphanumeric mode character set.
+const alphanumericChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// encodingMode returns the densest mode that can encode all of data, as
+// chosen by the encoder.
+func encodingMode(data string) qrMode {
+	switch {
+	case isDigits(data):
+		return modeNumeric
+	case data != "" && strings.Trim(data, alphanumericChars) == "":
+		return modeAlphanumeric
+	default:
+		return modeByte
+	}
+}
+
+// modeBits returns the number of data bits needed for n characters in m.
+func modeBits(m qrMode, n int) int {
+	switch m {
+	case modeNumeric:
+		return n/3*10 + [3]int{0, 4, 7}[n%3]
+	case modeAlphanumeric:
+		return n/2*11 + n%2*6
+	default:
+		return n * 8
+	}
+}
+
+// maxChars returns how many characters of mode m fit in a version 40
+// symbol whose byte mode capacity is limit, such as maxBytes(r).
+func maxChars(m qrMode, limit int) int {
+	// Data codewords less the mode indicator and character count
+	bits := (limit+3)*8 - 4 - [...]int{modeNumeric: 14, modeAlphanumeric: 13, modeByte: 16}[m]
+	switch m {
+	case modeNumeric:
+		n := bits / 10 * 3
+		if r := bits % 10; r >= 7 {
+			n += 2
+		} else if r >= 4 {
+			n++
+		}
+		return n
+	case modeAlphanumeric:
+		return bits/11*2 + min(bits%11/6, 1)
+	default:
+		return bits / 8
+	}
+}
+
+// checkCapacity returns an error if data does not fit the largest QR code
+// at recovery in the mode the encoder chooses.
+func checkCapacity(data string, recovery Recovery) error {
+	mode := encodingMode(data)
+	if limit := maxChars(mode, maxBytes(recovery)); len(data) > limit {
+		if mode == modeByte {
+			return fmt.Errorf("data too large: %d bytes exceeds %d byte limit for %v recovery",
+				len(data), limit, recovery)
+		}
+		return fmt.Errorf("data too large: %d characters exceeds %d character limit for %v recovery in %v mode",
+			len(data), limit, recovery, mode)
+	}
+	return nil
+}
+
 // recoveryLevel maps Recovery to barcode qr.ErrorCorrectionLevel.
 func recoveryLevel(r Recovery) qr.ErrorCorrectionLevel {
 	switch r {
@@ -48,35 +138,158 @@ func recoveryLevel(r Recovery) qr.ErrorCorrectionLevel {
 	}
 }
 
+// canceled returns a non-nil error wrapping ctx.Err() if ctx is done.
+func canceled(ctx context.Context, stage string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s canceled: %w", stage, err)
+	}
+	return nil
+}
+
+// encodeHooks customizes encodeDetailed for typed payloads.
+type encodeHooks struct {
+	// check, if set, is called with the decoded text after the byte
+	// comparison succeeds.
+	check func(decoded string) error
+
+	// decorate, if set, draws on the rendered image before verification.
+	// symbol is the area covered by the barcode.
+	decorate func(img *image.Gray, symbol image.Rectangle)
+
+	// variant distinguishes decorated images in cache keys.
+	variant string
+
+	// color encodes data as color layers instead of a single QR code.
+	color bool
+}
+
+// symbolRect returns the area covered by a barcode of mw x mh modules
+// rendered into width x height pixels, and the pixels per module.
+func symbolRect(mw, mh, width, height int) (image.Rectangle, int, error) {
+	factor := min(width/mw, height/mh)
+	if width <= 0 || height <= 0 || factor <= 0 {
+		return image.Rectangle{}, 0, fmt.Errorf("can not scale barcode to an image smaller than %dx%d", mw, mh)
+	}
+	offsetX := (width - mw*factor) / 2
+	offsetY := (height - mh*factor) / 2
+	return image.Rect(offsetX, offsetY, offsetX+mw*factor, offsetY+mh*factor), factor, nil
+}
+
+// render draws a 2D barcode at module resolution into a pooled greyscale
+// image of width x height pixels, centered with the largest integer scale
+// that fits. Output matches barcode.Scale. Release with putGray.
+func render(bc image.Image, width, height int) (*image.Gray, error) {
+	modules := bc.Bounds()
+	mw, mh := modules.Dx(), modules.Dy()
+
+	symbol, factor, err := symbolRect(mw, mh, width, height)
+	if err != nil {
+		return nil, err
+	}
+	offsetX, offsetY := symbol.Min.X, symbol.Min.Y
+
+	img := getGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	for my := 0; my < mh; my++ {
+		for mx := 0; mx < mw; mx++ {
+			v := color.GrayModel.Convert(bc.At(modules.Min.X+mx, modules.Min.Y+my)).(color.Gray).Y
+			if v == 0xff {
+				continue
+			}
+			for y := offsetY + my*factor; y < offsetY+(my+1)*factor; y++ {
+				row := img.Pix[y*img.Stride:]
+				for x := offsetX + mx*factor; x < offsetX+(mx+1)*factor; x++ {
+					row[x] = v
+				}
+			}
+		}
+	}
+
+	return img, nil
+}
+
 // encodeAndVerify generates a QR code and verifies it decodes correctly.
+// hooks add semantic verification and decoration.
 // Returns PNG bytes and error.
-func encodeAndVerify(data string, recovery Recovery, size int) ([]byte, error) {
+func encodeAndVerify(ctx context.Context, data string, recovery Recovery, size int, verifyOutput bool, hooks encodeHooks) ([]byte, error) {
 	level := recoveryLevel(recovery)
 
+	if err := canceled(ctx, "render"); err != nil {
+		return nil, err
+	}
+
 	// Create QR code with 8-bit greyscale
 	bc, err := qr.EncodeWithColor(data, level, qr.Auto, barcode.ColorScheme8)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create QR code: %w", err)
 	}
 
+	png, err := encodeSymbol(ctx, data, bc, qrSymbology, size, size, verifyOutput, hooks)
+	if err == nil || ctx.Err() != nil || encodingMode(data) == modeByte {
+		return png, err
+	}
+
+	// The decoder misreads a few numeric and alphanumeric mode symbols,
+	// such as some Base45 text. Byte mode reads reliably, if data fits.
+	bc, berr := qr.EncodeWithColor(data, level, qr.Unicode, barcode.ColorScheme8)
+	if berr != nil {
+		return nil, err
+	}
+	return encodeSymbol(ctx, data, bc, qrSymbology, size, size, verifyOutput, hooks)
+}
+
+// encodeSymbol renders modules, a barcode at one pixel per module, into a
+// width x height image and verifies it decodes correctly with sym. This is
+// the shared core of every symbology.
+// The rendered image is verified directly and encoded to PNG once.
+// If verifyOutput is set, the final PNG bytes are verified as well.
+// ctx is checked between the render, encode and decode stages.
+func encodeSymbol(ctx context.Context, data string, modules image.Image, sym symbology, width, height int, verifyOutput bool, hooks encodeHooks) ([]byte, error) {
 	// Scale to size
-	bc, err = barcode.Scale(bc, size, size)
+	img, err := render(modules, width, height)
 	if err != nil {
-		return nil, fmt.Errorf("failed to scale QR code: %w", err)
+		return nil, fmt.Errorf("failed to scale %s: %w", sym.name, err)
+	}
+	defer putGray(img)
+
+	if hooks.decorate != nil {
+		b := modules.Bounds()
+		symbol, _, _ := symbolRect(b.Dx(), b.Dy(), width, height)
+		hooks.decorate(img, symbol)
+	}
+
+	return encodeImage(ctx, data, img, sym, verifyOutput, hooks)
+}
+
+// encodeImage verifies that the rendered img decodes correctly with sym
+// and encodes it to PNG. If verifyOutput is set, the PNG bytes are
+// verified as well.
+func encodeImage(ctx context.Context, data string, img image.Image, sym symbology, verifyOutput bool, hooks encodeHooks) ([]byte, error) {
+	// Verify by decoding the rendered image
+	if err := verifySymbol(ctx, img, data, sym, hooks.check); err != nil {
+		return nil, err
+	}
+
+	if err := canceled(ctx, "encode"); err != nil {
+		return nil, err
 	}
 
 	// Encode to PNG
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, bc); err != nil {
+	png, err := encodePNG(img)
+	if err != nil {
 		return nil, fmt.Errorf("failed to generate PNG: %w", err)
 	}
 
-	// Verify by decoding
-	if err := Verify(buf.Bytes(), data); err != nil {
-		return nil, err
+	if verifyOutput {
+		if err := verifySymbolReader(ctx, bytes.NewReader(png), data, sym, hooks.check); err != nil {
+			return nil, err
+		}
 	}
 
-	return buf.Bytes(), nil
+	return png, nil
 }
 
 // Encode generates a verified QR code PNG image.
@@ -84,7 +297,13 @@ func encodeAndVerify(data string, recovery Recovery, size int) ([]byte, error) {
 //
 // If opts is nil or opts.Recovery is zero, uses Medium recovery (15%).
 func Encode(data string, opts *EncodeOptions) ([]byte, error) {
-	result, err := EncodeDetailed(data, opts)
+	return EncodeContext(context.Background(), data, opts)
+}
+
+// EncodeContext is like Encode but honors cancellation and deadlines of ctx.
+// If ctx is done before verification completes, the returned error wraps ctx.Err().
+func EncodeContext(ctx context.Context, data string, opts *EncodeOptions) ([]byte, error) {
+	result, err := EncodeDetailedContext(ctx, data, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -105,29 +324,86 @@ func EncodeToFile(data string, filename string, opts *EncodeOptions) error {
 	return nil
 }
 
+// EncodeTo generates a verified QR code and writes the PNG image to w.
+// Nothing is written to w unless verification succeeds.
+func EncodeTo(w io.Writer, data string, opts *EncodeOptions) error {
+	png, err := Encode(data, opts)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(png); err != nil {
+		return fmt.Errorf("failed to write PNG: %w", err)
+	}
+
+	return nil
+}
+
 // EncodeDetailed returns the verified QR code with metadata.
 func EncodeDetailed(data string, opts *EncodeOptions) (*Result, error) {
+	return EncodeDetailedContext(context.Background(), data, opts)
+}
+
+// EncodeDetailedContext is like EncodeDetailed but honors cancellation and
+// deadlines of ctx.
+func EncodeDetailedContext(ctx context.Context, data string, opts *EncodeOptions) (*Result, error) {
+	return encodeDetailed(ctx, data, opts, encodeHooks{})
+}
+
+// encodeDetailed implements EncodeDetailedContext with hooks for typed payloads.
+func encodeDetailed(ctx context.Context, data string, opts *EncodeOptions, hooks encodeHooks) (*Result, error) {
 	size := 256
-	recovery := Medium
+	recovery := opts.recovery()
+	verifyOutput := false
+	kanji := false
+	var cache Cache
+	verifyCached := false
 	if opts != nil {
+		verifyOutput = opts.VerifyOutput
+		kanji = opts.Kanji
+		cache = opts.Cache
+		verifyCached = opts.VerifyCached
 		if opts.Size > 0 {
 			size = opts.Size
 		}
-		if opts.Recovery != 0 {
-			recovery = opts.Recovery
-		}
 	}
 
-	if len(data) > maxBytes(recovery) {
-		return nil, fmt.Errorf("data too large: %d bytes exceeds %d byte limit for %v recovery",
-			len(data), maxBytes(recovery), recovery)
+	// Color layers and kanji mode replace the plain QR code
+	capacity, encode, sym, variant := checkCapacity, encodeAndVerify, qrSymbology, hooks.variant
+	switch {
+	case hooks.color:
+		capacity, encode, sym = checkColorCapacity, encodeColor, colorSymbology(ctx, splitLayers(data, len(colorLayers)))
+	case kanji:
+		capacity, encode, sym, variant = checkKanjiCapacity, encodeKanji, kanjiSymbology, variant+"/kanji"
+	}
+	if err := capacity(data, recovery); err != nil {
+		return nil, err
+	}
+
+	var key string
+	if cache != nil {
+		key = cacheKey(data, recovery, size, variant)
+		if png, ok := cachedImage(ctx, cache, key, data, sym, verifyCached, hooks.check); ok {
+			return &Result{
+				Image:    png,
+				Data:     data,
+				Recovery: recovery,
+				Size:     size,
+				Cached:   true,
+			}, nil
+		}
 	}
 
-	png, err := encodeAndVerify(data, recovery, size)
+	png, err := encode(ctx, data, recovery, size, verifyOutput, hooks)
 	if err != nil {
 		return nil, err
 	}
 
+	if cache != nil {
+		// Caching is best effort; a failed Put does not fail the encode.
+		_ = cache.Put(ctx, key, png)
+	}
+
 	return &Result{
 		Image:    png,
 		Data:     data,
@@ -135,3 +411,28 @@ func EncodeDetailed(data string, opts *EncodeOptions) (*Result, error) {
 		Size:     size,
 	}, nil
 }
+
+// cachedImage returns the image stored in cache for key. If recheck is set,
+// the image must also verify against data when read with sym; otherwise
+// check, if set, is run on data. Cache errors and failed checks are
+// treated as misses so the caller re-encodes.
+func cachedImage(ctx context.Context, cache Cache, key, data string, sym symbology, recheck bool, check func(string) error) ([]byte, bool) {
+	png, err := cache.Get(ctx, key)
+	if err != nil {
+		return nil, false
+	}
+
+	if recheck {
+		if err := verifySymbolReader(ctx, bytes.NewReader(png), data, sym, check); err != nil {
+			return nil, false
+		}
+	} else if check != nil {
+		// The image decoded to data when it was stored, but payload
+		// checks run on every hit
+		if err := check(data); err != nil {
+			return nil, false
+		}
+	}
+
+	return png, true
+}
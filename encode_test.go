@@ -2,11 +2,16 @@ package qrverify
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"image/color"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/boombuler/barcode"
 	"github.com/boombuler/barcode/qr"
 )
 
@@ -371,7 +376,7 @@ func TestEdgeCases(t *testing.T) {
 func TestEncodeTooLarge(t *testing.T) {
 	// QR code version 40 with Low recovery can hold ~2953 bytes
 	// Create data larger than maximum capacity
-	largeData := strings.Repeat("A", 3000)
+	largeData := strings.Repeat("a", 3000)
 
 	_, err := Encode(largeData, &EncodeOptions{Recovery: Low})
 	if err == nil {
@@ -385,6 +390,62 @@ func TestEncodeTooLarge(t *testing.T) {
 	}
 }
 
+func TestEncodeByteModeFallback(t *testing.T) {
+	// Base45 text of a sealed payload whose alphanumeric mode symbol the
+	// decoder can not read
+	data := "V50OJ0W9EWKFS$IL B3ROOL6.FR$SC8L4K1DB5QJNR/I7.T68E99G052AZYAX1H6Y61%U$RLVCJ8QDD32GXI/FGV5EJYG"
+	bc, err := qr.EncodeWithColor(data, qr.M, qr.Auto, barcode.ColorScheme8)
+	if err != nil {
+		t.Fatalf("qr.Encode failed: %v", err)
+	}
+	if _, err := encodeSymbol(context.Background(), data, bc, qrSymbology, 256, 256, false, encodeHooks{}); err == nil {
+		t.Skip("alphanumeric mode symbol decodes; nothing to fall back from")
+	}
+
+	png, err := Encode(data, nil)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := Verify(png, data); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+}
+
+func TestEncodeModeCapacity(t *testing.T) {
+	tests := []struct {
+		name  string
+		char  string
+		limit int
+	}{
+		{"numeric", "7", 5596},
+		{"alphanumeric", "A", 3391},
+		{"byte", "a", MaxBytesMedium},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maxChars(encodingMode(tt.char), MaxBytesMedium); got != tt.limit {
+				t.Errorf("maxChars() = %d, want %d", got, tt.limit)
+			}
+			if err := checkCapacity(strings.Repeat(tt.char, tt.limit), Medium); err != nil {
+				t.Errorf("Expected %d characters to fit, got: %v", tt.limit, err)
+			}
+			if err := checkCapacity(strings.Repeat(tt.char, tt.limit+1), Medium); err == nil {
+				t.Errorf("Expected error for %d characters, got nil", tt.limit+1)
+			}
+		})
+	}
+
+	// Beyond the byte limit, within the alphanumeric limit
+	data := strings.Repeat("ABC123", 550)
+	result, err := EncodeDetailed(data, nil)
+	if err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+	if err := Verify(result.Image, data); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+}
+
 func TestRecoveryLevel(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -432,7 +493,7 @@ func TestEncodeAndVerify(t *testing.T) {
 	data := "test data for encodeAndVerify"
 	size := 256
 
-	png, err := encodeAndVerify(data, Medium, size)
+	png, err := encodeAndVerify(context.Background(), data, Medium, size, false, encodeHooks{})
 	if err != nil {
 		t.Fatalf("encodeAndVerify failed: %v", err)
 	}
@@ -449,7 +510,7 @@ func TestEncodeAndVerify(t *testing.T) {
 
 func TestEncodeAndVerifyInvalidData(t *testing.T) {
 	// Empty string should work with new library
-	_, err := encodeAndVerify("", Medium, 256)
+	_, err := encodeAndVerify(context.Background(), "", Medium, 256, false, encodeHooks{})
 	if err != nil {
 		t.Fatalf("Unexpected error for empty data: %v", err)
 	}
@@ -506,7 +567,7 @@ func TestEncodeVerificationFailure(t *testing.T) {
 	// This test ensures that if verification fails, we get an error
 	// We can't easily force a verification failure with real QR codes,
 	// but we can test the error path by using data that's too large
-	largeData := strings.Repeat("X", 4000)
+	largeData := strings.Repeat("x", 4000)
 
 	_, err := Encode(largeData, &EncodeOptions{Recovery: Low})
 	if err == nil {
@@ -622,6 +683,7 @@ func TestEncodeEmptyOptions(t *testing.T) {
 
 func BenchmarkEncode(b *testing.B) {
 	data := "benchmark test data"
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		_, err := Encode(data, nil)
 		if err != nil {
@@ -630,6 +692,37 @@ func BenchmarkEncode(b *testing.B) {
 	}
 }
 
+// BenchmarkEncodePNGRoundTrip measures the previous pipeline, which encoded
+// to PNG and decoded the PNG again before verifying, as a baseline for
+// BenchmarkEncode.
+func BenchmarkEncodePNGRoundTrip(b *testing.B) {
+	data := "benchmark test data"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		png, err := generateTestQR(data, qr.M, 256)
+		if err != nil {
+			b.Fatalf("generateTestQR failed: %v", err)
+		}
+		if err := Verify(png, data); err != nil {
+			b.Fatalf("Verify failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkEncodeVerifyOutput measures encoding with the final PNG bytes
+// verified as well.
+func BenchmarkEncodeVerifyOutput(b *testing.B) {
+	data := "benchmark test data"
+	opts := &EncodeOptions{VerifyOutput: true}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := Encode(data, opts)
+		if err != nil {
+			b.Fatalf("Encode failed: %v", err)
+		}
+	}
+}
+
 func BenchmarkEncodeWithOptions(b *testing.B) {
 	data := "benchmark test data"
 	opts := &EncodeOptions{
@@ -812,7 +905,7 @@ func TestEncodeDetailedDataTooLarge(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			largeData := strings.Repeat("X", tt.dataSize)
+			largeData := strings.Repeat("x", tt.dataSize)
 			opts := &EncodeOptions{Recovery: tt.recovery}
 
 			_, err := EncodeDetailed(largeData, opts)
@@ -887,7 +980,7 @@ func TestEncodeEdgeCaseSizes(t *testing.T) {
 func TestEncodeToFileErrors(t *testing.T) {
 	t.Run("encode error propagates", func(t *testing.T) {
 		// Use data that's too large to trigger encode error
-		largeData := strings.Repeat("X", MaxBytesLow+100)
+		largeData := strings.Repeat("x", MaxBytesLow+100)
 		tempDir := t.TempDir()
 		filename := filepath.Join(tempDir, "test.png")
 
@@ -902,3 +995,134 @@ func TestEncodeToFileErrors(t *testing.T) {
 		}
 	})
 }
+
+// TestEncodeContext tests that EncodeContext honors cancellation and deadlines
+func TestEncodeContext(t *testing.T) {
+	data := "context test"
+
+	t.Run("background context succeeds", func(t *testing.T) {
+		png, err := EncodeContext(context.Background(), data, nil)
+		if err != nil {
+			t.Fatalf("EncodeContext failed: %v", err)
+		}
+
+		if err := Verify(png, data); err != nil {
+			t.Errorf("Verification failed: %v", err)
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := EncodeContext(ctx, data, nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got: %v", err)
+		}
+	})
+
+	t.Run("expired deadline", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+
+		_, err := EncodeDetailedContext(ctx, data, nil)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected context.DeadlineExceeded, got: %v", err)
+		}
+	})
+}
+
+// TestEncodeVerifyOutput tests encoding with final PNG verification enabled
+func TestEncodeVerifyOutput(t *testing.T) {
+	data := "paranoid test"
+
+	result, err := EncodeDetailed(data, &EncodeOptions{VerifyOutput: true})
+	if err != nil {
+		t.Fatalf("EncodeDetailed with VerifyOutput failed: %v", err)
+	}
+
+	plain, err := Encode(data, nil)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if !bytes.Equal(result.Image, plain) {
+		t.Error("VerifyOutput should not change the generated PNG")
+	}
+}
+
+// TestRenderMatchesScale ensures render produces the same pixels as barcode.Scale
+func TestRenderMatchesScale(t *testing.T) {
+	bc, err := qr.EncodeWithColor("render test", qr.M, qr.Auto, barcode.ColorScheme8)
+	if err != nil {
+		t.Fatalf("failed to create QR code: %v", err)
+	}
+
+	for _, size := range []int{21, 100, 256, 333} {
+		scaled, err := barcode.Scale(bc, size, size)
+		if err != nil {
+			t.Fatalf("Scale failed: %v", err)
+		}
+
+		img, err := render(bc, size, size)
+		if err != nil {
+			t.Fatalf("render failed: %v", err)
+		}
+
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				want := color.GrayModel.Convert(scaled.At(x, y)).(color.Gray)
+				if got := img.GrayAt(x, y); got != want {
+					t.Fatalf("size %d: pixel (%d,%d) = %v, want %v", size, x, y, got, want)
+				}
+			}
+		}
+		putGray(img)
+	}
+
+	if _, err := render(bc, 10, 10); err == nil {
+		t.Error("Expected error rendering smaller than module count")
+	}
+}
+
+// failingWriter records writes and always fails.
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.New("write failed")
+}
+
+// TestEncodeTo tests streaming output to an io.Writer
+func TestEncodeTo(t *testing.T) {
+	data := "https://example.com/stream"
+
+	var buf bytes.Buffer
+	if err := EncodeTo(&buf, data, nil); err != nil {
+		t.Fatalf("EncodeTo failed: %v", err)
+	}
+
+	if err := VerifyFrom(&buf, data); err != nil {
+		t.Errorf("VerifyFrom failed: %v", err)
+	}
+
+	t.Run("no write on encode error", func(t *testing.T) {
+		w := &failingWriter{}
+		err := EncodeTo(w, strings.Repeat("x", MaxBytesLow+1), &EncodeOptions{Recovery: Low})
+		if err == nil {
+			t.Fatal("Expected error for oversized data, got nil")
+		}
+		if w.writes != 0 {
+			t.Errorf("Expected no writes, got %d", w.writes)
+		}
+	})
+
+	t.Run("write error propagates", func(t *testing.T) {
+		w := &failingWriter{}
+		if err := EncodeTo(w, data, nil); err == nil {
+			t.Fatal("Expected write error, got nil")
+		}
+	})
+}
@@ -0,0 +1,326 @@
+package qrverify
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxBytesEPC is the maximum size of an EPC069-12 payload.
+const MaxBytesEPC = 331
+
+// EPCPayment is a SEPA credit transfer payload per EPC069-12 (GiroCode).
+// The standard mandates Medium recovery. Text fields are limited to the
+// basic SEPA Latin character set: letters, digits, space and /-?:().,'+
+type EPCPayment struct {
+	BIC         string // Beneficiary bank BIC, optional within the EEA
+	Name        string // Beneficiary name, required, at most 70 characters
+	IBAN        string // Beneficiary account, required; spaces are ignored
+	AmountCents int64  // Amount in euro cents, zero omits the amount
+	Purpose     string // ISO 20022 purpose code, 4 uppercase letters or digits
+	Reference   string // Structured ISO 11649 creditor reference (RF...)
+	Text        string // Unstructured remittance, at most 140 characters
+	Information string // Beneficiary to originator information, at most 70 characters
+}
+
+// RequiredRecovery returns Medium, the level mandated by EPC069-12.
+func (p *EPCPayment) RequiredRecovery() Recovery {
+	return Medium
+}
+
+// Encode returns the EPC069-12 payload.
+func (p *EPCPayment) Encode(limit int) (string, error) {
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+
+	version := "002"
+	if p.BIC != "" {
+		version = "001"
+	}
+	amount := ""
+	if p.AmountCents > 0 {
+		amount = "EUR" + formatCents(p.AmountCents)
+	}
+
+	lines := []string{
+		"BCD",
+		version,
+		"1", // UTF-8
+		"SCT",
+		p.BIC,
+		p.Name,
+		normalizeIBAN(p.IBAN),
+		amount,
+		p.Purpose,
+		p.Reference,
+		p.Text,
+		p.Information,
+	}
+	// Trailing empty fields may be omitted
+	for lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	s := strings.Join(lines, "\n")
+	if n := min(limit, MaxBytesEPC); len(s) > n {
+		return "", fmt.Errorf("data too large: EPC payment is %d bytes, exceeds %d byte limit", len(s), n)
+	}
+	return s, nil
+}
+
+// Check parses decoded as an EPC069-12 payload and compares its fields with p.
+func (p *EPCPayment) Check(decoded string) error {
+	got, err := ParseEPCPayment(decoded)
+	if err != nil {
+		return err
+	}
+
+	fields := []struct {
+		name           string
+		original, read string
+	}{
+		{"EPCPayment.BIC", p.BIC, got.BIC},
+		{"EPCPayment.Name", p.Name, got.Name},
+		{"EPCPayment.IBAN", normalizeIBAN(p.IBAN), got.IBAN},
+		{"EPCPayment.AmountCents", strconv.FormatInt(p.AmountCents, 10), strconv.FormatInt(got.AmountCents, 10)},
+		{"EPCPayment.Purpose", p.Purpose, got.Purpose},
+		{"EPCPayment.Reference", p.Reference, got.Reference},
+		{"EPCPayment.Text", p.Text, got.Text},
+		{"EPCPayment.Information", p.Information, got.Information},
+	}
+	for _, f := range fields {
+		if err := checkField(f.name, f.original, f.read); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks p against the EPC069-12 field constraints.
+func (p *EPCPayment) validate() error {
+	if p.BIC != "" {
+		if err := validateBIC(p.BIC); err != nil {
+			return fmt.Errorf("invalid EPC payment: %w", err)
+		}
+	}
+	if p.Name == "" {
+		return errors.New("invalid EPC payment: beneficiary name is required")
+	}
+	if err := validateIBAN(p.IBAN); err != nil {
+		return fmt.Errorf("invalid EPC payment: %w", err)
+	}
+	if p.AmountCents < 0 || p.AmountCents > 99999999999 {
+		return errors.New("invalid EPC payment: amount must be between 0.01 and 999999999.99")
+	}
+	if p.Reference != "" && p.Text != "" {
+		return errors.New("invalid EPC payment: reference and text are mutually exclusive")
+	}
+	if p.Purpose != "" && (len(p.Purpose) != 4 || !isUpperAlnum(p.Purpose)) {
+		return errors.New("invalid EPC payment: purpose must be 4 uppercase letters or digits")
+	}
+	if p.Reference != "" {
+		if err := validateCreditorReference(p.Reference); err != nil {
+			return fmt.Errorf("invalid EPC payment: %w", err)
+		}
+	}
+
+	limits := []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"name", p.Name, 70},
+		{"reference", p.Reference, 35},
+		{"text", p.Text, 140},
+		{"information", p.Information, 70},
+	}
+	for _, l := range limits {
+		if !isSEPAText(l.value) {
+			return fmt.Errorf("invalid EPC payment: %s must use the SEPA Latin character set", l.name)
+		}
+		if len(l.value) > l.max {
+			return fmt.Errorf("invalid EPC payment: %s exceeds %d characters", l.name, l.max)
+		}
+	}
+	return nil
+}
+
+// ParseEPCPayment parses an EPC069-12 payload.
+func ParseEPCPayment(s string) (*EPCPayment, error) {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if len(lines) < 7 {
+		return nil, errors.New("invalid EPC payment: too few lines")
+	}
+	for len(lines) < 12 {
+		lines = append(lines, "")
+	}
+	if len(lines) > 12 {
+		return nil, errors.New("invalid EPC payment: too many lines")
+	}
+
+	if lines[0] != "BCD" {
+		return nil, errors.New("invalid EPC payment: missing BCD service tag")
+	}
+	if lines[1] != "001" && lines[1] != "002" {
+		return nil, errors.New("invalid EPC payment: unsupported version")
+	}
+	if lines[2] != "1" {
+		return nil, errors.New("invalid EPC payment: unsupported character set")
+	}
+	if lines[3] != "SCT" {
+		return nil, errors.New("invalid EPC payment: unsupported identification code")
+	}
+	if lines[1] == "001" && lines[4] == "" {
+		return nil, errors.New("invalid EPC payment: BIC is required in version 001")
+	}
+
+	p := &EPCPayment{
+		BIC:         lines[4],
+		Name:        lines[5],
+		IBAN:        lines[6],
+		Purpose:     lines[8],
+		Reference:   lines[9],
+		Text:        lines[10],
+		Information: lines[11],
+	}
+	if lines[7] != "" {
+		amount, ok := strings.CutPrefix(lines[7], "EUR")
+		if !ok {
+			return nil, errors.New("invalid EPC payment: amount must be in EUR")
+		}
+		cents, err := parseCents(amount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EPC payment: %w", err)
+		}
+		p.AmountCents = cents
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// formatCents formats an amount in cents with two decimals.
+func formatCents(cents int64) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
+
+// parseCents parses a decimal amount with at most two decimals into cents.
+func parseCents(s string) (int64, error) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" || len(frac) > 2 || strings.Trim(whole+frac, "0123456789") != "" {
+		return 0, errors.New("malformed amount")
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	cents, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, errors.New("malformed amount")
+	}
+	return cents, nil
+}
+
+// isSEPAText reports whether s uses only the basic SEPA Latin character set.
+func isSEPAText(s string) bool {
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune(" /-?:().,'+", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ibanLengths lists IBAN lengths for SEPA countries.
+var ibanLengths = map[string]int{
+	"AD": 24, "AT": 20, "BE": 16, "BG": 22, "CH": 21, "CY": 28, "CZ": 24,
+	"DE": 22, "DK": 18, "EE": 20, "ES": 24, "FI": 18, "FR": 27, "GB": 22,
+	"GI": 23, "GR": 27, "HR": 21, "HU": 28, "IE": 22, "IS": 26, "IT": 27,
+	"LI": 21, "LT": 20, "LU": 20, "LV": 21, "MC": 27, "MT": 31, "NL": 18,
+	"NO": 15, "PL": 28, "PT": 25, "RO": 24, "SE": 24, "SI": 19, "SK": 24,
+	"SM": 27, "VA": 22,
+}
+
+// normalizeIBAN removes spaces and uppercases iban.
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+}
+
+// validateIBAN checks the format, country length and ISO 7064 mod 97-10
+// checksum of iban.
+func validateIBAN(iban string) error {
+	iban = normalizeIBAN(iban)
+	if len(iban) < 15 || len(iban) > 34 {
+		return errors.New("IBAN has invalid length")
+	}
+	if !isUpperAlpha(iban[:2]) || !isDigits(iban[2:4]) || !isUpperAlnum(iban[4:]) {
+		return errors.New("IBAN has invalid format")
+	}
+	if n, ok := ibanLengths[iban[:2]]; ok && len(iban) != n {
+		return errors.New("IBAN has invalid length for country")
+	}
+	if mod97(iban[4:]+iban[:4]) != 1 {
+		return errors.New("IBAN checksum mismatch")
+	}
+	return nil
+}
+
+// validateBIC checks the format of an 8 or 11 character BIC.
+func validateBIC(bic string) error {
+	if len(bic) != 8 && len(bic) != 11 {
+		return errors.New("BIC must be 8 or 11 characters")
+	}
+	if !isUpperAlpha(bic[:6]) || !isUpperAlnum(bic[6:]) {
+		return errors.New("BIC has invalid format")
+	}
+	return nil
+}
+
+// validateCreditorReference checks an ISO 11649 creditor reference.
+func validateCreditorReference(ref string) error {
+	if len(ref) < 5 || len(ref) > 25 || !strings.HasPrefix(ref, "RF") ||
+		!isDigits(ref[2:4]) || !isUpperAlnum(ref[4:]) {
+		return errors.New("creditor reference has invalid format")
+	}
+	if mod97(ref[4:]+ref[:4]) != 1 {
+		return errors.New("creditor reference checksum mismatch")
+	}
+	return nil
+}
+
+// mod97 returns s modulo 97 with letters converted to numbers (A=10 ... Z=35)
+// per ISO 7064. s must be uppercase alphanumeric.
+func mod97(s string) int {
+	n := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			n = (n*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			n = (n*100 + int(r-'A') + 10) % 97
+		}
+	}
+	return n
+}
+
+// isDigits reports whether s is non-empty and all ASCII digits.
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// isUpperAlpha reports whether s is non-empty and all ASCII uppercase letters.
+func isUpperAlpha(s string) bool {
+	return s != "" && strings.Trim(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+}
+
+// isUpperAlnum reports whether s is non-empty and all ASCII digits or
+// uppercase letters.
+func isUpperAlnum(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+}
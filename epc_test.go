@@ -0,0 +1,153 @@
+package qrverify
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEPCPaymentEncode(t *testing.T) {
+	p := &EPCPayment{
+		BIC:         "BHBLDEHHXXX",
+		Name:        "Franz Mustermann",
+		IBAN:        "DE89 3704 0044 0532 0130 00",
+		AmountCents: 1230,
+		Text:        "Rechnung 123",
+	}
+
+	got, err := p.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	want := "BCD\n001\n1\nSCT\nBHBLDEHHXXX\nFranz Mustermann\nDE89370400440532013000\nEUR12.30\n\n\nRechnung 123"
+	if got != want {
+		t.Errorf("Encode() = %q, want %q", got, want)
+	}
+
+	if err := p.Check(got); err != nil {
+		t.Errorf("Check failed: %v", err)
+	}
+}
+
+func TestEPCPaymentVersion002(t *testing.T) {
+	p := &EPCPayment{
+		Name:      "Red Cross",
+		IBAN:      "BE72000000001616",
+		Purpose:   "CHAR",
+		Reference: "RF18539007547034",
+	}
+
+	got, err := p.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.HasPrefix(got, "BCD\n002\n1\nSCT\n\n") {
+		t.Errorf("Expected version 002 without BIC, got %q", got)
+	}
+
+	parsed, err := ParseEPCPayment(got)
+	if err != nil {
+		t.Fatalf("ParseEPCPayment failed: %v", err)
+	}
+	if *parsed != *p {
+		t.Errorf("ParseEPCPayment() = %+v, want %+v", *parsed, *p)
+	}
+}
+
+func TestEPCPaymentInvalid(t *testing.T) {
+	valid := EPCPayment{Name: "A", IBAN: "DE89370400440532013000"}
+
+	tests := []struct {
+		name   string
+		modify func(p *EPCPayment)
+	}{
+		{"missing name", func(p *EPCPayment) { p.Name = "" }},
+		{"bad IBAN checksum", func(p *EPCPayment) { p.IBAN = "DE72370400440532013000" }},
+		{"bad IBAN length", func(p *EPCPayment) { p.IBAN = "DE8937040044053201300" }},
+		{"bad BIC", func(p *EPCPayment) { p.BIC = "BHBL" }},
+		{"negative amount", func(p *EPCPayment) { p.AmountCents = -1 }},
+		{"amount too large", func(p *EPCPayment) { p.AmountCents = 100000000000 }},
+		{"name too long", func(p *EPCPayment) { p.Name = strings.Repeat("x", 71) }},
+		{"bad reference", func(p *EPCPayment) { p.Reference = "RF00539007547034" }},
+		{"reference and text", func(p *EPCPayment) { p.Reference = "RF18539007547034"; p.Text = "x" }},
+		{"multi-line text", func(p *EPCPayment) { p.Text = "a\nb" }},
+		{"non-SEPA name", func(p *EPCPayment) { p.Name = "Franz Mustermänn" }},
+		{"non-SEPA text", func(p *EPCPayment) { p.Text = "Rechnung #123" }},
+		{"short purpose", func(p *EPCPayment) { p.Purpose = "GD" }},
+		{"lowercase purpose", func(p *EPCPayment) { p.Purpose = "gdds" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := valid
+			tt.modify(&p)
+			if _, err := p.Encode(MaxBytesMedium); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestParseCents(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"12.30", 1230, false},
+		{"12.3", 1230, false},
+		{"12", 1200, false},
+		{"0.01", 1, false},
+		{"999999999.99", 99999999999, false},
+		{"1.234", 0, true},
+		{"", 0, true},
+		{"1e3", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseCents(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseCents(%q) = %d, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestEPCPaymentCheckMismatch(t *testing.T) {
+	p := &EPCPayment{Name: "A", IBAN: "DE89370400440532013000", AmountCents: 100}
+	other := *p
+	other.AmountCents = 1000
+
+	text, err := other.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	var fieldErr *FieldError
+	if err := p.Check(text); !errors.As(err, &fieldErr) || fieldErr.Field != "EPCPayment.AmountCents" {
+		t.Errorf("Expected FieldError for EPCPayment.AmountCents, got: %v", err)
+	}
+}
+
+func TestEncodePayloadEPC(t *testing.T) {
+	p := &EPCPayment{
+		BIC:         "BHBLDEHHXXX",
+		Name:        "Franz Mustermann",
+		IBAN:        "DE89370400440532013000",
+		AmountCents: 999,
+	}
+
+	result, err := EncodePayload(p, nil)
+	if err != nil {
+		t.Fatalf("EncodePayload failed: %v", err)
+	}
+	if result.Recovery != Medium {
+		t.Errorf("Recovery = %v, want Medium", result.Recovery)
+	}
+	if err := VerifyPayload(result.Image, p); err != nil {
+		t.Errorf("VerifyPayload failed: %v", err)
+	}
+
+	// Other recovery levels violate the standard
+	if _, err := EncodePayload(p, &EncodeOptions{Recovery: High}); err == nil {
+		t.Error("Expected error for High recovery")
+	}
+}
@@ -19,3 +19,73 @@ func (e *VerificationError) Detail() string {
 	return fmt.Sprintf("verification failed: decoded %q does not match original %q",
 		e.Decoded, e.Original)
 }
+
+// FieldError indicates a decoded payload field does not match the original.
+type FieldError struct {
+	Field    string // Payload field name, such as "WiFi.SSID"
+	Original string // What was encoded
+	Decoded  string // What was decoded
+}
+
+// Error returns a safe error message without exposing field content.
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("verification failed: decoded %s does not match original", e.Field)
+}
+
+// Detail returns an error message with full field content for debugging.
+func (e *FieldError) Detail() string {
+	return fmt.Sprintf("verification failed: decoded %s %q does not match original %q",
+		e.Field, e.Decoded, e.Original)
+}
+
+// checkField returns a FieldError if original and decoded differ.
+func checkField(field, original, decoded string) error {
+	if original != decoded {
+		return &FieldError{Field: field, Original: original, Decoded: decoded}
+	}
+	return nil
+}
+
+// SignatureError indicates a signed payload's signature does not match its
+// content, so the payload was tampered with or signed by a different key.
+type SignatureError struct {
+	KeyID string // Key ID named by the payload
+}
+
+// Error returns a message naming the key ID.
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("signature verification failed: payload does not match signature for key %q", e.KeyID)
+}
+
+// UnknownKeyError indicates a signed payload names a key ID that is not in
+// the verifier's key set.
+type UnknownKeyError struct {
+	KeyID string // Key ID named by the payload
+}
+
+// Error returns a message naming the key ID.
+func (e *UnknownKeyError) Error() string {
+	return fmt.Sprintf("signature verification failed: unknown key %q", e.KeyID)
+}
+
+// WrongKeyError indicates a sealed payload was encrypted with a different
+// key than the one supplied.
+type WrongKeyError struct {
+	KeyID string // Key ID stored in the payload
+}
+
+// Error returns a message naming the key ID.
+func (e *WrongKeyError) Error() string {
+	return fmt.Sprintf("decryption failed: payload was sealed with a different key (key ID %q)", e.KeyID)
+}
+
+// TamperError indicates a sealed payload failed authentication with the
+// correct key, so its content was modified.
+type TamperError struct {
+	KeyID string // Key ID stored in the payload
+}
+
+// Error returns a message naming the key ID.
+func (e *TamperError) Error() string {
+	return fmt.Sprintf("decryption failed: payload sealed with key ID %q was modified", e.KeyID)
+}
@@ -1,6 +1,7 @@
 package qrverify
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -101,3 +102,24 @@ func TestVerificationError_Detail(t *testing.T) {
 		})
 	}
 }
+
+func TestFieldError(t *testing.T) {
+	err := &FieldError{Field: "WiFi.Password", Original: "secret", Decoded: "other"}
+
+	msg := err.Error()
+	if strings.Contains(msg, "secret") || strings.Contains(msg, "other") {
+		t.Errorf("Error() exposes field content: %s", msg)
+	}
+	if !strings.Contains(msg, "WiFi.Password") {
+		t.Errorf("Error() should name the field: %s", msg)
+	}
+
+	detail := err.Detail()
+	if !strings.Contains(detail, "secret") || !strings.Contains(detail, "other") {
+		t.Errorf("Detail() should include field content: %s", detail)
+	}
+
+	if checkField("f", "a", "a") != nil {
+		t.Error("checkField should return nil for equal values")
+	}
+}
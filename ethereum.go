@@ -0,0 +1,236 @@
+package qrverify
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// EthereumPayment is an Ethereum payment request in the EIP-681 ethereum:
+// URI format, either a native transfer or an ERC-20 token transfer.
+// ENS names are not supported.
+type EthereumPayment struct {
+	Address string   // Recipient, hex with 0x prefix, required
+	ChainID uint64   // EIP-155 chain ID, zero omits it (mainnet)
+	Value   *big.Int // Amount in wei, or token base units if Token is set; nil omits it
+	Token   string   // ERC-20 contract address, optional
+}
+
+// Encode returns the ethereum: URI with EIP-55 checksummed addresses and
+// the amount as an integer.
+func (p *EthereumPayment) Encode(limit int) (string, error) {
+	if err := validateEthereumAddress(p.Address); err != nil {
+		return "", fmt.Errorf("invalid Ethereum payment: %w", err)
+	}
+	if p.Token != "" {
+		if err := validateEthereumAddress(p.Token); err != nil {
+			return "", fmt.Errorf("invalid Ethereum payment: token %w", err)
+		}
+	}
+	if p.Value != nil && p.Value.Sign() < 0 {
+		return "", errors.New("invalid Ethereum payment: value must not be negative")
+	}
+
+	var b strings.Builder
+	b.WriteString("ethereum:")
+	if p.Token != "" {
+		b.WriteString(eip55(p.Token))
+	} else {
+		b.WriteString(eip55(p.Address))
+	}
+	if p.ChainID != 0 {
+		b.WriteString("@" + strconv.FormatUint(p.ChainID, 10))
+	}
+
+	value := p.value()
+	if p.Token != "" {
+		b.WriteString("/transfer?address=" + eip55(p.Address))
+		if value != "" {
+			b.WriteString("&uint256=" + value)
+		}
+	} else if value != "" {
+		b.WriteString("?value=" + value)
+	}
+	return checkLimit("ethereum URI", b.String(), limit)
+}
+
+// Check parses decoded as an ethereum: URI and compares its fields with p.
+// Addresses compare by their checksummed form.
+func (p *EthereumPayment) Check(decoded string) error {
+	got, err := ParseEthereumPayment(decoded)
+	if err != nil {
+		return err
+	}
+
+	fields := []struct {
+		name           string
+		original, read string
+	}{
+		{"EthereumPayment.Address", eip55(p.Address), eip55(got.Address)},
+		{"EthereumPayment.ChainID", strconv.FormatUint(p.ChainID, 10), strconv.FormatUint(got.ChainID, 10)},
+		{"EthereumPayment.Value", p.value(), got.value()},
+		{"EthereumPayment.Token", eip55(p.Token), eip55(got.Token)},
+	}
+	for _, f := range fields {
+		if err := checkField(f.name, f.original, f.read); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value returns p.Value in decimal, or "" if it is nil or zero.
+func (p *EthereumPayment) value() string {
+	if p.Value == nil || p.Value.Sign() == 0 {
+		return ""
+	}
+	return p.Value.String()
+}
+
+// ParseEthereumPayment parses an EIP-681 ethereum: URI for a native
+// transfer or an ERC-20 transfer call. Amounts in scientific notation
+// such as 1.5e18 are accepted if they are whole numbers.
+func ParseEthereumPayment(s string) (*EthereumPayment, error) {
+	body, ok := cutScheme(s, "ethereum")
+	if !ok {
+		return nil, errors.New("invalid Ethereum payment: missing ethereum: scheme")
+	}
+	body = strings.TrimPrefix(body, "pay-")
+	path, query, _ := strings.Cut(body, "?")
+	path, function, _ := strings.Cut(path, "/")
+	target, chain, hasChain := strings.Cut(path, "@")
+
+	if err := validateEthereumAddress(target); err != nil {
+		return nil, fmt.Errorf("invalid Ethereum payment: %w", err)
+	}
+	p := &EthereumPayment{}
+	if hasChain {
+		id, err := strconv.ParseUint(chain, 10, 64)
+		if err != nil || id == 0 {
+			return nil, errors.New("invalid Ethereum payment: malformed chain ID")
+		}
+		p.ChainID = id
+	}
+
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, errors.New("invalid Ethereum payment: malformed query")
+	}
+	amountKey := "value"
+	switch function {
+	case "":
+		p.Address = target
+	case "transfer":
+		p.Token = target
+		p.Address = q.Get("address")
+		if err := validateEthereumAddress(p.Address); err != nil {
+			return nil, fmt.Errorf("invalid Ethereum payment: recipient %w", err)
+		}
+		amountKey = "uint256"
+	default:
+		return nil, errors.New("invalid Ethereum payment: unsupported function")
+	}
+
+	if v := q.Get(amountKey); v != "" {
+		if p.Value, err = parseEIP681Number(v); err != nil {
+			return nil, fmt.Errorf("invalid Ethereum payment: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// parseEIP681Number parses a non-negative integer, optionally in
+// scientific notation, without loss of precision.
+func parseEIP681Number(s string) (*big.Int, error) {
+	mantissa, exp, hasExp := strings.Cut(strings.ToLower(s), "e")
+	whole, frac, _ := strings.Cut(mantissa, ".")
+	if !isDigits(whole) || (frac != "" && !isDigits(frac)) {
+		return nil, errors.New("malformed amount")
+	}
+
+	shift := 0
+	if hasExp {
+		n, err := strconv.Atoi(exp)
+		if err != nil || n < 0 || n > 80 {
+			return nil, errors.New("malformed amount")
+		}
+		shift = n
+	}
+	if len(strings.TrimRight(frac, "0")) > shift {
+		return nil, errors.New("amount is not a whole number")
+	}
+
+	digits := whole + frac
+	if shift >= len(frac) {
+		digits += strings.Repeat("0", shift-len(frac))
+	} else {
+		digits = digits[:len(digits)-(len(frac)-shift)]
+	}
+	v, _ := new(big.Int).SetString(digits, 10)
+	return v, nil
+}
+
+// ParseUnits parses a decimal amount such as "1.5" into base units with
+// the given number of decimals, such as 18 for ether to wei.
+func ParseUnits(s string, decimals int) (*big.Int, error) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if !isDigits(whole) || len(frac) > decimals || (frac != "" && !isDigits(frac)) {
+		return nil, errors.New("malformed amount")
+	}
+	v, _ := new(big.Int).SetString(whole+frac+strings.Repeat("0", decimals-len(frac)), 10)
+	return v, nil
+}
+
+// validateEthereumAddress checks addr is 0x followed by 40 hex digits and,
+// if it mixes upper and lower case, that it matches its EIP-55 checksum.
+func validateEthereumAddress(addr string) error {
+	if len(addr) != 42 || (addr[:2] != "0x" && addr[:2] != "0X") {
+		return errors.New("address must be 0x followed by 40 hex digits")
+	}
+	if _, err := hex.DecodeString(addr[2:]); err != nil {
+		return errors.New("address must be 0x followed by 40 hex digits")
+	}
+	digits := addr[2:]
+	if strings.ToLower(digits) != digits && strings.ToUpper(digits) != digits && eip55(addr) != "0x"+digits {
+		return errors.New("address checksum mismatch")
+	}
+	return nil
+}
+
+// eip55 returns addr in EIP-55 mixed-case checksum form. addr must be a
+// valid address or empty.
+func eip55(addr string) string {
+	if addr == "" {
+		return ""
+	}
+	lower := strings.ToLower(addr[2:])
+	hash := keccak256([]byte(lower))
+
+	b := []byte(lower)
+	for i, c := range b {
+		nibble := hash[i/2] >> 4
+		if i%2 == 1 {
+			nibble = hash[i/2] & 0xf
+		}
+		if c >= 'a' && nibble >= 8 {
+			b[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(b)
+}
+
+// keccak256 returns the legacy Keccak-256 hash used by Ethereum, which
+// differs from SHA3-256 only in padding.
+func keccak256(data []byte) [32]byte {
+	var out [32]byte
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	h.Sum(out[:0])
+	return out
+}
@@ -0,0 +1,184 @@
+package qrverify
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestKeccak256(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"abc", "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+	}
+	for _, tt := range tests {
+		got := keccak256([]byte(tt.input))
+		if hex.EncodeToString(got[:]) != tt.want {
+			t.Errorf("keccak256(%q) = %x, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEIP55(t *testing.T) {
+	// Test vectors from EIP-55
+	addrs := []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	}
+	for _, addr := range addrs {
+		if got := eip55(addr); got != addr {
+			t.Errorf("eip55(%q) = %q", addr, got)
+		}
+		if err := validateEthereumAddress(addr); err != nil {
+			t.Errorf("validateEthereumAddress(%q) failed: %v", addr, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAe",
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeg",
+		"0x5AAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", // Checksum
+	}
+	for _, addr := range invalid {
+		if err := validateEthereumAddress(addr); err == nil {
+			t.Errorf("Expected error for %q", addr)
+		}
+	}
+}
+
+func TestEthereumPaymentEncode(t *testing.T) {
+	oneEther, _ := ParseUnits("1", 18)
+	tests := []struct {
+		name    string
+		payment EthereumPayment
+		want    string
+	}{
+		{
+			name:    "address only, lowercase input checksummed",
+			payment: EthereumPayment{Address: "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"},
+			want:    "ethereum:0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		},
+		{
+			name:    "value and chain",
+			payment: EthereumPayment{Address: "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", ChainID: 1, Value: oneEther},
+			want:    "ethereum:0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359@1?value=1000000000000000000",
+		},
+		{
+			name: "ERC-20 transfer",
+			payment: EthereumPayment{
+				Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+				Token:   "0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+				ChainID: 137,
+				Value:   big.NewInt(2_500_000),
+			},
+			want: "ethereum:0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB@137/transfer?address=0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed&uint256=2500000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.payment.Encode(MaxBytesMedium)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+			if err := tt.payment.Check(got); err != nil {
+				t.Errorf("Check failed: %v", err)
+			}
+		})
+	}
+
+	if _, err := (&EthereumPayment{Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", Value: big.NewInt(-1)}).Encode(MaxBytesMedium); err == nil {
+		t.Error("Expected error for negative value")
+	}
+}
+
+func TestParseEthereumPayment(t *testing.T) {
+	p, err := ParseEthereumPayment("ethereum:pay-0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359@1?value=2.014e18")
+	if err != nil {
+		t.Fatalf("ParseEthereumPayment failed: %v", err)
+	}
+	if p.Value.String() != "2014000000000000000" || p.ChainID != 1 {
+		t.Errorf("ParseEthereumPayment() = %+v", p)
+	}
+
+	invalid := []string{
+		"ethereum:",
+		"bitcoin:0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359",
+		"ethereum:0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359@x",
+		"ethereum:0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359?value=1.5",
+		"ethereum:0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359?value=1.25e1",
+		"ethereum:0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359?value=-1",
+		"ethereum:0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359/approve?address=0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"ethereum:0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359/transfer?address=0x1",
+	}
+	for _, s := range invalid {
+		if _, err := ParseEthereumPayment(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		input    string
+		decimals int
+		want     string
+	}{
+		{"1", 18, "1000000000000000000"},
+		{"0.000000000000000001", 18, "1"},
+		{"123.45", 6, "123450000"},
+	}
+	for _, tt := range tests {
+		got, err := ParseUnits(tt.input, tt.decimals)
+		if err != nil {
+			t.Fatalf("ParseUnits(%q) failed: %v", tt.input, err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseUnits(%q, %d) = %s, want %s", tt.input, tt.decimals, got, tt.want)
+		}
+	}
+
+	for _, s := range []string{"", "1.2345678", "1e3", "-1", ".5"} {
+		if _, err := ParseUnits(s, 6); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestEthereumPaymentCheck(t *testing.T) {
+	p := &EthereumPayment{Address: "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", Value: big.NewInt(1000)}
+
+	if err := p.Check("ethereum:0xFB6916095CA1DF60BB79CE92CE3EA74C37C5D359?value=1e3"); err != nil {
+		t.Errorf("Check failed: %v", err)
+	}
+
+	err := p.Check("ethereum:0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359@5?value=1000")
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "EthereumPayment.ChainID" {
+		t.Fatalf("Expected FieldError for EthereumPayment.ChainID, got: %v", err)
+	}
+}
+
+func TestEncodePayloadEthereum(t *testing.T) {
+	value, _ := ParseUnits("0.05", 18)
+	p := &EthereumPayment{Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", ChainID: 1, Value: value}
+
+	result, err := EncodePayload(p, nil)
+	if err != nil {
+		t.Fatalf("EncodePayload failed: %v", err)
+	}
+	if err := VerifyPayload(result.Image, p); err != nil {
+		t.Errorf("VerifyPayload failed: %v", err)
+	}
+}
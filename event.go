@@ -0,0 +1,291 @@
+package qrverify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// iCalendar date and date-time layouts (RFC 5545 section 3.3).
+const (
+	icalDate     = "20060102"
+	icalDateTime = "20060102T150405"
+)
+
+// CalendarEvent is an iCalendar (RFC 5545) VEVENT payload.
+type CalendarEvent struct {
+	Summary     string    // Title, required
+	Start       time.Time // Required
+	End         time.Time // Optional, must be after Start
+	AllDay      bool      // Write dates only; End is the exclusive end date
+	Location    string
+	Description string
+	URL         string
+	UID         string // Optional globally unique identifier
+
+	// Calendar wraps the event in a VCALENDAR object for apps that require
+	// a complete iCalendar stream.
+	// Zero value writes a bare VEVENT, which is smaller.
+	Calendar bool
+}
+
+// Encode returns the event as iCalendar text. Times in a named IANA
+// location are written with a TZID parameter, other times in UTC. Lines are
+// folded at 75 octets when the result fits in limit; otherwise the unfolded
+// form is used, and then the shorter form with all times in UTC.
+func (e *CalendarEvent) Encode(limit int) (string, error) {
+	if err := e.validate(); err != nil {
+		return "", err
+	}
+
+	candidates := []string{e.ical(true, false), e.ical(false, false), e.ical(false, true)}
+	for _, s := range candidates {
+		if len(s) <= limit {
+			return s, nil
+		}
+	}
+	last := candidates[len(candidates)-1]
+	return "", fmt.Errorf("data too large: calendar event is %d bytes, exceeds %d byte limit", len(last), limit)
+}
+
+// Check parses decoded as an iCalendar event and compares its fields with e.
+// Times are compared as instants, so a UTC time matches the same instant
+// in any location.
+func (e *CalendarEvent) Check(decoded string) error {
+	got, err := ParseCalendarEvent(decoded)
+	if err != nil {
+		return err
+	}
+
+	if err := checkField("CalendarEvent.Summary", e.Summary, got.Summary); err != nil {
+		return err
+	}
+	if err := checkField("CalendarEvent.AllDay", fmt.Sprint(e.AllDay), fmt.Sprint(got.AllDay)); err != nil {
+		return err
+	}
+	if err := checkField("CalendarEvent.Start", e.formatTime(e.Start), e.formatTime(got.Start)); err != nil {
+		return err
+	}
+	if err := checkField("CalendarEvent.End", e.formatTime(e.End), e.formatTime(got.End)); err != nil {
+		return err
+	}
+	if err := checkField("CalendarEvent.Location", e.Location, got.Location); err != nil {
+		return err
+	}
+	if err := checkField("CalendarEvent.Description", e.Description, got.Description); err != nil {
+		return err
+	}
+	if err := checkField("CalendarEvent.URL", e.URL, got.URL); err != nil {
+		return err
+	}
+	return checkField("CalendarEvent.UID", e.UID, got.UID)
+}
+
+// formatTime formats t for comparison: the date for all-day events and the
+// UTC instant otherwise.
+func (e *CalendarEvent) formatTime(t time.Time) string {
+	switch {
+	case t.IsZero():
+		return ""
+	case e.AllDay:
+		return t.Format(time.DateOnly)
+	default:
+		return t.UTC().Format(time.RFC3339)
+	}
+}
+
+// validate checks that e can be encoded.
+func (e *CalendarEvent) validate() error {
+	if e.Summary == "" {
+		return errors.New("invalid calendar event: summary is required")
+	}
+	if e.Start.IsZero() {
+		return errors.New("invalid calendar event: start time is required")
+	}
+	if !e.End.IsZero() {
+		if e.AllDay && e.End.Format(icalDate) <= e.Start.Format(icalDate) {
+			return errors.New("invalid calendar event: end date must be after start date")
+		}
+		if !e.AllDay && !e.End.After(e.Start) {
+			return errors.New("invalid calendar event: end time must be after start time")
+		}
+	}
+	for _, f := range []string{e.Summary, e.Location, e.Description, e.URL, e.UID} {
+		if !utf8.ValidString(f) {
+			return errors.New("invalid calendar event: fields must be valid UTF-8")
+		}
+	}
+	if strings.ContainsAny(e.URL, " \r\n") || strings.ContainsAny(e.UID, "\r\n") {
+		return errors.New("invalid calendar event: URL and UID must be single-line without spaces")
+	}
+	return nil
+}
+
+// ical returns e as iCalendar text, optionally folded and with all times
+// converted to UTC.
+func (e *CalendarEvent) ical(fold, utc bool) string {
+	var lines []string
+	add := func(line string) {
+		lines = append(lines, line)
+	}
+	esc := vCardEscaper.Replace
+
+	if e.Calendar {
+		add("BEGIN:VCALENDAR")
+		add("VERSION:2.0")
+		add("PRODID:-//qrverify//EN")
+	}
+	add("BEGIN:VEVENT")
+	if e.UID != "" {
+		add("UID:" + e.UID)
+	}
+	add("SUMMARY:" + esc(e.Summary))
+	add("DTSTART" + e.icalTime(e.Start, utc))
+	if !e.End.IsZero() {
+		add("DTEND" + e.icalTime(e.End, utc))
+	}
+	if e.Location != "" {
+		add("LOCATION:" + esc(e.Location))
+	}
+	if e.Description != "" {
+		add("DESCRIPTION:" + esc(e.Description))
+	}
+	if e.URL != "" {
+		add("URL:" + e.URL)
+	}
+	add("END:VEVENT")
+	if e.Calendar {
+		add("END:VCALENDAR")
+	}
+
+	if fold {
+		for i, l := range lines {
+			lines[i] = foldLine(l, 75)
+		}
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// icalTime returns the parameters and value of a DTSTART or DTEND property
+// for t, starting with ";" or ":".
+func (e *CalendarEvent) icalTime(t time.Time, utc bool) string {
+	if e.AllDay {
+		return ";VALUE=DATE:" + t.Format(icalDate)
+	}
+	if name := t.Location().String(); !utc && name != "UTC" && name != "Local" {
+		// Zones that cannot be loaded back by name with the same offset,
+		// such as most time.FixedZone locations, are written in UTC.
+		if loc, err := time.LoadLocation(name); err == nil {
+			_, want := t.Zone()
+			if _, got := t.In(loc).Zone(); got == want {
+				return ";TZID=" + name + ":" + t.Format(icalDateTime)
+			}
+		}
+	}
+	return ":" + t.UTC().Format(icalDateTime) + "Z"
+}
+
+// ParseCalendarEvent parses the first VEVENT in an iCalendar payload.
+// Floating times without a zone are interpreted in time.Local.
+func ParseCalendarEvent(s string) (*CalendarEvent, error) {
+	lines := strings.Split(unfold(s), "\n")
+
+	e := &CalendarEvent{}
+	inEvent, ended := false, false
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case line == "":
+			continue
+		case line == "BEGIN:VCALENDAR":
+			e.Calendar = true
+			continue
+		case line == "BEGIN:VEVENT":
+			inEvent = true
+			continue
+		case line == "END:VEVENT":
+			ended = inEvent
+		}
+		if ended {
+			break
+		}
+		if !inEvent {
+			continue
+		}
+
+		prop, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, errors.New("invalid calendar event: property without value")
+		}
+		name, params, _ := strings.Cut(prop, ";")
+
+		switch strings.ToUpper(name) {
+		case "SUMMARY":
+			e.Summary = unescapeVCard(value)
+		case "DTSTART":
+			t, allDay, err := parseICalTime(params, value)
+			if err != nil {
+				return nil, err
+			}
+			e.Start, e.AllDay = t, allDay
+		case "DTEND":
+			t, _, err := parseICalTime(params, value)
+			if err != nil {
+				return nil, err
+			}
+			e.End = t
+		case "LOCATION":
+			e.Location = unescapeVCard(value)
+		case "DESCRIPTION":
+			e.Description = unescapeVCard(value)
+		case "URL":
+			e.URL = value
+		case "UID":
+			e.UID = value
+		}
+	}
+
+	if !ended {
+		return nil, errors.New("invalid calendar event: missing VEVENT")
+	}
+	if e.Start.IsZero() {
+		return nil, errors.New("invalid calendar event: missing DTSTART")
+	}
+	return e, nil
+}
+
+// parseICalTime parses a DATE or DATE-TIME property value with its
+// parameters, reporting whether it is a date.
+func parseICalTime(params, value string) (time.Time, bool, error) {
+	loc := time.Local
+	for _, p := range strings.Split(params, ";") {
+		key, v, _ := strings.Cut(p, "=")
+		switch strings.ToUpper(key) {
+		case "VALUE":
+			if strings.EqualFold(v, "DATE") {
+				t, err := time.ParseInLocation(icalDate, value, time.UTC)
+				if err != nil {
+					return time.Time{}, false, errors.New("invalid calendar event: malformed date")
+				}
+				return t, true, nil
+			}
+		case "TZID":
+			l, err := time.LoadLocation(strings.Trim(v, `"`))
+			if err != nil {
+				return time.Time{}, false, errors.New("invalid calendar event: unknown time zone")
+			}
+			loc = l
+		}
+	}
+
+	if utc, ok := strings.CutSuffix(value, "Z"); ok {
+		value, loc = utc, time.UTC
+	}
+	t, err := time.ParseInLocation(icalDateTime, value, loc)
+	if err != nil {
+		return time.Time{}, false, errors.New("invalid calendar event: malformed date-time")
+	}
+	return t, false, nil
+}
@@ -0,0 +1,201 @@
+package qrverify
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarEventEncode(t *testing.T) {
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		event CalendarEvent
+		want  string
+	}{
+		{
+			name: "UTC",
+			event: CalendarEvent{
+				Summary: "Launch",
+				Start:   time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+				End:     time.Date(2026, 10, 18, 13, 30, 0, 0, time.UTC),
+			},
+			want: "BEGIN:VEVENT\r\nSUMMARY:Launch\r\nDTSTART:20261018T120000Z\r\nDTEND:20261018T133000Z\r\nEND:VEVENT\r\n",
+		},
+		{
+			name: "time zone and escaping",
+			event: CalendarEvent{
+				Summary:  "Meetup; talks, drinks",
+				Start:    time.Date(2026, 11, 2, 18, 0, 0, 0, zurich),
+				Location: `Room 1\2`,
+			},
+			want: "BEGIN:VEVENT\r\nSUMMARY:Meetup\\; talks\\, drinks\r\nDTSTART;TZID=Europe/Zurich:20261102T180000\r\nLOCATION:Room 1\\\\2\r\nEND:VEVENT\r\n",
+		},
+		{
+			name: "all day in calendar",
+			event: CalendarEvent{
+				Summary:  "Conference",
+				Start:    time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+				End:      time.Date(2026, 6, 3, 0, 0, 0, 0, time.UTC),
+				AllDay:   true,
+				UID:      "conf-2026@example.com",
+				Calendar: true,
+			},
+			want: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//qrverify//EN\r\nBEGIN:VEVENT\r\nUID:conf-2026@example.com\r\n" +
+				"SUMMARY:Conference\r\nDTSTART;VALUE=DATE:20260601\r\nDTEND;VALUE=DATE:20260603\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		},
+		{
+			name: "unnamed zone written in UTC",
+			event: CalendarEvent{
+				Summary: "Call",
+				Start:   time.Date(2026, 1, 5, 9, 0, 0, 0, time.FixedZone("Office", -5*3600)),
+			},
+			want: "BEGIN:VEVENT\r\nSUMMARY:Call\r\nDTSTART:20260105T140000Z\r\nEND:VEVENT\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.event.Encode(MaxBytesMedium)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+			if err := tt.event.Check(got); err != nil {
+				t.Errorf("Check failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestCalendarEventEncodeCompact(t *testing.T) {
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	e := &CalendarEvent{
+		Summary:     "Annual general meeting",
+		Start:       time.Date(2026, 3, 14, 10, 0, 0, 0, zurich),
+		End:         time.Date(2026, 3, 14, 12, 0, 0, 0, zurich),
+		Description: strings.Repeat("Agenda item with a longer description. ", 4),
+	}
+
+	folded, err := e.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	for _, line := range strings.Split(folded, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line exceeds 75 octets: %q", line)
+		}
+	}
+
+	// Shrink the limit to force the unfolded form, then UTC times
+	unfolded := e.ical(false, false)
+	utc := e.ical(false, true)
+	if len(utc) >= len(unfolded) || len(unfolded) >= len(folded) {
+		t.Fatalf("unexpected candidate sizes: %d, %d, %d", len(folded), len(unfolded), len(utc))
+	}
+	for _, want := range []string{unfolded, utc} {
+		got, err := e.Encode(len(want))
+		if err != nil {
+			t.Fatalf("Encode(%d) failed: %v", len(want), err)
+		}
+		if got != want {
+			t.Errorf("Encode(%d) = %q, want %q", len(want), got, want)
+		}
+		if err := e.Check(got); err != nil {
+			t.Errorf("Check failed: %v", err)
+		}
+	}
+
+	if _, err := e.Encode(len(utc) - 1); err == nil || !strings.Contains(err.Error(), "data too large") {
+		t.Errorf("Expected data too large error, got: %v", err)
+	}
+}
+
+func TestCalendarEventEncodeInvalid(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	invalid := []CalendarEvent{
+		{Start: start},
+		{Summary: "x"},
+		{Summary: "x", Start: start, End: start},
+		{Summary: "x", Start: start, End: start.Add(time.Hour), AllDay: true},
+		{Summary: "x", Start: start, URL: "https://example.com/a b"},
+		{Summary: "\xff", Start: start},
+	}
+	for _, e := range invalid {
+		if _, err := e.Encode(MaxBytesMedium); err == nil {
+			t.Errorf("Expected error for %+v", e)
+		}
+	}
+}
+
+func TestParseCalendarEvent(t *testing.T) {
+	// Lowercase property names, folded lines and LF endings
+	e, err := ParseCalendarEvent("BEGIN:VEVENT\nsummary:Long\n  title\nDTSTART:20260101T100000Z\nDESCRIPTION:a\\nb\nEND:VEVENT\n")
+	if err != nil {
+		t.Fatalf("ParseCalendarEvent failed: %v", err)
+	}
+	if e.Summary != "Long title" || e.Description != "a\nb" || !e.Start.Equal(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParseCalendarEvent() = %+v", e)
+	}
+
+	invalid := []string{
+		"",
+		"SUMMARY:x\r\n",
+		"BEGIN:VEVENT\r\nSUMMARY:x\r\nDTSTART:20260101T100000Z\r\n",
+		"BEGIN:VEVENT\r\nSUMMARY:x\r\nEND:VEVENT\r\n",
+		"BEGIN:VEVENT\r\nDTSTART:2026-01-01\r\nEND:VEVENT\r\n",
+		"BEGIN:VEVENT\r\nDTSTART;TZID=Mars/Olympus:20260101T100000\r\nEND:VEVENT\r\n",
+		"BEGIN:VEVENT\r\nSUMMARY\r\nEND:VEVENT\r\n",
+	}
+	for _, s := range invalid {
+		if _, err := ParseCalendarEvent(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestCalendarEventCheck(t *testing.T) {
+	e := &CalendarEvent{Summary: "Launch", Start: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+
+	err := e.Check("BEGIN:VEVENT\r\nSUMMARY:Launch\r\nDTSTART:20261018T130000Z\r\nEND:VEVENT\r\n")
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "CalendarEvent.Start" {
+		t.Fatalf("Expected FieldError for CalendarEvent.Start, got: %v", err)
+	}
+}
+
+func TestEncodePayloadCalendarEvent(t *testing.T) {
+	e := &CalendarEvent{
+		Summary:     "Open day",
+		Start:       time.Date(2026, 5, 9, 9, 0, 0, 0, time.UTC),
+		End:         time.Date(2026, 5, 9, 17, 0, 0, 0, time.UTC),
+		Location:    "Main campus, building A",
+		Description: "Tours every hour.\nFree entry.",
+		URL:         "https://example.com/open-day",
+	}
+
+	result, err := EncodePayload(e, nil)
+	if err != nil {
+		t.Fatalf("EncodePayload failed: %v", err)
+	}
+	if err := VerifyPayload(result.Image, e); err != nil {
+		t.Errorf("VerifyPayload failed: %v", err)
+	}
+
+	other := *e
+	other.Location = "Side campus"
+	if err := VerifyPayload(result.Image, &other); err == nil {
+		t.Error("Expected VerifyPayload to fail for different location")
+	}
+}
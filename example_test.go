@@ -58,3 +58,22 @@ func ExampleEncodeToFile() {
 	fmt.Println("File created successfully")
 	// Output: File created successfully
 }
+
+func ExampleEncodePayload() {
+	wifi := &qrverify.WiFi{
+		SSID:     "Guest;Network",
+		Password: "correct horse",
+	}
+	text, _ := wifi.Encode(qrverify.MaxBytesMedium)
+	fmt.Println(text)
+
+	_, err := qrverify.EncodePayload(wifi, nil)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("Verified")
+	// Output:
+	// WIFI:T:WPA;S:Guest\;Network;P:correct horse;;
+	// Verified
+}
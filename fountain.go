@@ -0,0 +1,497 @@
+package qrverify
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Fountain frame layout: version, total size, block size, CRC-32 of the
+// data and the frame seed, followed by one coded block.
+const (
+	fountainVersion    = 1
+	fountainHeaderSize = 15
+
+	// DefaultFountainBlockSize is the number of data bytes per frame when
+	// FountainOptions.BlockSize is zero. Frames stay small enough to scan
+	// reliably from a screen.
+	DefaultFountainBlockSize = 400
+
+	// maxFountainBlockSize keeps a frame within the 3391 character
+	// alphanumeric capacity at Medium recovery after Base45.
+	maxFountainBlockSize = 2245
+
+	// maxFountainBlocks bounds the work a decoder does for one transfer.
+	maxFountainBlocks = 1 << 14
+)
+
+// Robust soliton distribution parameters.
+const (
+	solitonC     = 0.1
+	solitonDelta = 0.5
+)
+
+// FountainOptions configures EncodeFountain.
+// Zero values provide sensible defaults.
+type FountainOptions struct {
+	// BlockSize is the number of data bytes carried by each frame.
+	// Zero value uses DefaultFountainBlockSize.
+	BlockSize int
+
+	// Frames is the number of frames to generate, at least the number of
+	// blocks. Frames beyond the block count are redundancy: a receiver can
+	// miss that many frames and usually still reassemble the data.
+	// Zero value uses twice the number of blocks.
+	Frames int
+
+	// Encode configures each frame's QR code.
+	Encode *EncodeOptions
+}
+
+// EncodeFountain splits data into blocks and generates a sequence of
+// verified QR codes carrying LT fountain coded combinations of them, for
+// transfers larger than a single code holds. The first frames carry the
+// blocks as they are; later frames carry XOR combinations of random
+// blocks, so a FountainDecoder can reassemble the data from any
+// sufficient subset of frames in any order. Each frame is Base45 text and
+// is verified by decoding like Encode; a frame that fails verification is
+// replaced by one with the next seed. If the replacements leave the
+// frames unable to reassemble the data, an error is returned; frames
+// beyond the block count make that unlikely. Result.Data is the frame
+// text.
+func EncodeFountain(data []byte, opts *FountainOptions) ([]*Result, error) {
+	return EncodeFountainContext(context.Background(), data, opts)
+}
+
+// EncodeFountainContext is like EncodeFountain but honors cancellation and
+// deadlines of ctx.
+func EncodeFountainContext(ctx context.Context, data []byte, opts *FountainOptions) ([]*Result, error) {
+	if opts == nil {
+		opts = &FountainOptions{}
+	}
+	if len(data) == 0 {
+		return nil, errors.New("fountain: data is empty")
+	}
+	if uint64(len(data)) > math.MaxUint32 {
+		return nil, errors.New("fountain: data exceeds 4 GiB")
+	}
+
+	blockSize := opts.BlockSize
+	if blockSize == 0 {
+		blockSize = DefaultFountainBlockSize
+	}
+	if blockSize < 1 || blockSize > maxFountainBlockSize {
+		return nil, fmt.Errorf("fountain: block size must be 1-%d", maxFountainBlockSize)
+	}
+	k := (len(data) + blockSize - 1) / blockSize
+	if k > maxFountainBlocks {
+		return nil, fmt.Errorf("fountain: data needs %d blocks, exceeds %d block limit", k, maxFountainBlocks)
+	}
+	frames := opts.Frames
+	if frames == 0 {
+		frames = 2 * k
+	}
+	if frames < k {
+		return nil, fmt.Errorf("fountain: %d frames can not carry %d blocks", frames, k)
+	}
+
+	blocks := make([][]byte, k)
+	for i := range blocks {
+		blocks[i] = make([]byte, blockSize)
+		copy(blocks[i], data[i*blockSize:])
+	}
+
+	header := fountainHeader{
+		size:      uint32(len(data)),
+		blockSize: uint16(blockSize),
+		checksum:  crc32.ChecksumIEEE(data),
+	}
+	cdf := solitonCDF(k)
+	frame := func(seed uint32) BatchItem {
+		header.seed = seed
+		raw := header.append(make([]byte, 0, fountainHeaderSize+blockSize))
+		block := make([]byte, blockSize)
+		for _, j := range fountainIndices(seed, k, cdf) {
+			xorBytes(block, blocks[j])
+		}
+		text, _ := Base45{}.Apply(append(raw, block...))
+		return BatchItem{Data: string(text), Opts: opts.Encode}
+	}
+
+	// The decoder occasionally fails on a particular symbol. The code is
+	// rateless, so such a frame is replaced with the next seed rather than
+	// failing the transfer; only a round without any success is fatal.
+	results := make([]*Result, 0, frames)
+	seed := uint32(0)
+	for len(results) < frames {
+		items := make([]BatchItem, frames-len(results))
+		for i := range items {
+			items[i] = frame(seed)
+			seed++
+		}
+		batch, err := EncodeBatch(ctx, items, nil)
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("fountain canceled: %w", err)
+		}
+		n := len(results)
+		for _, r := range batch {
+			if r.Err == nil {
+				results = append(results, r.Result)
+			}
+		}
+		if len(results) == n {
+			return nil, err
+		}
+	}
+
+	// A replacement combines random blocks, so it may not restore the
+	// blocks of the frame it replaced
+	if seed > uint32(frames) {
+		if err := fountainComplete(results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// fountainComplete returns an error unless frames together determine
+// every block of their transfer.
+func fountainComplete(frames []*Result) error {
+	d := NewFountainDecoder()
+	for _, f := range frames {
+		done, err := d.AddFrame(f.Data)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+	solved, needed := d.Progress()
+	return fmt.Errorf("fountain: frames determine %d of %d blocks after replacing failed frames; add frames beyond the block count", solved, needed)
+}
+
+// WriteGIF writes frames as an animated GIF that loops forever, showing
+// each frame for delay. The GIF has a black and white palette, so frames
+// keep their exact pixels.
+func WriteGIF(w io.Writer, frames []*Result, delay time.Duration) error {
+	if len(frames) == 0 {
+		return errors.New("gif: no frames")
+	}
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{}
+	for i, f := range frames {
+		img, err := png.Decode(bytes.NewReader(f.Image))
+		if err != nil {
+			return fmt.Errorf("gif: frame %d: failed to decode PNG: %w", i, err)
+		}
+		p := image.NewPaletted(img.Bounds(), palette)
+		draw.Draw(p, p.Rect, img, img.Bounds().Min, draw.Src)
+		anim.Image = append(anim.Image, p)
+		anim.Delay = append(anim.Delay, int(delay/(10*time.Millisecond)))
+	}
+	if err := gif.EncodeAll(w, anim); err != nil {
+		return fmt.Errorf("gif: %w", err)
+	}
+	return nil
+}
+
+// FountainDecoder reassembles data from fountain frames received in any
+// order. Duplicate frames are ignored. It is not safe for concurrent use.
+type FountainDecoder struct {
+	header fountainHeader
+	k      int
+	cdf    []float64
+	seen   map[uint32]bool
+	rows   map[int]*fountainRow // Reduced equations by pivot block
+	data   []byte               // Reassembled data once complete
+}
+
+// fountainRow is an equation over GF(2): the XOR of the blocks in mask
+// equals block.
+type fountainRow struct {
+	mask  []uint64
+	block []byte
+}
+
+// NewFountainDecoder returns an empty decoder. The transfer parameters are
+// taken from the first frame added.
+func NewFountainDecoder() *FountainDecoder {
+	return &FountainDecoder{}
+}
+
+// AddFrame adds the decoded text of a frame and reports whether the data
+// is complete. Frames of a different transfer are rejected.
+func (d *FountainDecoder) AddFrame(text string) (bool, error) {
+	raw, err := Base45{}.Invert([]byte(text))
+	if err != nil {
+		return d.Done(), fmt.Errorf("invalid fountain frame: %w", err)
+	}
+	h, block, err := parseFountainFrame(raw)
+	if err != nil {
+		return d.Done(), err
+	}
+
+	if d.seen == nil {
+		d.header = h
+		d.k = (int(h.size) + int(h.blockSize) - 1) / int(h.blockSize)
+		d.cdf = solitonCDF(d.k)
+		d.seen = make(map[uint32]bool)
+		d.rows = make(map[int]*fountainRow)
+	} else if h.size != d.header.size || h.blockSize != d.header.blockSize || h.checksum != d.header.checksum {
+		return d.Done(), errors.New("fountain frame belongs to a different transfer")
+	}
+	if d.Done() || d.seen[h.seed] {
+		return d.Done(), nil
+	}
+	d.seen[h.seed] = true
+
+	row := &fountainRow{mask: make([]uint64, (d.k+63)/64), block: block}
+	for _, j := range fountainIndices(h.seed, d.k, d.cdf) {
+		row.mask[j/64] |= 1 << (j % 64)
+	}
+	d.reduce(row)
+	if len(d.rows) == d.k {
+		err := d.assemble()
+		return d.Done(), err
+	}
+	return false, nil
+}
+
+// AddImage reads the QR code in img, such as a frame of a GIF written by
+// WriteGIF or a camera capture, and adds it like AddFrame.
+func (d *FountainDecoder) AddImage(img image.Image) (bool, error) {
+	decoded, err := decode(img)
+	if err != nil {
+		return d.Done(), fmt.Errorf("failed to read QR code: %w", err)
+	}
+	return d.AddFrame(decoded)
+}
+
+// Done reports whether the data has been reassembled.
+func (d *FountainDecoder) Done() bool {
+	return d.data != nil
+}
+
+// Progress returns the number of blocks solved so far and the number
+// needed. Both are zero before the first frame. Solved counts independent
+// frames, so it can jump to needed with a single frame.
+func (d *FountainDecoder) Progress() (solved, needed int) {
+	return len(d.rows), d.k
+}
+
+// Data returns the reassembled data, or an error if more frames are
+// needed.
+func (d *FountainDecoder) Data() ([]byte, error) {
+	if !d.Done() {
+		solved, needed := d.Progress()
+		return nil, fmt.Errorf("fountain: incomplete, %d of %d blocks solved", solved, needed)
+	}
+	return d.data, nil
+}
+
+// reduce adds row to the system, keeping it in reduced row echelon form:
+// every stored row has a pivot block no other row contains. A row that
+// reduces to nothing is a combination of earlier frames and is dropped.
+func (d *FountainDecoder) reduce(row *fountainRow) {
+	for w, word := range row.mask {
+		for word != 0 {
+			j := w*64 + bits.TrailingZeros64(word)
+			word &= word - 1
+			if r, ok := d.rows[j]; ok {
+				row.xor(r)
+			}
+		}
+	}
+
+	pivot := row.first()
+	if pivot < 0 {
+		return
+	}
+	bit := uint64(1) << (pivot % 64)
+	for _, r := range d.rows {
+		if r.mask[pivot/64]&bit != 0 {
+			r.xor(row)
+		}
+	}
+	d.rows[pivot] = row
+}
+
+// assemble concatenates the solved blocks and checks the CRC-32.
+func (d *FountainDecoder) assemble() error {
+	data := make([]byte, 0, d.k*int(d.header.blockSize))
+	for i := 0; i < d.k; i++ {
+		data = append(data, d.rows[i].block...)
+	}
+	data = data[:d.header.size]
+	if crc32.ChecksumIEEE(data) != d.header.checksum {
+		d.rows = make(map[int]*fountainRow)
+		d.seen = make(map[uint32]bool)
+		return errors.New("fountain: reassembled data checksum mismatch")
+	}
+	d.data = data
+	return nil
+}
+
+// xor adds other to r.
+func (r *fountainRow) xor(other *fountainRow) {
+	for i := range r.mask {
+		r.mask[i] ^= other.mask[i]
+	}
+	xorBytes(r.block, other.block)
+}
+
+// first returns the lowest block in r, or -1 if r is empty.
+func (r *fountainRow) first() int {
+	for w, word := range r.mask {
+		if word != 0 {
+			return w*64 + bits.TrailingZeros64(word)
+		}
+	}
+	return -1
+}
+
+// fountainHeader holds the transfer parameters repeated in every frame.
+type fountainHeader struct {
+	size      uint32 // Data length in bytes
+	blockSize uint16
+	checksum  uint32 // CRC-32 (IEEE) of the data, also identifies the transfer
+	seed      uint32 // Selects the blocks combined in this frame
+}
+
+// append appends the binary header to b.
+func (h fountainHeader) append(b []byte) []byte {
+	b = append(b, fountainVersion)
+	b = binary.BigEndian.AppendUint32(b, h.size)
+	b = binary.BigEndian.AppendUint16(b, h.blockSize)
+	b = binary.BigEndian.AppendUint32(b, h.checksum)
+	return binary.BigEndian.AppendUint32(b, h.seed)
+}
+
+// parseFountainFrame splits a binary frame into its header and block.
+func parseFountainFrame(raw []byte) (fountainHeader, []byte, error) {
+	var h fountainHeader
+	if len(raw) < fountainHeaderSize {
+		return h, nil, errors.New("invalid fountain frame: too short")
+	}
+	if raw[0] != fountainVersion {
+		return h, nil, errors.New("invalid fountain frame: unsupported version")
+	}
+	h.size = binary.BigEndian.Uint32(raw[1:])
+	h.blockSize = binary.BigEndian.Uint16(raw[5:])
+	h.checksum = binary.BigEndian.Uint32(raw[7:])
+	h.seed = binary.BigEndian.Uint32(raw[11:])
+
+	block := raw[fountainHeaderSize:]
+	if h.size == 0 || h.blockSize == 0 || len(block) != int(h.blockSize) {
+		return h, nil, errors.New("invalid fountain frame: inconsistent sizes")
+	}
+	if k := (uint64(h.size) + uint64(h.blockSize) - 1) / uint64(h.blockSize); k > maxFountainBlocks {
+		return h, nil, errors.New("invalid fountain frame: too many blocks")
+	}
+	return h, bytes.Clone(block), nil
+}
+
+// fountainIndices returns the blocks combined in the frame with seed. The
+// first k seeds carry one block each in order; later seeds draw a degree
+// from the robust soliton distribution and that many distinct blocks.
+func fountainIndices(seed uint32, k int, cdf []float64) []int {
+	if int(seed) < k {
+		return []int{int(seed)}
+	}
+
+	rng := splitMix64(uint64(seed))
+	u := rng.float64()
+	degree := 1
+	for degree < k && cdf[degree-1] < u {
+		degree++
+	}
+
+	// Partial Fisher-Yates shuffle over the block indices
+	perm := make([]int, k)
+	for i := range perm {
+		perm[i] = i
+	}
+	for i := 0; i < degree; i++ {
+		j := i + int(rng.next()%uint64(k-i))
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+	return perm[:degree]
+}
+
+// solitonCDF returns the cumulative robust soliton distribution over
+// degrees 1 to k.
+func solitonCDF(k int) []float64 {
+	r := solitonC * math.Log(float64(k)/solitonDelta) * math.Sqrt(float64(k))
+	spike := k
+	if r > 0 {
+		spike = min(max(int(math.Round(float64(k)/r)), 1), k)
+	}
+
+	weights := make([]float64, k)
+	for d := 1; d <= k; d++ {
+		// Ideal soliton
+		if d == 1 {
+			weights[d-1] = 1 / float64(k)
+		} else {
+			weights[d-1] = 1 / float64(d*(d-1))
+		}
+		// Robust correction
+		switch {
+		case r <= 0:
+		case d < spike:
+			weights[d-1] += r / float64(d*k)
+		case d == spike:
+			weights[d-1] += r * math.Log(r/solitonDelta) / float64(k)
+		}
+	}
+
+	cdf := make([]float64, k)
+	sum := 0.0
+	for i, w := range weights {
+		sum += max(w, 0)
+		cdf[i] = sum
+	}
+	for i := range cdf {
+		cdf[i] /= sum
+	}
+	return cdf
+}
+
+// splitMix64 is a small deterministic generator. Encoder and decoder must
+// derive identical block choices from a seed, so the sequence is fixed
+// here rather than left to a library.
+type splitMix64 uint64
+
+// next returns the next 64 random bits.
+func (s *splitMix64) next() uint64 {
+	*s += 0x9e3779b97f4a7c15
+	z := uint64(*s)
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// float64 returns a uniform value in [0, 1).
+func (s *splitMix64) float64() float64 {
+	return float64(s.next()>>11) / (1 << 53)
+}
+
+// xorBytes XORs src into dst.
+func xorBytes(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
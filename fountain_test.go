@@ -0,0 +1,236 @@
+package qrverify
+
+import (
+	"bytes"
+	"image/gif"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// fountainData returns n incompressible bytes, larger than a single code
+// holds when n exceeds MaxBytesLow.
+func fountainData(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+func TestFountainRoundTrip(t *testing.T) {
+	data := fountainData(3 * MaxBytesLow)
+	frames, err := EncodeFountain(data, nil)
+	if err != nil {
+		t.Fatalf("EncodeFountain failed: %v", err)
+	}
+	k := (len(data) + DefaultFountainBlockSize - 1) / DefaultFountainBlockSize
+	if len(frames) != 2*k {
+		t.Fatalf("got %d frames, want %d", len(frames), 2*k)
+	}
+	for i, f := range frames {
+		if err := Verify(f.Image, f.Data); err != nil {
+			t.Fatalf("frame %d: Verify failed: %v", i, err)
+		}
+	}
+
+	d := NewFountainDecoder()
+	for i := len(frames) - 1; i >= 0 && !d.Done(); i-- {
+		if _, err := d.AddFrame(frames[i].Data); err != nil {
+			t.Fatalf("AddFrame(%d) failed: %v", i, err)
+		}
+	}
+	got, err := d.Data()
+	if err != nil {
+		t.Fatalf("Data failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("reassembled data differs")
+	}
+}
+
+func TestFountainDroppedFrames(t *testing.T) {
+	data := fountainData(6000)
+	frames, err := EncodeFountain(data, &FountainOptions{BlockSize: 300, Frames: 60})
+	if err != nil {
+		t.Fatalf("EncodeFountain failed: %v", err)
+	}
+	const k = 20
+
+	tests := []struct {
+		name string
+		keep func(i int) bool
+	}{
+		{"every third dropped", func(i int) bool { return i%3 != 0 }},
+		{"first half dropped", func(i int) bool { return i >= 30 }},
+		{"all uncoded dropped", func(i int) bool { return i >= k }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kept []*Result
+			for i, f := range frames {
+				if tt.keep(i) {
+					kept = append(kept, f)
+				}
+			}
+			rand.New(rand.NewSource(2)).Shuffle(len(kept), func(i, j int) {
+				kept[i], kept[j] = kept[j], kept[i]
+			})
+
+			d := NewFountainDecoder()
+			used := 0
+			for _, f := range kept {
+				used++
+				done, err := d.AddFrame(f.Data)
+				if err != nil {
+					t.Fatalf("AddFrame failed: %v", err)
+				}
+				if done {
+					break
+				}
+			}
+			got, err := d.Data()
+			if err != nil {
+				t.Fatalf("Data failed after %d of %d frames: %v", used, len(kept), err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Error("reassembled data differs")
+			}
+		})
+	}
+}
+
+func TestFountainComplete(t *testing.T) {
+	frames, err := EncodeFountain(fountainData(500), &FountainOptions{BlockSize: 250})
+	if err != nil {
+		t.Fatalf("EncodeFountain failed: %v", err)
+	}
+	if err := fountainComplete(frames[:2]); err != nil {
+		t.Errorf("fountainComplete(systematic frames) failed: %v", err)
+	}
+	// A frame set missing the second block, as after a failed frame
+	if err := fountainComplete([]*Result{frames[0], frames[0]}); err == nil {
+		t.Error("Expected error for frames missing a block")
+	}
+}
+
+func TestFountainMaxBlockSize(t *testing.T) {
+	// Base45 frames are encoded in alphanumeric mode, which holds more
+	// characters than the byte limit
+	data := fountainData(maxFountainBlockSize)
+	frames, err := EncodeFountain(data, &FountainOptions{BlockSize: maxFountainBlockSize, Frames: 1})
+	if err != nil {
+		t.Fatalf("EncodeFountain failed: %v", err)
+	}
+	if n := len(frames[0].Data); n <= MaxBytesMedium {
+		t.Errorf("Frame is %d characters, want more than %d", n, MaxBytesMedium)
+	}
+
+	d := NewFountainDecoder()
+	if done, err := d.AddFrame(frames[0].Data); !done || err != nil {
+		t.Fatalf("AddFrame = %v, %v", done, err)
+	}
+}
+
+func TestFountainTooFewFrames(t *testing.T) {
+	data := fountainData(2000)
+	frames, err := EncodeFountain(data, &FountainOptions{BlockSize: 200})
+	if err != nil {
+		t.Fatalf("EncodeFountain failed: %v", err)
+	}
+
+	d := NewFountainDecoder()
+	for _, f := range frames[:9] {
+		if done, err := d.AddFrame(f.Data); done || err != nil {
+			t.Fatalf("AddFrame = %v, %v", done, err)
+		}
+	}
+	// Duplicates add nothing
+	if done, err := d.AddFrame(frames[0].Data); done || err != nil {
+		t.Fatalf("AddFrame(duplicate) = %v, %v", done, err)
+	}
+	if solved, needed := d.Progress(); solved != 9 || needed != 10 {
+		t.Errorf("Progress() = %d, %d, want 9, 10", solved, needed)
+	}
+	if _, err := d.Data(); err == nil {
+		t.Error("Expected error from Data before completion")
+	}
+}
+
+func TestFountainGIF(t *testing.T) {
+	data := fountainData(4000)
+	frames, err := EncodeFountain(data, &FountainOptions{BlockSize: 250})
+	if err != nil {
+		t.Fatalf("EncodeFountain failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGIF(&buf, frames, 200*time.Millisecond); err != nil {
+		t.Fatalf("WriteGIF failed: %v", err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("gif.DecodeAll failed: %v", err)
+	}
+	if len(anim.Image) != len(frames) || anim.Delay[0] != 20 || anim.LoopCount != 0 {
+		t.Fatalf("GIF has %d frames, delay %d, loop count %d", len(anim.Image), anim.Delay[0], anim.LoopCount)
+	}
+
+	// Read frames back to front, dropping every fourth
+	d := NewFountainDecoder()
+	for i := len(anim.Image) - 1; i >= 0 && !d.Done(); i-- {
+		if i%4 == 0 {
+			continue
+		}
+		if _, err := d.AddImage(anim.Image[i]); err != nil {
+			t.Fatalf("AddImage(%d) failed: %v", i, err)
+		}
+	}
+	got, err := d.Data()
+	if err != nil {
+		t.Fatalf("Data failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("reassembled data differs")
+	}
+}
+
+func TestFountainDecoderErrors(t *testing.T) {
+	a, err := EncodeFountain(fountainData(500), &FountainOptions{BlockSize: 100})
+	if err != nil {
+		t.Fatalf("EncodeFountain failed: %v", err)
+	}
+	b, err := EncodeFountain([]byte("other transfer"), &FountainOptions{BlockSize: 100})
+	if err != nil {
+		t.Fatalf("EncodeFountain failed: %v", err)
+	}
+
+	d := NewFountainDecoder()
+	if _, err := d.AddFrame(a[0].Data); err != nil {
+		t.Fatalf("AddFrame failed: %v", err)
+	}
+	if _, err := d.AddFrame(b[0].Data); err == nil {
+		t.Error("Expected error for frame of another transfer")
+	}
+	for _, s := range []string{"", "hello", "BB8", "not base45!"} {
+		if _, err := d.AddFrame(s); err == nil {
+			t.Errorf("Expected error for frame %q", s)
+		}
+	}
+}
+
+func TestEncodeFountainInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		opts *FountainOptions
+	}{
+		{"empty", nil, nil},
+		{"block size", []byte("x"), &FountainOptions{BlockSize: 5000}},
+		{"negative block size", []byte("x"), &FountainOptions{BlockSize: -1}},
+		{"too few frames", make([]byte, 1000), &FountainOptions{BlockSize: 100, Frames: 9}},
+	}
+	for _, tt := range tests {
+		if _, err := EncodeFountain(tt.data, tt.opts); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
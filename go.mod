@@ -1,13 +1,15 @@
 module github.com/13rac1/qrverify
 
-go 1.23
+go 1.23.0
 
 require (
 	github.com/boombuler/barcode v1.1.0
 	github.com/makiuchi-d/gozxing v0.1.1
+	golang.org/x/crypto v0.41.0
+	golang.org/x/text v0.28.0
 )
 
 require (
-	golang.org/x/text v0.3.7 // indirect
+	golang.org/x/sys v0.35.0 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 )
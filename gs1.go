@@ -0,0 +1,586 @@
+package qrverify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net/url"
+	"strings"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode/decoder"
+	"github.com/makiuchi-d/gozxing/qrcode/encoder"
+)
+
+// gs1DefaultDomain is the resolver of GS1 Digital Link URIs without a
+// domain of their own.
+const gs1DefaultDomain = "https://id.gs1.org"
+
+// gs1CharSet82 is GS1 character set 82, allowed in alphanumeric values.
+const gs1CharSet82 = `!"%&'()*+,-./0123456789:;<=>?ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz`
+
+// GS1Element is a GS1 application identifier (AI) and its value.
+type GS1Element struct {
+	AI    string // Application identifier, such as "01" for a GTIN
+	Value string // Value without the AI, such as "09501101530003"
+}
+
+// gs1Format describes the value of an application identifier.
+type gs1Format struct {
+	numeric  bool // Digits only, otherwise character set 82
+	min, max int  // Value length in characters
+	check    bool // Last digit is a GS1 check digit
+	date     bool // YYMMDD date, DD may be 00 for the end of the month
+}
+
+// gs1AIs lists the supported application identifiers of two and three
+// digits. Four digit measures are handled by gs1Lookup.
+var gs1AIs = map[string]gs1Format{
+	"00":  {numeric: true, min: 18, max: 18, check: true}, // SSCC
+	"01":  {numeric: true, min: 14, max: 14, check: true}, // GTIN
+	"02":  {numeric: true, min: 14, max: 14, check: true}, // GTIN of contained items
+	"10":  {min: 1, max: 20},                              // Batch or lot
+	"11":  {numeric: true, min: 6, max: 6, date: true},    // Production date
+	"12":  {numeric: true, min: 6, max: 6, date: true},    // Due date
+	"13":  {numeric: true, min: 6, max: 6, date: true},    // Packaging date
+	"15":  {numeric: true, min: 6, max: 6, date: true},    // Best before date
+	"16":  {numeric: true, min: 6, max: 6, date: true},    // Sell by date
+	"17":  {numeric: true, min: 6, max: 6, date: true},    // Expiration date
+	"20":  {numeric: true, min: 2, max: 2},                // Variant
+	"21":  {min: 1, max: 20},                              // Serial number
+	"22":  {min: 1, max: 20},                              // Consumer product variant
+	"240": {min: 1, max: 30},                              // Additional product ID
+	"241": {min: 1, max: 30},                              // Customer part number
+	"250": {min: 1, max: 30},                              // Secondary serial number
+	"254": {min: 1, max: 20},                              // GLN extension
+	"30":  {numeric: true, min: 1, max: 8},                // Variable count
+	"37":  {numeric: true, min: 1, max: 8},                // Count of trade items
+	"400": {min: 1, max: 30},                              // Customer purchase order
+	"401": {min: 1, max: 30},                              // GINC
+	"402": {numeric: true, min: 17, max: 17, check: true}, // GSIN
+	"410": {numeric: true, min: 13, max: 13, check: true}, // Ship to GLN
+	"411": {numeric: true, min: 13, max: 13, check: true}, // Bill to GLN
+	"412": {numeric: true, min: 13, max: 13, check: true}, // Purchased from GLN
+	"413": {numeric: true, min: 13, max: 13, check: true}, // Ship for GLN
+	"414": {numeric: true, min: 13, max: 13, check: true}, // Physical location GLN
+	"415": {numeric: true, min: 13, max: 13, check: true}, // Invoicing party GLN
+	"416": {numeric: true, min: 13, max: 13, check: true}, // Production location GLN
+	"417": {numeric: true, min: 13, max: 13, check: true}, // Party GLN
+	"420": {min: 1, max: 20},                              // Ship to postal code
+	"422": {numeric: true, min: 3, max: 3},                // Country of origin
+}
+
+// gs1AIs4 lists the supported application identifiers of four digits
+// other than measures.
+var gs1AIs4 = map[string]gs1Format{
+	"7003": {numeric: true, min: 10, max: 10},              // Expiration date and time
+	"8004": {min: 1, max: 30},                              // GIAI
+	"8017": {numeric: true, min: 18, max: 18, check: true}, // GSRN provider
+	"8018": {numeric: true, min: 18, max: 18, check: true}, // GSRN recipient
+	"8019": {numeric: true, min: 1, max: 10},               // Service relation instance
+	"8020": {min: 1, max: 25},                              // Payment slip reference
+	"8200": {min: 1, max: 70},                              // Product URL
+}
+
+// gs1Predefined maps the first two digits of an AI to the total length of
+// elements with a predefined length, which need no separator. All other
+// elements end with FNC1 unless they are last.
+var gs1Predefined = map[string]int{
+	"00": 20, "01": 16, "02": 16, "03": 16, "04": 18,
+	"11": 8, "12": 8, "13": 8, "14": 8, "15": 8, "16": 8, "17": 8, "18": 8, "19": 8,
+	"20": 4,
+	"31": 10, "32": 10, "33": 10, "34": 10, "35": 10, "36": 10,
+	"41": 16,
+}
+
+// gs1PrimaryKeys lists the Digital Link primary keys and their key
+// qualifiers in the order they appear in the URI path.
+var gs1PrimaryKeys = map[string][]string{
+	"00":   nil,
+	"01":   {"22", "10", "21"},
+	"401":  nil,
+	"402":  nil,
+	"414":  {"254"},
+	"417":  nil,
+	"8004": nil,
+	"8017": {"8019"},
+	"8018": {"8019"},
+}
+
+// gs1Lookup returns the format of ai.
+func gs1Lookup(ai string) (gs1Format, bool) {
+	switch len(ai) {
+	case 2, 3:
+		f, ok := gs1AIs[ai]
+		return f, ok
+	case 4:
+		if f, ok := gs1AIs4[ai]; ok {
+			return f, true
+		}
+		// Measures 310n-369n with n decimal places, amounts 390n and 392n
+		n := ai[3]
+		switch {
+		case ai[0] == '3' && ai[1] >= '1' && ai[1] <= '6' && isDigit(ai[2]) && n >= '0' && n <= '5':
+			return gs1Format{numeric: true, min: 6, max: 6}, true
+		case (ai[:3] == "390" || ai[:3] == "392") && isDigit(n):
+			return gs1Format{numeric: true, min: 1, max: 15}, true
+		}
+	}
+	return gs1Format{}, false
+}
+
+// validateGS1Element checks the value of e against the format of its AI.
+func validateGS1Element(e GS1Element) error {
+	f, ok := gs1Lookup(e.AI)
+	if !ok {
+		return fmt.Errorf("invalid GS1 data: unsupported application identifier (%s)", e.AI)
+	}
+	v := e.Value
+	if len(v) < f.min || len(v) > f.max {
+		if f.min == f.max {
+			return fmt.Errorf("invalid GS1 data: AI (%s) must be %d characters", e.AI, f.min)
+		}
+		return fmt.Errorf("invalid GS1 data: AI (%s) must be %d to %d characters", e.AI, f.min, f.max)
+	}
+	for i := 0; i < len(v); i++ {
+		if f.numeric && !isDigit(v[i]) {
+			return fmt.Errorf("invalid GS1 data: AI (%s) must be digits", e.AI)
+		}
+		if !strings.Contains(gs1CharSet82, v[i:i+1]) {
+			return fmt.Errorf("invalid GS1 data: AI (%s) has a character outside GS1 character set 82", e.AI)
+		}
+	}
+	if f.check {
+		if check, _ := GS1CheckDigit(v[:len(v)-1]); v[len(v)-1] != check {
+			return fmt.Errorf("invalid GS1 data: AI (%s) check digit %c, want %c", e.AI, v[len(v)-1], check)
+		}
+	}
+	if f.date {
+		month, day := atoi2(v[2:4]), atoi2(v[4:6])
+		if month < 1 || month > 12 || day > 31 {
+			return fmt.Errorf("invalid GS1 data: AI (%s) must be a YYMMDD date", e.AI)
+		}
+	}
+	return nil
+}
+
+// atoi2 returns the value of two ASCII digits.
+func atoi2(s string) int {
+	return int(s[0]-'0')*10 + int(s[1]-'0')
+}
+
+// validateGS1Elements checks each element and rejects repeated AIs.
+func validateGS1Elements(elements []GS1Element) error {
+	if len(elements) == 0 {
+		return errors.New("invalid GS1 data: no elements")
+	}
+	seen := make(map[string]bool, len(elements))
+	for _, e := range elements {
+		if err := validateGS1Element(e); err != nil {
+			return err
+		}
+		if seen[e.AI] {
+			return fmt.Errorf("invalid GS1 data: AI (%s) is repeated", e.AI)
+		}
+		seen[e.AI] = true
+	}
+	return nil
+}
+
+// FormatGS1 returns elements as a GS1 element string in the given order.
+// Elements without a predefined length are terminated by an ASCII group
+// separator (0x1D), which symbols encode as FNC1, unless they are last.
+// The result can be passed to EncodeDataMatrix and EncodeLinear in GS1
+// mode.
+func FormatGS1(elements []GS1Element) (string, error) {
+	if err := validateGS1Elements(elements); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for i, e := range elements {
+		b.WriteString(e.AI)
+		b.WriteString(e.Value)
+		if _, ok := gs1Predefined[e.AI[:2]]; !ok && i < len(elements)-1 {
+			b.WriteString(gs1Separator)
+		}
+	}
+	return b.String(), nil
+}
+
+// ParseGS1 parses a GS1 element string with group separators (0x1D) for
+// FNC1, as read from a GS1 symbol. A leading separator is ignored. Every
+// element is validated, including check digits.
+func ParseGS1(s string) ([]GS1Element, error) {
+	s = strings.TrimPrefix(s, gs1Separator)
+	if s == "" {
+		return nil, errors.New("invalid GS1 data: no elements")
+	}
+	var elements []GS1Element
+	for s != "" {
+		n := 2
+		for ; n <= 4 && n <= len(s); n++ {
+			if _, ok := gs1Lookup(s[:n]); ok {
+				break
+			}
+		}
+		if n > 4 || n > len(s) {
+			return nil, fmt.Errorf("invalid GS1 data: unsupported application identifier at %q", s[:min(4, len(s))])
+		}
+		ai := s[:n]
+
+		var value string
+		if total, ok := gs1Predefined[ai[:2]]; ok {
+			if len(s) < total {
+				return nil, fmt.Errorf("invalid GS1 data: AI (%s) is truncated", ai)
+			}
+			value, s = s[n:total], strings.TrimPrefix(s[total:], gs1Separator)
+		} else {
+			value, s, _ = strings.Cut(s[n:], gs1Separator)
+		}
+		elements = append(elements, GS1Element{AI: ai, Value: value})
+	}
+	if err := validateGS1Elements(elements); err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+// checkGS1Elements compares decoded elements with the original ones by
+// AI, ignoring their order.
+func checkGS1Elements(original, decoded []GS1Element) error {
+	values := make(map[string]string, len(decoded))
+	for _, e := range decoded {
+		values[e.AI] = e.Value
+	}
+	for _, e := range original {
+		if err := checkField("GS1 AI ("+e.AI+")", e.Value, values[e.AI]); err != nil {
+			return err
+		}
+		delete(values, e.AI)
+	}
+	for _, e := range decoded {
+		if _, extra := values[e.AI]; extra {
+			return checkField("GS1 AI ("+e.AI+")", "", e.Value)
+		}
+	}
+	return nil
+}
+
+// GS1DigitalLink is a GS1 Digital Link URI, such as
+// https://id.gs1.org/01/09501101530003/10/ABC123?17=250101. The primary
+// key and its key qualifiers form the path; other elements become query
+// parameters. Verification compares the elements, not the URI text.
+type GS1DigitalLink struct {
+	Domain   string       // URI up to the primary key, zero value uses https://id.gs1.org
+	Elements []GS1Element // Exactly one primary key, such as a GTIN (01), and any attributes
+}
+
+// Encode returns the Digital Link URI.
+func (d *GS1DigitalLink) Encode(limit int) (string, error) {
+	domain, err := d.domain()
+	if err != nil {
+		return "", err
+	}
+	if err := validateGS1Elements(d.Elements); err != nil {
+		return "", err
+	}
+
+	key := -1
+	for i, e := range d.Elements {
+		if _, ok := gs1PrimaryKeys[e.AI]; ok {
+			if key >= 0 {
+				return "", fmt.Errorf("invalid GS1 Digital Link: primary keys (%s) and (%s)", d.Elements[key].AI, e.AI)
+			}
+			key = i
+		}
+	}
+	if key < 0 {
+		return "", errors.New("invalid GS1 Digital Link: no primary key such as GTIN (01)")
+	}
+
+	primary := d.Elements[key]
+	var b strings.Builder
+	b.WriteString(domain)
+	inPath := map[string]bool{primary.AI: true}
+	fmt.Fprintf(&b, "/%s/%s", primary.AI, uriEscape(primary.Value))
+	for _, q := range gs1PrimaryKeys[primary.AI] {
+		for _, e := range d.Elements {
+			if e.AI == q {
+				fmt.Fprintf(&b, "/%s/%s", e.AI, uriEscape(e.Value))
+				inPath[e.AI] = true
+			}
+		}
+	}
+	sep := "?"
+	for _, e := range d.Elements {
+		if !inPath[e.AI] {
+			fmt.Fprintf(&b, "%s%s=%s", sep, e.AI, uriEscape(e.Value))
+			sep = "&"
+		}
+	}
+	return checkLimit("GS1 Digital Link", b.String(), limit)
+}
+
+// Check parses decoded as a Digital Link URI and compares its domain and
+// elements with d.
+func (d *GS1DigitalLink) Check(decoded string) error {
+	got, err := ParseGS1DigitalLink(decoded)
+	if err != nil {
+		return err
+	}
+	domain, err := d.domain()
+	if err != nil {
+		return err
+	}
+	if err := checkField("GS1DigitalLink.Domain", domain, got.Domain); err != nil {
+		return err
+	}
+	return checkGS1Elements(d.Elements, got.Elements)
+}
+
+// domain returns the validated URI prefix without a trailing slash.
+func (d *GS1DigitalLink) domain() (string, error) {
+	if d.Domain == "" {
+		return gs1DefaultDomain, nil
+	}
+	u, err := url.Parse(d.Domain)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", errors.New("invalid GS1 Digital Link: domain must be an http or https URL without query")
+	}
+	return strings.TrimSuffix(d.Domain, "/"), nil
+}
+
+// ParseGS1DigitalLink parses an uncompressed GS1 Digital Link URI. Path
+// segments before the primary key are part of the domain. GTIN-8, -12 and
+// -13 are padded to 14 digits. Query parameters that are not AIs, such as
+// linkType, are ignored.
+func ParseGS1DigitalLink(s string) (*GS1DigitalLink, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, errors.New("invalid GS1 Digital Link: must be an http or https URL")
+	}
+
+	segments := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	key := -1
+	for i := 0; i+1 < len(segments); i++ {
+		if _, ok := gs1PrimaryKeys[segments[i]]; ok {
+			key = i
+			break
+		}
+	}
+	if key < 0 {
+		return nil, errors.New("invalid GS1 Digital Link: no primary key in path")
+	}
+	if (len(segments)-key)%2 != 0 {
+		return nil, errors.New("invalid GS1 Digital Link: path must be AI and value pairs")
+	}
+
+	d := &GS1DigitalLink{Domain: u.Scheme + "://" + u.Host}
+	if key > 0 {
+		d.Domain += "/" + strings.Join(segments[:key], "/")
+	}
+	qualifiers := gs1PrimaryKeys[segments[key]]
+	for i := key; i < len(segments); i += 2 {
+		ai := segments[i]
+		value, err := url.PathUnescape(segments[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid GS1 Digital Link: AI (%s) value is not escaped correctly", ai)
+		}
+		if i > key {
+			// Qualifiers follow the primary key in their fixed order
+			n := 0
+			for n < len(qualifiers) && qualifiers[n] != ai {
+				n++
+			}
+			if n == len(qualifiers) {
+				return nil, fmt.Errorf("invalid GS1 Digital Link: AI (%s) is not a key qualifier of (%s)", ai, segments[key])
+			}
+			qualifiers = qualifiers[n+1:]
+		}
+		if ai == "01" && len(value) < 14 && (len(value) == 8 || len(value) == 12 || len(value) == 13) {
+			value = strings.Repeat("0", 14-len(value)) + value
+		}
+		d.Elements = append(d.Elements, GS1Element{AI: ai, Value: value})
+	}
+
+	if u.RawQuery != "" {
+		for _, param := range strings.Split(u.RawQuery, "&") {
+			name, value, _ := strings.Cut(param, "=")
+			if _, ok := gs1Lookup(name); !ok {
+				continue
+			}
+			value, err := url.QueryUnescape(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid GS1 Digital Link: AI (%s) value is not escaped correctly", name)
+			}
+			d.Elements = append(d.Elements, GS1Element{AI: name, Value: value})
+		}
+	}
+
+	if err := validateGS1Elements(d.Elements); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// EncodeGS1 generates a verified GS1 QR code: the element string of
+// elements in FNC1 mode. Verification requires the FNC1 symbology
+// identifier ]Q3, compares the text byte for byte and parses it back into
+// AIs. opts.Cache is not used.
+func EncodeGS1(elements []GS1Element, opts *EncodeOptions) (*Result, error) {
+	return EncodeGS1Context(context.Background(), elements, opts)
+}
+
+// EncodeGS1Context is like EncodeGS1 but honors cancellation and
+// deadlines of ctx.
+func EncodeGS1Context(ctx context.Context, elements []GS1Element, opts *EncodeOptions) (*Result, error) {
+	data, err := FormatGS1(elements)
+	if err != nil {
+		return nil, err
+	}
+	recovery := opts.recovery()
+	size := 256
+	verifyOutput := false
+	if opts != nil {
+		verifyOutput = opts.VerifyOutput
+		if opts.Size > 0 {
+			size = opts.Size
+		}
+	}
+	if len(data) > maxBytes(recovery) {
+		return nil, fmt.Errorf("data too large: %d bytes exceeds %d byte limit for %v recovery",
+			len(data), maxBytes(recovery), recovery)
+	}
+
+	if err := canceled(ctx, "render"); err != nil {
+		return nil, err
+	}
+	modules, err := gs1QRModules(data, recovery)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks := encodeHooks{check: func(decoded string) error {
+		got, err := ParseGS1(decoded)
+		if err != nil {
+			return err
+		}
+		return checkGS1Elements(elements, got)
+	}}
+	png, err := encodeSymbol(ctx, data, modules, gs1QRSymbology, size, size, verifyOutput, hooks)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Image:    png,
+		Data:     data,
+		Recovery: recovery,
+		Size:     size,
+	}, nil
+}
+
+// VerifyGS1 checks that qrImage (PNG bytes) holds a QR code with the GS1
+// elements, in any order, either as an FNC1 mode element string or as a
+// GS1 Digital Link URI.
+func VerifyGS1(qrImage []byte, elements []GS1Element) error {
+	img, err := png.Decode(bytes.NewReader(qrImage))
+	if err != nil {
+		return fmt.Errorf("failed to decode PNG: %w", err)
+	}
+
+	reader := getReader()
+	defer putReader(reader)
+	result, err := readBarcode(img, reader, nil)
+	if err != nil {
+		return fmt.Errorf("failed to read QR code: %w", err)
+	}
+
+	var got []GS1Element
+	if id, _ := result.GetResultMetadata()[gozxing.ResultMetadataType_SYMBOLOGY_IDENTIFIER].(string); id == "]Q3" {
+		got, err = ParseGS1(result.GetText())
+	} else {
+		var link *GS1DigitalLink
+		if link, err = ParseGS1DigitalLink(result.GetText()); err == nil {
+			got = link.Elements
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return checkGS1Elements(elements, got)
+}
+
+// gs1QRSymbology reads GS1 QR codes. The symbol must carry the FNC1 in
+// first position symbology identifier ]Q3.
+var gs1QRSymbology = symbology{
+	name: "QR code",
+	decode: func(img image.Image) (string, error) {
+		reader := getReader()
+		defer putReader(reader)
+		result, err := readBarcode(img, reader, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode QR code: %w", err)
+		}
+		if id, _ := result.GetResultMetadata()[gozxing.ResultMetadataType_SYMBOLOGY_IDENTIFIER].(string); id != "]Q3" {
+			return "", errors.New("verification failed: QR code is not a GS1 symbol")
+		}
+		return result.GetText(), nil
+	},
+}
+
+// gs1QRModules encodes the element string data as a QR code in FNC1 mode
+// at one pixel per module, without a quiet zone like Encode.
+func gs1QRModules(data string, recovery Recovery) (*image.Gray, error) {
+	hints := map[gozxing.EncodeHintType]interface{}{gozxing.EncodeHintType_GS1_FORMAT: true}
+	code, err := encoder.Encoder_encode(gs1QRContent(data), qrLevel(recovery), hints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create QR code: %w", err)
+	}
+	return matrixImage(code.GetMatrix()), nil
+}
+
+// qrLevel maps Recovery to the gozxing error correction level.
+func qrLevel(r Recovery) decoder.ErrorCorrectionLevel {
+	switch r {
+	case Low:
+		return decoder.ErrorCorrectionLevel_L
+	case High:
+		return decoder.ErrorCorrectionLevel_Q
+	case Highest:
+		return decoder.ErrorCorrectionLevel_H
+	default:
+		return decoder.ErrorCorrectionLevel_M
+	}
+}
+
+// matrixImage converts a gozxing QR matrix to an image at one pixel per
+// module.
+func matrixImage(matrix *encoder.ByteMatrix) *image.Gray {
+	w, h := matrix.GetWidth(), matrix.GetHeight()
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if matrix.Get(x, y) != 1 {
+				img.Pix[y*img.Stride+x] = 0xff
+			}
+		}
+	}
+	return img
+}
+
+// gs1QRContent returns data in the form the QR encoder stores it. Data
+// that fits alphanumeric mode has FNC1 written as % and % as %%, as
+// readers expect in FNC1 mode; byte mode stores group separators as is.
+func gs1QRContent(data string) string {
+	const alphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+	for i := 0; i < len(data); i++ {
+		if data[i] != gs1Separator[0] && !strings.Contains(alphanumeric, data[i:i+1]) {
+			return data
+		}
+	}
+	return strings.NewReplacer("%", "%%", gs1Separator, "%").Replace(data)
+}
@@ -0,0 +1,160 @@
+package qrverify
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testGS1Elements = []GS1Element{
+	{AI: "01", Value: "09501101530003"},
+	{AI: "10", Value: "ABC123"},
+	{AI: "17", Value: "250101"},
+	{AI: "3103", Value: "000195"},
+	{AI: "21", Value: "xyz-1"},
+}
+
+func TestFormatGS1(t *testing.T) {
+	s, err := FormatGS1(testGS1Elements)
+	if err != nil {
+		t.Fatalf("FormatGS1 failed: %v", err)
+	}
+	want := "0109501101530003" + "10ABC123\x1d" + "17250101" + "3103000195" + "21xyz-1"
+	if s != want {
+		t.Errorf("FormatGS1() = %q, want %q", s, want)
+	}
+
+	got, err := ParseGS1("\x1d" + s)
+	if err != nil {
+		t.Fatalf("ParseGS1 failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, testGS1Elements) {
+		t.Errorf("ParseGS1() = %v", got)
+	}
+}
+
+func TestGS1Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements []GS1Element
+	}{
+		{"none", nil},
+		{"unknown AI", []GS1Element{{"99", "A"}}},
+		{"GTIN check digit", []GS1Element{{"01", "09501101530004"}}},
+		{"GTIN length", []GS1Element{{"01", "9501101530003"}}},
+		{"numeric", []GS1Element{{"30", "12A"}}},
+		{"too long", []GS1Element{{"10", strings.Repeat("A", 21)}}},
+		{"character set", []GS1Element{{"10", "A B"}}},
+		{"date", []GS1Element{{"17", "251301"}}},
+		{"measure decimals", []GS1Element{{"3106", "000195"}}},
+		{"repeated", []GS1Element{{"10", "A"}, {"10", "B"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := FormatGS1(tt.elements); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	for _, s := range []string{"", "0109501101530003\x1d99X", "01095011015300", "10ABC\x1d10DEF"} {
+		if _, err := ParseGS1(s); err == nil {
+			t.Errorf("ParseGS1(%q) expected error", s)
+		}
+	}
+}
+
+func TestGS1DigitalLink(t *testing.T) {
+	d := &GS1DigitalLink{Elements: testGS1Elements}
+	uri, err := d.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	want := "https://id.gs1.org/01/09501101530003/10/ABC123/21/xyz-1?17=250101&3103=000195"
+	if uri != want {
+		t.Errorf("Encode() = %q, want %q", uri, want)
+	}
+	if err := d.Check(uri); err != nil {
+		t.Errorf("Check failed: %v", err)
+	}
+
+	result, err := EncodePayload(d, nil)
+	if err != nil {
+		t.Fatalf("EncodePayload failed: %v", err)
+	}
+	if err := VerifyGS1(result.Image, testGS1Elements); err != nil {
+		t.Errorf("VerifyGS1 failed: %v", err)
+	}
+	var fe *FieldError
+	if err := VerifyGS1(result.Image, testGS1Elements[:2]); !errors.As(err, &fe) || fe.Field != "GS1 AI (21)" {
+		t.Errorf("VerifyGS1 with missing element = %v, want FieldError for AI (21)", err)
+	}
+}
+
+func TestParseGS1DigitalLink(t *testing.T) {
+	d, err := ParseGS1DigitalLink("https://example.com/shop/01/9501101530003/21/12%2F3?linkType=gs1:pip&17=250100")
+	if err != nil {
+		t.Fatalf("ParseGS1DigitalLink failed: %v", err)
+	}
+	want := &GS1DigitalLink{
+		Domain: "https://example.com/shop",
+		Elements: []GS1Element{
+			{AI: "01", Value: "09501101530003"},
+			{AI: "21", Value: "12/3"},
+			{AI: "17", Value: "250100"},
+		},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("ParseGS1DigitalLink() = %+v", d)
+	}
+
+	for _, s := range []string{
+		"ftp://id.gs1.org/01/09501101530003",
+		"https://id.gs1.org/10/ABC",
+		"https://id.gs1.org/01/09501101530003/21",
+		"https://id.gs1.org/01/09501101530003/21/1/10/A",
+		"https://id.gs1.org/01/09501101530004",
+	} {
+		if _, err := ParseGS1DigitalLink(s); err == nil {
+			t.Errorf("ParseGS1DigitalLink(%q) expected error", s)
+		}
+	}
+
+	if _, err := (&GS1DigitalLink{Elements: testGS1Elements[1:]}).Encode(MaxBytesMedium); err == nil {
+		t.Error("expected error without primary key")
+	}
+	if _, err := (&GS1DigitalLink{Domain: "example.com", Elements: testGS1Elements}).Encode(MaxBytesMedium); err == nil {
+		t.Error("expected error for domain without scheme")
+	}
+}
+
+func TestEncodeGS1(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements []GS1Element
+	}{
+		{"byte mode", testGS1Elements},
+		{"alphanumeric mode", []GS1Element{{"01", "09501101530003"}, {"10", "AB%12"}, {"17", "250101"}}},
+		{"numeric mode", []GS1Element{{"00", "095011015300000010"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := EncodeGS1(tt.elements, &EncodeOptions{VerifyOutput: true})
+			if err != nil {
+				t.Fatalf("EncodeGS1 failed: %v", err)
+			}
+			if err := VerifyGS1(result.Image, tt.elements); err != nil {
+				t.Errorf("VerifyGS1 failed: %v", err)
+			}
+		})
+	}
+
+	plain, err := Encode("0109501101530003", nil)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := VerifyGS1(plain, []GS1Element{{"01", "09501101530003"}}); err == nil {
+		t.Error("expected error for a QR code without FNC1")
+	}
+}
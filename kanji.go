@@ -0,0 +1,257 @@
+package qrverify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"unicode/utf8"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/common/reedsolomon"
+	"github.com/makiuchi-d/gozxing/qrcode/decoder"
+	"github.com/makiuchi-d/gozxing/qrcode/encoder"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// eciShiftJIS is the ECI designator of Shift_JIS byte segments.
+const eciShiftJIS = 20
+
+// kanjiSegment is a run of Shift_JIS bytes stored in one QR mode.
+type kanjiSegment struct {
+	kanji bool   // Kanji mode, two bytes per character, otherwise byte mode
+	data  []byte // Shift_JIS bytes
+}
+
+// kanjiSegments transcodes data to Shift_JIS and splits it into runs of
+// kanji mode characters and runs of other characters for byte mode.
+func kanjiSegments(data string) ([]kanjiSegment, error) {
+	if !utf8.ValidString(data) {
+		return nil, errors.New("invalid kanji data: must be UTF-8")
+	}
+	enc := japanese.ShiftJIS.NewEncoder()
+	var segments []kanjiSegment
+	for _, r := range data {
+		b, err := enc.Bytes([]byte(string(r)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid kanji data: %q is not representable in Shift_JIS", r)
+		}
+		kanji := len(b) == 2 && kanjiCode(b) >= 0
+		if n := len(segments); n > 0 && segments[n-1].kanji == kanji {
+			segments[n-1].data = append(segments[n-1].data, b...)
+			continue
+		}
+		segments = append(segments, kanjiSegment{kanji: kanji, data: b})
+	}
+	return segments, nil
+}
+
+// kanjiCode returns the 13-bit kanji mode value of a double-byte
+// Shift_JIS character, or -1 if kanji mode can not encode it.
+func kanjiCode(b []byte) int {
+	c := int(b[0])<<8 | int(b[1])
+	switch {
+	case c >= 0x8140 && c <= 0x9ffc:
+		c -= 0x8140
+	case c >= 0xe040 && c <= 0xebbf:
+		c -= 0xc140
+	default:
+		return -1
+	}
+	return (c>>8)*0xc0 + c&0xff
+}
+
+// kanjiBits returns the data bits of segments, including mode indicators
+// and character counts, for QR version. Byte segments with bytes beyond
+// ASCII are preceded by the Shift_JIS ECI.
+func kanjiBits(segments []kanjiSegment, version *decoder.Version) *gozxing.BitArray {
+	bits := gozxing.NewEmptyBitArray()
+	if needsECI(segments) {
+		bits.AppendBits(decoder.Mode_ECI.GetBits(), 4)
+		bits.AppendBits(eciShiftJIS, 8)
+	}
+	for _, s := range segments {
+		if s.kanji {
+			bits.AppendBits(decoder.Mode_KANJI.GetBits(), 4)
+			bits.AppendBits(len(s.data)/2, decoder.Mode_KANJI.GetCharacterCountBits(version))
+			for i := 0; i < len(s.data); i += 2 {
+				bits.AppendBits(kanjiCode(s.data[i:i+2]), 13)
+			}
+		} else {
+			bits.AppendBits(decoder.Mode_BYTE.GetBits(), 4)
+			bits.AppendBits(len(s.data), decoder.Mode_BYTE.GetCharacterCountBits(version))
+			for _, c := range s.data {
+				bits.AppendBits(int(c), 8)
+			}
+		}
+	}
+	return bits
+}
+
+// needsECI reports whether a byte segment holds bytes beyond ASCII, which
+// readers would otherwise guess the character set of.
+func needsECI(segments []kanjiSegment) bool {
+	for _, s := range segments {
+		if s.kanji {
+			continue
+		}
+		for _, c := range s.data {
+			if c >= 0x80 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// kanjiVersion returns the smallest QR version whose data capacity at
+// recovery holds segments, with the data bits for that version.
+func kanjiVersion(segments []kanjiSegment, recovery Recovery) (*decoder.Version, *gozxing.BitArray, error) {
+	level := qrLevel(recovery)
+	var bits *gozxing.BitArray
+	limit := 0
+	for v := 1; v <= 40; v++ {
+		version, err := decoder.Version_GetVersionForNumber(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		bits = kanjiBits(segments, version)
+		ec := version.GetECBlocksForLevel(level)
+		limit = 8 * (version.GetTotalCodewords() - ec.GetTotalECCodewords())
+		if bits.GetSize() <= limit {
+			return version, bits, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("data too large: %d bits in kanji mode exceed %d bit limit for %v recovery",
+		bits.GetSize(), limit, recovery)
+}
+
+// checkKanjiCapacity returns an error if data does not fit the largest QR
+// code at recovery in kanji mode.
+func checkKanjiCapacity(data string, recovery Recovery) error {
+	segments, err := kanjiSegments(data)
+	if err != nil {
+		return err
+	}
+	_, _, err = kanjiVersion(segments, recovery)
+	return err
+}
+
+// encodeKanji is like encodeAndVerify with kanji mode segments.
+func encodeKanji(ctx context.Context, data string, recovery Recovery, size int, verifyOutput bool, hooks encodeHooks) ([]byte, error) {
+	if err := canceled(ctx, "render"); err != nil {
+		return nil, err
+	}
+	modules, err := kanjiModules(data, recovery)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSymbol(ctx, data, modules, kanjiSymbology, size, size, verifyOutput, hooks)
+}
+
+// kanjiModules encodes data as a QR code with kanji mode segments at one
+// pixel per module, without a quiet zone like Encode.
+func kanjiModules(data string, recovery Recovery) (*image.Gray, error) {
+	segments, err := kanjiSegments(data)
+	if err != nil {
+		return nil, err
+	}
+	version, bits, err := kanjiVersion(segments, recovery)
+	if err != nil {
+		return nil, err
+	}
+
+	level := qrLevel(recovery)
+	ec := version.GetECBlocksForLevel(level)
+	dataBytes := version.GetTotalCodewords() - ec.GetTotalECCodewords()
+
+	// Terminator, byte alignment and alternating pad codewords
+	bits.AppendBits(0, min(4, 8*dataBytes-bits.GetSize()))
+	if n := bits.GetSize() % 8; n != 0 {
+		bits.AppendBits(0, 8-n)
+	}
+	for pad := 0xec; bits.GetSizeInBytes() < dataBytes; pad ^= 0xec ^ 0x11 {
+		bits.AppendBits(pad, 8)
+	}
+	codewords := make([]byte, dataBytes)
+	bits.ToBytes(0, codewords, 0, dataBytes)
+
+	final, err := qrInterleave(codewords, ec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create QR code: %w", err)
+	}
+
+	// Pick the mask with the lowest penalty, as the encoder does
+	dimension := version.GetDimensionForVersion()
+	var best *encoder.ByteMatrix
+	bestPenalty := 0
+	for mask := 0; mask < 8; mask++ {
+		matrix := encoder.NewByteMatrix(dimension, dimension)
+		if err := encoder.MatrixUtil_buildMatrix(final, level, version, mask, matrix); err != nil {
+			return nil, fmt.Errorf("failed to create QR code: %w", err)
+		}
+		penalty := encoder.MaskUtil_applyMaskPenaltyRule1(matrix) +
+			encoder.MaskUtil_applyMaskPenaltyRule2(matrix) +
+			encoder.MaskUtil_applyMaskPenaltyRule3(matrix) +
+			encoder.MaskUtil_applyMaskPenaltyRule4(matrix)
+		if best == nil || penalty < bestPenalty {
+			best, bestPenalty = matrix, penalty
+		}
+	}
+	return matrixImage(best), nil
+}
+
+// qrInterleave splits codewords into the error correction blocks of ec,
+// appends Reed-Solomon codewords to each and interleaves the blocks.
+func qrInterleave(codewords []byte, ec *decoder.ECBlocks) (*gozxing.BitArray, error) {
+	rs := reedsolomon.NewReedSolomonEncoder(reedsolomon.GenericGF_QR_CODE_FIELD_256)
+	ecCount := ec.GetECCodewordsPerBlock()
+	var blocks [][]int
+	maxData := 0
+	for _, b := range ec.GetECBlocks() {
+		for i := 0; i < b.GetCount(); i++ {
+			n := b.GetDataCodewords()
+			block := make([]int, n+ecCount)
+			for j := range n {
+				block[j] = int(codewords[j])
+			}
+			codewords = codewords[n:]
+			if err := rs.Encode(block, ecCount); err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, block)
+			maxData = max(maxData, n)
+		}
+	}
+
+	bits := gozxing.NewEmptyBitArray()
+	for i := 0; i < maxData; i++ {
+		for _, block := range blocks {
+			if n := len(block) - ecCount; i < n {
+				bits.AppendBits(block[i], 8)
+			}
+		}
+	}
+	for i := 0; i < ecCount; i++ {
+		for _, block := range blocks {
+			bits.AppendBits(block[len(block)-ecCount+i], 8)
+		}
+	}
+	return bits, nil
+}
+
+// kanjiSymbology reads QR codes with byte segments decoded as Shift_JIS.
+// Kanji segments are always Shift_JIS; the decoded text is UTF-8.
+var kanjiSymbology = symbology{
+	name: "QR code",
+	decode: func(img image.Image) (string, error) {
+		reader := getReader()
+		defer putReader(reader)
+		hints := map[gozxing.DecodeHintType]interface{}{gozxing.DecodeHintType_CHARACTER_SET: "Shift_JIS"}
+		result, err := readBarcode(img, reader, hints)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode QR code: %w", err)
+		}
+		return result.GetText(), nil
+	},
+}
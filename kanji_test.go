@@ -0,0 +1,123 @@
+package qrverify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/boombuler/barcode/qr"
+)
+
+func TestKanjiCode(t *testing.T) {
+	// 点 and 茗 are the examples of ISO/IEC 18004 7.4.6
+	tests := []struct {
+		sjis []byte
+		want int
+	}{
+		{[]byte{0x93, 0x5f}, 0x0d9f}, // 点
+		{[]byte{0xe4, 0xaa}, 0x1aaa}, // 茗
+		{[]byte{0x82, 0xa0}, 0x0120}, // あ
+		{[]byte{0xfa, 0x40}, -1},     // IBM extension, byte mode only
+	}
+	for _, tt := range tests {
+		if got := kanjiCode(tt.sjis); got != tt.want {
+			t.Errorf("kanjiCode(% x) = %#x, want %#x", tt.sjis, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeKanji(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"kanji", "抹茶アイスクリーム"},
+		{"mixed", "商品名: 抹茶アイス 120g"},
+		{"half-width katakana", "ﾏｯﾁｬ 抹茶"},
+		{"ascii", "ABC-123"},
+		{"beyond byte limit", strings.Repeat("漢字", 700)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := EncodeDetailed(tt.data, &EncodeOptions{Kanji: true, VerifyOutput: true})
+			if err != nil {
+				t.Fatalf("EncodeDetailed failed: %v", err)
+			}
+			if err := Verify(result.Image, tt.data); err != nil {
+				t.Errorf("Verify failed: %v", err)
+			}
+		})
+	}
+
+	if _, err := EncodeDetailed(strings.Repeat("漢字", 700), nil); err == nil {
+		t.Error("expected byte mode to exceed the byte limit")
+	}
+}
+
+func TestEncodeKanjiSmaller(t *testing.T) {
+	data := strings.Repeat("日本語の商品名", 20)
+	segments, err := kanjiSegments(data)
+	if err != nil {
+		t.Fatalf("kanjiSegments failed: %v", err)
+	}
+	version, _, err := kanjiVersion(segments, Medium)
+	if err != nil {
+		t.Fatalf("kanjiVersion failed: %v", err)
+	}
+	kanji, err := kanjiModules(data, Medium)
+	if err != nil {
+		t.Fatalf("kanjiModules failed: %v", err)
+	}
+	if n := kanji.Bounds().Dx(); n != version.GetDimensionForVersion() {
+		t.Errorf("kanji symbol is %d modules, want %d", n, version.GetDimensionForVersion())
+	}
+
+	utf8, err := qr.Encode(data, qr.M, qr.Auto)
+	if err != nil {
+		t.Fatalf("byte mode encoding failed: %v", err)
+	}
+	if kanji.Bounds().Dx() >= utf8.Bounds().Dx() {
+		t.Errorf("kanji symbol %d modules, byte mode %d modules", kanji.Bounds().Dx(), utf8.Bounds().Dx())
+	}
+}
+
+func TestEncodeKanjiInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"emoji", "抹茶 😀"},
+		{"invalid UTF-8", "\xff\xfe"},
+		{"too large", strings.Repeat("漢字", 800)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := EncodeDetailed(tt.data, &EncodeOptions{Kanji: true}); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestEncodeKanjiCache(t *testing.T) {
+	cache := NewLRUCache(4)
+	data := "抹茶"
+	if _, err := EncodeDetailed(data, &EncodeOptions{Cache: cache}); err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+	result, err := EncodeDetailed(data, &EncodeOptions{Cache: cache, Kanji: true})
+	if err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+	if result.Cached {
+		t.Error("kanji mode was served the cached byte mode image")
+	}
+
+	// Rechecks read the cached image like the kanji encoder verified it
+	result, err = EncodeDetailed(data, &EncodeOptions{Cache: cache, Kanji: true, VerifyCached: true})
+	if err != nil {
+		t.Fatalf("EncodeDetailed failed: %v", err)
+	}
+	if !result.Cached {
+		t.Error("kanji mode recheck missed the cached image")
+	}
+}
@@ -0,0 +1,301 @@
+package qrverify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/code39"
+	"github.com/boombuler/barcode/ean"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/oned"
+)
+
+// LinearFormat selects a 1D barcode symbology.
+type LinearFormat int
+
+const (
+	Code128 LinearFormat = iota // Code 128, ASCII text
+	GS1128                      // GS1-128, Code 128 with FNC1 and GS1 element strings
+	EAN13                       // EAN-13, 13 digits including the check digit
+	UPCA                        // UPC-A, 12 digits including the check digit
+	Code39                      // Code 39, upper case letters, digits and -. $/+%
+)
+
+// String returns the symbology name.
+func (f LinearFormat) String() string {
+	switch f {
+	case Code128:
+		return "Code 128"
+	case GS1128:
+		return "GS1-128"
+	case EAN13:
+		return "EAN-13"
+	case UPCA:
+		return "UPC-A"
+	case Code39:
+		return "Code 39"
+	default:
+		return "LinearFormat(unknown)"
+	}
+}
+
+// quietZone returns the minimum quiet zone of the symbology in modules.
+func (f LinearFormat) quietZone() int {
+	switch f {
+	case EAN13:
+		return 11
+	case UPCA:
+		return 9
+	default:
+		return 10
+	}
+}
+
+// LinearOptions configures 1D barcode generation.
+// Zero values provide sensible defaults.
+type LinearOptions struct {
+	// BarHeight is the height of the bars in modules, the width of the
+	// narrowest bar.
+	// Zero value uses 50 or 15% of the symbol width, whichever is larger.
+	BarHeight int
+
+	// QuietZone is the blank margin left and right of the bars in
+	// modules. Margins below the symbology's minimum may not scan.
+	// Zero value uses the minimum: 11 for EAN-13, 9 for UPC-A and 10
+	// otherwise.
+	QuietZone int
+
+	// CheckDigit appends a modulo 43 check character to Code 39 symbols.
+	// Other symbologies always carry a check digit.
+	CheckDigit bool
+
+	// FullASCII encodes any ASCII text in Code 39 as pairs of characters.
+	// Readers must be set to full ASCII mode to decode it.
+	FullASCII bool
+
+	// Size is the image width in pixels. The height follows the bar
+	// height.
+	// Zero value uses two pixels per module.
+	Size int
+
+	// VerifyOutput additionally decodes the final PNG bytes.
+	VerifyOutput bool
+}
+
+// EncodeLinear generates a verified 1D barcode PNG image in format.
+// Verification decodes the bars with a reader for the symbology and
+// compares the text byte for byte.
+//
+// GS1-128 data is a GS1 element string; group separators (0x1D) after
+// variable length fields become FNC1. EAN-13 and UPC-A data are digits
+// with or without the check digit: a missing check digit is computed, a
+// present one must be correct. Result.Data holds all digits. The Code 39
+// check character is not part of Result.Data.
+func EncodeLinear(data string, format LinearFormat, opts *LinearOptions) (*Result, error) {
+	return EncodeLinearContext(context.Background(), data, format, opts)
+}
+
+// EncodeLinearContext is like EncodeLinear but honors cancellation and
+// deadlines of ctx.
+func EncodeLinearContext(ctx context.Context, data string, format LinearFormat, opts *LinearOptions) (*Result, error) {
+	if opts == nil {
+		opts = &LinearOptions{}
+	}
+	if opts.BarHeight < 0 || opts.QuietZone < 0 {
+		return nil, fmt.Errorf("failed to create %v: negative bar height or quiet zone", format)
+	}
+
+	if err := canceled(ctx, "render"); err != nil {
+		return nil, err
+	}
+	data, bc, err := linearBars(data, format, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	width := bc.Bounds().Dx()
+	height := opts.BarHeight
+	if height == 0 {
+		height = max(50, width*15/100)
+	}
+	quiet := format.quietZone()
+	if opts.QuietZone > 0 {
+		quiet = opts.QuietZone
+	}
+
+	modules := image.NewGray(image.Rect(0, 0, width+2*quiet, height))
+	for i := range modules.Pix {
+		modules.Pix[i] = 0xff
+	}
+	for x := 0; x < width; x++ {
+		if r, _, _, _ := bc.At(x, 0).RGBA(); r == 0 {
+			for y := 0; y < height; y++ {
+				modules.Pix[y*modules.Stride+quiet+x] = 0
+			}
+		}
+	}
+
+	b := modules.Bounds()
+	size := 2 * b.Dx()
+	if opts.Size > 0 {
+		size = opts.Size
+	}
+	img, err := encodeSymbol(ctx, data, modules, linearSymbology(format, opts), size, size*b.Dy()/b.Dx(), opts.VerifyOutput, encodeHooks{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Image: img,
+		Data:  data,
+		Size:  size,
+		Symbol: &SymbolInfo{
+			Format:  format.String(),
+			Rows:    height,
+			Columns: width,
+		},
+	}, nil
+}
+
+// VerifyLinear checks that qrImage (PNG bytes) holds a 1D barcode in
+// format that decodes to expectedData. opts.CheckDigit and opts.FullASCII
+// select how Code 39 is read; the other options are ignored.
+func VerifyLinear(qrImage []byte, expectedData string, format LinearFormat, opts *LinearOptions) error {
+	img, err := png.Decode(bytes.NewReader(qrImage))
+	if err != nil {
+		return fmt.Errorf("failed to decode PNG: %w", err)
+	}
+	if opts == nil {
+		opts = &LinearOptions{}
+	}
+	return verifySymbol(context.Background(), img, expectedData, linearSymbology(format, opts), nil)
+}
+
+// GS1CheckDigit returns the modulo 10 check digit of digits, as used by
+// GTINs, EAN, UPC and other GS1 keys: digits are weighted 3 and 1
+// alternately from the right.
+func GS1CheckDigit(digits string) (byte, error) {
+	if digits == "" {
+		return 0, errors.New("invalid check digit input: no digits")
+	}
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		c := digits[i]
+		if !isDigit(c) {
+			return 0, fmt.Errorf("invalid check digit input %q: must be digits", digits)
+		}
+		weight := 1
+		if (len(digits)-i)%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(c-'0')
+	}
+	return byte('0' + (10-sum%10)%10), nil
+}
+
+// withCheckDigit returns digits of length n-1 with the check digit
+// appended, or checks the last of n digits.
+func withCheckDigit(digits string, n int, format LinearFormat) (string, error) {
+	if len(digits) != n-1 && len(digits) != n {
+		return "", fmt.Errorf("invalid %v data: %d digits, want %d or %d", format, len(digits), n-1, n)
+	}
+	check, err := GS1CheckDigit(digits[:n-1])
+	if err != nil {
+		return "", fmt.Errorf("invalid %v data: must be digits", format)
+	}
+	if len(digits) == n && digits[n-1] != check {
+		return "", fmt.Errorf("invalid %v data: check digit %c, want %c", format, digits[n-1], check)
+	}
+	return digits[:n-1] + string(check), nil
+}
+
+// linearBars validates data and encodes it as bars one module high. It
+// returns data as it will be decoded.
+func linearBars(data string, format LinearFormat, opts *LinearOptions) (string, barcode.Barcode, error) {
+	if data == "" {
+		return "", nil, fmt.Errorf("failed to create %v: data is empty", format)
+	}
+	var bc barcode.Barcode
+	var err error
+	switch format {
+	case Code128, GS1128:
+		for i := 0; i < len(data); i++ {
+			if data[i] > 0x7f {
+				return "", nil, fmt.Errorf("invalid %v data: only ASCII is allowed", format)
+			}
+		}
+		content := data
+		if format == GS1128 {
+			if err := validateGS1Data(data); err != nil {
+				return "", nil, err
+			}
+			content = string(code128.FNC1) + strings.ReplaceAll(data, gs1Separator, string(code128.FNC1))
+		}
+		bc, err = code128.Encode(content)
+	case EAN13, UPCA:
+		n, prefix := 13, ""
+		if format == UPCA {
+			// UPC-A is EAN-13 with a leading zero
+			n, prefix = 12, "0"
+		}
+		if data, err = withCheckDigit(data, n, format); err != nil {
+			return "", nil, err
+		}
+		bc, err = ean.Encode(prefix + data)
+	case Code39:
+		bc, err = code39.Encode(data, opts.CheckDigit, opts.FullASCII)
+	default:
+		return "", nil, fmt.Errorf("failed to create barcode: unknown format %v", format)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create %v: %w", format, err)
+	}
+	return data, bc, nil
+}
+
+// linearSymbology reads 1D barcodes in format. GS1-128 symbols must carry
+// the FNC1 symbology identifier ]C1, which is removed.
+func linearSymbology(format LinearFormat, opts *LinearOptions) symbology {
+	return symbology{
+		name: format.String(),
+		decode: func(img image.Image) (string, error) {
+			var reader gozxing.Reader
+			var hints map[gozxing.DecodeHintType]interface{}
+			switch format {
+			case Code128:
+				reader = oned.NewCode128Reader()
+			case GS1128:
+				reader = oned.NewCode128Reader()
+				hints = map[gozxing.DecodeHintType]interface{}{gozxing.DecodeHintType_ASSUME_GS1: true}
+			case EAN13:
+				reader = oned.NewEAN13Reader()
+			case UPCA:
+				reader = oned.NewUPCAReader()
+			case Code39:
+				reader = oned.NewCode39ReaderWithFlags(opts.CheckDigit, opts.FullASCII)
+			default:
+				return "", fmt.Errorf("unknown format %v", format)
+			}
+
+			result, err := readBarcode(img, reader, hints)
+			if err != nil {
+				return "", fmt.Errorf("failed to decode %v: %w", format, err)
+			}
+			text := result.GetText()
+			if format == GS1128 {
+				var ok bool
+				if text, ok = strings.CutPrefix(text, "]C1"); !ok {
+					return "", errors.New("verification failed: Code 128 is not a GS1-128 symbol")
+				}
+			}
+			return text, nil
+		},
+	}
+}
@@ -0,0 +1,118 @@
+package qrverify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncodeLinear(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format LinearFormat
+		opts   *LinearOptions
+		want   string // Result.Data, empty if equal to data
+	}{
+		{"code 128", "Hello, World! 123", Code128, nil, ""},
+		{"code 128 control", "tab\there", Code128, &LinearOptions{QuietZone: 2, BarHeight: 10}, ""},
+		{"gs1-128", "0109501101530003\x1d10ABC123\x1d17250101", GS1128, &LinearOptions{VerifyOutput: true}, ""},
+		{"ean-13 computed", "400638133393", EAN13, nil, "4006381333931"},
+		{"ean-13 checked", "4006381333931", EAN13, nil, ""},
+		{"upc-a computed", "03600029145", UPCA, nil, "036000291452"},
+		{"code 39", "CODE-39 TEST", Code39, nil, ""},
+		{"code 39 check digit", "CODE39", Code39, &LinearOptions{CheckDigit: true}, ""},
+		{"code 39 full ascii", "lower case!", Code39, &LinearOptions{FullASCII: true}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := EncodeLinear(tt.data, tt.format, tt.opts)
+			if err != nil {
+				t.Fatalf("EncodeLinear failed: %v", err)
+			}
+			want := tt.data
+			if tt.want != "" {
+				want = tt.want
+			}
+			if result.Data != want {
+				t.Errorf("Data = %q, want %q", result.Data, want)
+			}
+			if s := result.Symbol; s == nil || s.Format != tt.format.String() || s.Rows < 10 || s.Columns == 0 {
+				t.Errorf("Symbol = %+v", s)
+			}
+			if err := VerifyLinear(result.Image, want, tt.format, tt.opts); err != nil {
+				t.Errorf("VerifyLinear failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestEncodeLinearInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format LinearFormat
+		opts   *LinearOptions
+	}{
+		{"empty", "", Code128, nil},
+		{"code 128 non-ascii", "Grüße", Code128, nil},
+		{"code 128 too long", strings.Repeat("A", 81), Code128, nil},
+		{"gs1-128 no ai", "ABC", GS1128, nil},
+		{"ean-13 length", "12345", EAN13, nil},
+		{"ean-13 letters", "40063813339A", EAN13, nil},
+		{"ean-13 check digit", "4006381333932", EAN13, nil},
+		{"upc-a check digit", "036000291453", UPCA, nil},
+		{"code 39 lower case", "lower", Code39, nil},
+		{"bar height", "A", Code128, &LinearOptions{BarHeight: -1}},
+		{"format", "A", LinearFormat(99), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := EncodeLinear(tt.data, tt.format, tt.opts); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestVerifyLinearMismatch(t *testing.T) {
+	result, err := EncodeLinear("4006381333931", EAN13, nil)
+	if err != nil {
+		t.Fatalf("EncodeLinear failed: %v", err)
+	}
+	if err := VerifyLinear(result.Image, "4006381333948", EAN13, nil); err == nil {
+		t.Error("expected mismatch error")
+	}
+	if err := VerifyLinear(result.Image, "4006381333931", Code128, nil); err == nil {
+		t.Error("expected error reading EAN-13 as Code 128")
+	}
+
+	plain, err := EncodeLinear("0112345678901231", Code128, nil)
+	if err != nil {
+		t.Fatalf("EncodeLinear failed: %v", err)
+	}
+	if err := VerifyLinear(plain.Image, "0112345678901231", GS1128, nil); err == nil {
+		t.Error("expected error reading plain Code 128 as GS1-128")
+	}
+}
+
+func TestGS1CheckDigit(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   byte
+	}{
+		{"400638133393", '1'},
+		{"03600029145", '2'},
+		{"0950110153000", '3'},
+		{"1234567", '0'},
+	}
+	for _, tt := range tests {
+		if got, err := GS1CheckDigit(tt.digits); err != nil || got != tt.want {
+			t.Errorf("GS1CheckDigit(%q) = %c, %v, want %c", tt.digits, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "12a4"} {
+		if _, err := GS1CheckDigit(bad); err == nil {
+			t.Errorf("GS1CheckDigit(%q) expected error", bad)
+		}
+	}
+}
@@ -36,6 +36,38 @@ type EncodeOptions struct {
 	// Size is the image dimension in pixels.
 	// Zero value uses 256.
 	Size int
+
+	// VerifyOutput additionally decodes the final PNG bytes, not only the
+	// rendered image they were encoded from.
+	// Zero value verifies the rendered image only.
+	VerifyOutput bool
+
+	// Kanji stores double-byte Shift_JIS characters in QR kanji mode, 13
+	// bits each instead of 24 for most kanji in UTF-8, and other
+	// characters as Shift_JIS bytes. Data must be UTF-8 text that
+	// Shift_JIS can represent; verification decodes with the Shift_JIS
+	// character set and compares the UTF-8 text.
+	// Zero value stores data as UTF-8 bytes.
+	Kanji bool
+
+	// Cache, if set, stores verified images keyed by data and options.
+	// A hit skips encoding and image verification; payload checks still
+	// run on the data.
+	// Zero value disables caching.
+	Cache Cache
+
+	// VerifyCached re-verifies images returned by Cache before use.
+	// An image that fails the check is re-encoded and replaced.
+	// Zero value trusts cached images.
+	VerifyCached bool
+}
+
+// recovery returns the recovery level for o, defaulting to Medium.
+func (o *EncodeOptions) recovery() Recovery {
+	if o == nil || o.Recovery == 0 {
+		return Medium
+	}
+	return o.Recovery
 }
 
 // Result contains a verified QR code with metadata.
@@ -44,4 +76,24 @@ type Result struct {
 	Data     string   // Verified input data
 	Recovery Recovery // Final recovery level used
 	Size     int      // Image dimensions in pixels
+	Cached   bool     // Image was served from EncodeOptions.Cache
+
+	// Pipeline reports size statistics for EncodePipeline, nil otherwise.
+	Pipeline *PipelineStats
+
+	// Symbol describes the symbol of non-QR symbologies, nil for QR codes.
+	Symbol *SymbolInfo
+}
+
+// SymbolInfo describes an encoded barcode symbol. Fields that do not
+// apply to a symbology are zero.
+type SymbolInfo struct {
+	Format         string // Symbology, such as "Data Matrix"
+	Rows           int    // Symbol height in modules excluding the quiet zone, or PDF417 rows
+	Columns        int    // Symbol width in modules, excluding the quiet zone
+	DataColumns    int    // PDF417 data columns
+	DataCodewords  int    // Data capacity of the symbol in codewords
+	ErrorCodewords int    // Error correction codewords
+	Layers         int    // Aztec data layers around the bullseye
+	Compact        bool   // Aztec compact rather than full-range symbol
 }
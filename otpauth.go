@@ -0,0 +1,293 @@
+package qrverify
+
+import (
+	"bytes"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// OTPType specifies the one-time password algorithm family.
+type OTPType int
+
+const (
+	TOTP OTPType = iota // Time-based (RFC 6238) (default)
+	HOTP                // Counter-based (RFC 4226)
+)
+
+// String returns the type as written in the otpauth:// URI.
+func (t OTPType) String() string {
+	switch t {
+	case TOTP:
+		return "totp"
+	case HOTP:
+		return "hotp"
+	default:
+		return "OTPType(unknown)"
+	}
+}
+
+// OTPAlgorithm specifies the HMAC hash function.
+type OTPAlgorithm int
+
+const (
+	SHA1   OTPAlgorithm = iota // HMAC-SHA1 (default)
+	SHA256                     // HMAC-SHA256
+	SHA512                     // HMAC-SHA512
+)
+
+// String returns the algorithm as written in the otpauth:// URI.
+func (a OTPAlgorithm) String() string {
+	switch a {
+	case SHA1:
+		return "SHA1"
+	case SHA256:
+		return "SHA256"
+	case SHA512:
+		return "SHA512"
+	default:
+		return "OTPAlgorithm(unknown)"
+	}
+}
+
+// otpBase32 is unpadded base32 as used for otpauth:// secrets.
+var otpBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// ParseOTPSecret decodes a base32 secret as shown to users, ignoring case,
+// spaces and padding.
+func ParseOTPSecret(s string) ([]byte, error) {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	s = strings.TrimRight(s, "=")
+	secret, err := otpBase32.DecodeString(s)
+	if err != nil || len(secret) == 0 {
+		return nil, errors.New("invalid OTP secret: malformed base32")
+	}
+	return secret, nil
+}
+
+// OTPAuth is a two-factor provisioning payload in the otpauth:// URI format.
+// The secret never appears in error messages.
+type OTPAuth struct {
+	Type      OTPType
+	Issuer    string // Provider name, recommended
+	Account   string // Account name, required
+	Secret    []byte // Shared secret, required
+	Algorithm OTPAlgorithm
+	Digits    int    // 6 or 8, zero value uses 6
+	Period    int    // TOTP step in seconds, zero value uses 30
+	Counter   uint64 // HOTP initial counter
+}
+
+// digits returns o.Digits with the default applied.
+func (o *OTPAuth) digits() int {
+	if o.Digits == 0 {
+		return 6
+	}
+	return o.Digits
+}
+
+// period returns o.Period with the default applied.
+func (o *OTPAuth) period() int {
+	if o.Period == 0 {
+		return 30
+	}
+	return o.Period
+}
+
+// Encode returns the otpauth:// URI. Parameters with default values are
+// omitted.
+func (o *OTPAuth) Encode(limit int) (string, error) {
+	if err := o.validate(); err != nil {
+		return "", err
+	}
+
+	label := uriEscape(o.Account)
+	if o.Issuer != "" {
+		label = uriEscape(o.Issuer) + ":" + label
+	}
+
+	var b strings.Builder
+	b.WriteString("otpauth://")
+	b.WriteString(o.Type.String())
+	b.WriteString("/")
+	b.WriteString(label)
+	b.WriteString("?secret=")
+	b.WriteString(otpBase32.EncodeToString(o.Secret))
+	if o.Issuer != "" {
+		b.WriteString("&issuer=")
+		b.WriteString(uriEscape(o.Issuer))
+	}
+	if o.Algorithm != SHA1 {
+		b.WriteString("&algorithm=")
+		b.WriteString(o.Algorithm.String())
+	}
+	if o.digits() != 6 {
+		b.WriteString("&digits=")
+		b.WriteString(strconv.Itoa(o.digits()))
+	}
+	if o.Type == TOTP && o.period() != 30 {
+		b.WriteString("&period=")
+		b.WriteString(strconv.Itoa(o.period()))
+	}
+	if o.Type == HOTP {
+		b.WriteString("&counter=")
+		b.WriteString(strconv.FormatUint(o.Counter, 10))
+	}
+
+	s := b.String()
+	if len(s) > limit {
+		return "", fmt.Errorf("data too large: otpauth URI is %d bytes, exceeds %d byte limit", len(s), limit)
+	}
+	return s, nil
+}
+
+// Check parses decoded as an otpauth:// URI and compares its fields with o.
+// Secret mismatches report lengths only.
+func (o *OTPAuth) Check(decoded string) error {
+	got, err := ParseOTPAuth(decoded)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(o.Secret, got.Secret) {
+		return &FieldError{
+			Field:    "OTPAuth.Secret",
+			Original: fmt.Sprintf("%d byte secret", len(o.Secret)),
+			Decoded:  fmt.Sprintf("%d byte secret", len(got.Secret)),
+		}
+	}
+
+	fields := []struct {
+		name           string
+		original, read string
+	}{
+		{"OTPAuth.Type", o.Type.String(), got.Type.String()},
+		{"OTPAuth.Issuer", o.Issuer, got.Issuer},
+		{"OTPAuth.Account", o.Account, got.Account},
+		{"OTPAuth.Algorithm", o.Algorithm.String(), got.Algorithm.String()},
+		{"OTPAuth.Digits", strconv.Itoa(o.digits()), strconv.Itoa(got.digits())},
+		{"OTPAuth.Period", strconv.Itoa(o.period()), strconv.Itoa(got.period())},
+		{"OTPAuth.Counter", strconv.FormatUint(o.Counter, 10), strconv.FormatUint(got.Counter, 10)},
+	}
+	for _, f := range fields {
+		if err := checkField(f.name, f.original, f.read); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks o can be encoded.
+func (o *OTPAuth) validate() error {
+	if o.Type != TOTP && o.Type != HOTP {
+		return errors.New("invalid otpauth payload: unknown type")
+	}
+	if o.Account == "" {
+		return errors.New("invalid otpauth payload: account is required")
+	}
+	if strings.Contains(o.Account, ":") || strings.Contains(o.Issuer, ":") {
+		return errors.New("invalid otpauth payload: issuer and account must not contain a colon")
+	}
+	if len(o.Secret) == 0 {
+		return errors.New("invalid otpauth payload: secret is required")
+	}
+	if o.Algorithm < SHA1 || o.Algorithm > SHA512 {
+		return errors.New("invalid otpauth payload: unknown algorithm")
+	}
+	if d := o.digits(); d != 6 && d != 8 {
+		return errors.New("invalid otpauth payload: digits must be 6 or 8")
+	}
+	if o.period() < 0 {
+		return errors.New("invalid otpauth payload: period must be positive")
+	}
+	if o.Type == TOTP && o.Counter != 0 {
+		return errors.New("invalid otpauth payload: counter is only valid for HOTP")
+	}
+	if o.Type == HOTP && o.period() != 30 {
+		return errors.New("invalid otpauth payload: period is only valid for TOTP")
+	}
+	return nil
+}
+
+// ParseOTPAuth parses an otpauth:// URI.
+func ParseOTPAuth(s string) (*OTPAuth, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "otpauth" {
+		return nil, errors.New("invalid otpauth payload: not an otpauth URI")
+	}
+
+	o := &OTPAuth{}
+	switch u.Host {
+	case "totp":
+		o.Type = TOTP
+	case "hotp":
+		o.Type = HOTP
+	default:
+		return nil, errors.New("invalid otpauth payload: unknown type")
+	}
+
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		o.Issuer, o.Account = issuer, strings.TrimLeft(account, " ")
+	} else {
+		o.Account = label
+	}
+
+	q, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, errors.New("invalid otpauth payload: malformed query")
+	}
+
+	if o.Secret, err = ParseOTPSecret(q.Get("secret")); err != nil {
+		return nil, errors.New("invalid otpauth payload: malformed secret")
+	}
+	if issuer := q.Get("issuer"); issuer != "" {
+		if o.Issuer != "" && o.Issuer != issuer {
+			return nil, errors.New("invalid otpauth payload: issuer parameter does not match label")
+		}
+		o.Issuer = issuer
+	}
+
+	switch strings.ToUpper(q.Get("algorithm")) {
+	case "", "SHA1":
+		o.Algorithm = SHA1
+	case "SHA256":
+		o.Algorithm = SHA256
+	case "SHA512":
+		o.Algorithm = SHA512
+	default:
+		return nil, errors.New("invalid otpauth payload: unknown algorithm")
+	}
+
+	ints := []struct {
+		key string
+		dst *int
+	}{
+		{"digits", &o.Digits},
+		{"period", &o.Period},
+	}
+	for _, i := range ints {
+		if v := q.Get(i.key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid otpauth payload: malformed %s", i.key)
+			}
+			*i.dst = n
+		}
+	}
+	if v := q.Get("counter"); v != "" {
+		if o.Counter, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return nil, errors.New("invalid otpauth payload: malformed counter")
+		}
+	} else if o.Type == HOTP {
+		return nil, errors.New("invalid otpauth payload: counter is required for HOTP")
+	}
+
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
@@ -0,0 +1,165 @@
+package qrverify
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// otpSecret is the RFC 4226 test secret "12345678901234567890".
+var otpSecret = []byte("12345678901234567890")
+
+func TestOTPAuthEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		otp  OTPAuth
+		want string
+	}{
+		{
+			name: "TOTP defaults",
+			otp:  OTPAuth{Issuer: "Example", Account: "alice@example.com", Secret: otpSecret},
+			want: "otpauth://totp/Example:alice%40example.com?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&issuer=Example",
+		},
+		{
+			name: "spaces percent-encoded",
+			otp:  OTPAuth{Issuer: "Big Corp", Account: "john doe", Secret: otpSecret, Algorithm: SHA256, Digits: 8, Period: 60},
+			want: "otpauth://totp/Big%20Corp:john%20doe?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&issuer=Big%20Corp&algorithm=SHA256&digits=8&period=60",
+		},
+		{
+			name: "HOTP",
+			otp:  OTPAuth{Type: HOTP, Account: "bob", Secret: otpSecret, Counter: 42},
+			want: "otpauth://hotp/bob?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=42",
+		},
+		{
+			name: "HOTP zero counter",
+			otp:  OTPAuth{Type: HOTP, Account: "bob", Secret: otpSecret, Algorithm: SHA512},
+			want: "otpauth://hotp/bob?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&algorithm=SHA512&counter=0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.otp.Encode(MaxBytesMedium)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+			if err := tt.otp.Check(got); err != nil {
+				t.Errorf("Check failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestOTPAuthEncodeInvalid(t *testing.T) {
+	invalid := []OTPAuth{
+		{Secret: otpSecret},
+		{Account: "a"},
+		{Account: "a:b", Secret: otpSecret},
+		{Issuer: "x:y", Account: "a", Secret: otpSecret},
+		{Account: "a", Secret: otpSecret, Digits: 7},
+		{Account: "a", Secret: otpSecret, Algorithm: OTPAlgorithm(9)},
+		{Account: "a", Secret: otpSecret, Counter: 1},
+		{Type: HOTP, Account: "a", Secret: otpSecret, Period: 60},
+		{Type: OTPType(5), Account: "a", Secret: otpSecret},
+	}
+	for _, o := range invalid {
+		if _, err := o.Encode(MaxBytesMedium); err == nil {
+			t.Errorf("Expected error for %+v", o)
+		}
+	}
+
+	o := OTPAuth{Account: "a", Secret: otpSecret}
+	if _, err := o.Encode(10); err == nil || !strings.Contains(err.Error(), "data too large") {
+		t.Errorf("Expected data too large error, got: %v", err)
+	}
+}
+
+func TestParseOTPAuth(t *testing.T) {
+	// Lowercase, padded secret and issuer only in the label
+	o, err := ParseOTPAuth("otpauth://totp/ACME%20Co:jane?secret=gezdgnbvgy3tqojqgezdgnbvgy3tqojq&digits=8")
+	if err != nil {
+		t.Fatalf("ParseOTPAuth failed: %v", err)
+	}
+	if o.Issuer != "ACME Co" || o.Account != "jane" || o.Digits != 8 || !bytes.Equal(o.Secret, otpSecret) {
+		t.Errorf("ParseOTPAuth() = %+v", o)
+	}
+
+	invalid := []string{
+		"",
+		"https://totp/a?secret=GEZDGNBV",
+		"otpauth://motp/a?secret=GEZDGNBV",
+		"otpauth://totp/a",
+		"otpauth://totp/a?secret=1189",
+		"otpauth://totp/A:a?secret=GEZDGNBV&issuer=B",
+		"otpauth://totp/a?secret=GEZDGNBV&algorithm=MD5",
+		"otpauth://totp/a?secret=GEZDGNBV&digits=x",
+		"otpauth://hotp/a?secret=GEZDGNBV",
+		"otpauth://hotp/a?secret=GEZDGNBV&counter=1&period=60",
+	}
+	for _, s := range invalid {
+		if _, err := ParseOTPAuth(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestParseOTPSecret(t *testing.T) {
+	got, err := ParseOTPSecret("gezd gnbv gy3t qojq gezd gnbv gy3t qojq")
+	if err != nil {
+		t.Fatalf("ParseOTPSecret failed: %v", err)
+	}
+	if !bytes.Equal(got, otpSecret) {
+		t.Errorf("ParseOTPSecret() = %q, want %q", got, otpSecret)
+	}
+
+	for _, s := range []string{"", "GEZ1", "!!!!"} {
+		if _, err := ParseOTPSecret(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestOTPAuthCheckHidesSecret(t *testing.T) {
+	o := &OTPAuth{Issuer: "Example", Account: "alice", Secret: otpSecret}
+	other := &OTPAuth{Issuer: "Example", Account: "alice", Secret: []byte("another secret value")}
+	decoded, err := other.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	err = o.Check(decoded)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "OTPAuth.Secret" {
+		t.Fatalf("Expected FieldError for OTPAuth.Secret, got: %v", err)
+	}
+	for _, secret := range []string{"GEZDGNBV", string(otpSecret), "another secret"} {
+		if strings.Contains(err.Error(), secret) || strings.Contains(fieldErr.Detail(), secret) {
+			t.Errorf("Error exposes secret: %s", fieldErr.Detail())
+		}
+	}
+
+	if err := o.Check("otpauth://totp/Example:alice?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&issuer=Example&period=60"); err == nil {
+		t.Error("Expected error for different period")
+	}
+}
+
+func TestEncodePayloadOTPAuth(t *testing.T) {
+	o := &OTPAuth{Issuer: "Example Inc", Account: "alice@example.com", Secret: otpSecret}
+
+	result, err := EncodePayload(o, nil)
+	if err != nil {
+		t.Fatalf("EncodePayload failed: %v", err)
+	}
+	if err := VerifyPayload(result.Image, o); err != nil {
+		t.Errorf("VerifyPayload failed: %v", err)
+	}
+
+	other := &OTPAuth{Issuer: "Example Inc", Account: "alice@example.com", Secret: []byte("different")}
+	if err := VerifyPayload(result.Image, other); err == nil {
+		t.Error("Expected VerifyPayload to fail for different secret")
+	}
+}
@@ -0,0 +1,92 @@
+package qrverify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+)
+
+// Payload is structured QR code content with a text encoding and a parser
+// used to verify the decoded text field by field.
+type Payload interface {
+	// Encode returns the payload text. limit is the byte capacity of the
+	// chosen recovery level; formats with several valid encodings return
+	// the most compact one that fits.
+	Encode(limit int) (string, error)
+
+	// Check parses decoded text and compares it with the payload field by
+	// field. Mismatches are reported as *FieldError.
+	Check(decoded string) error
+}
+
+// RecoveryRequirer is implemented by payloads whose format mandates a
+// specific error correction level.
+type RecoveryRequirer interface {
+	RequiredRecovery() Recovery
+}
+
+// Decorator is implemented by payloads whose format requires an overlay
+// on the rendered symbol, such as the Swiss cross. The decorated image is
+// what gets verified and returned.
+type Decorator interface {
+	// Decorate draws on img. symbol is the area covered by the QR code.
+	Decorate(img *image.Gray, symbol image.Rectangle)
+}
+
+// EncodePayload generates a verified QR code for p. The decoded text must
+// match byte for byte and pass p.Check.
+func EncodePayload(p Payload, opts *EncodeOptions) (*Result, error) {
+	return EncodePayloadContext(context.Background(), p, opts)
+}
+
+// EncodePayloadContext is like EncodePayload but honors cancellation and
+// deadlines of ctx.
+func EncodePayloadContext(ctx context.Context, p Payload, opts *EncodeOptions) (*Result, error) {
+	recovery := opts.recovery()
+	if r, ok := p.(RecoveryRequirer); ok {
+		required := r.RequiredRecovery()
+		if opts != nil && opts.Recovery != 0 && opts.Recovery != required {
+			return nil, fmt.Errorf("payload requires %v recovery, got %v", required, opts.Recovery)
+		}
+		recovery = required
+
+		o := EncodeOptions{Recovery: required}
+		if opts != nil {
+			o = *opts
+			o.Recovery = required
+		}
+		opts = &o
+	}
+
+	text, err := p.Encode(maxBytes(recovery))
+	if err != nil {
+		return nil, err
+	}
+
+	hooks := encodeHooks{check: p.Check}
+	if d, ok := p.(Decorator); ok {
+		hooks.decorate = d.Decorate
+		hooks.variant = fmt.Sprintf("%T", p)
+	}
+
+	return encodeDetailed(ctx, text, opts, hooks)
+}
+
+// VerifyPayload checks that qrImage (PNG bytes) decodes to text that
+// parses to p. Unlike Verify, the comparison is semantic, so any valid
+// encoding of the same fields is accepted.
+func VerifyPayload(qrImage []byte, p Payload) error {
+	img, err := png.Decode(bytes.NewReader(qrImage))
+	if err != nil {
+		return fmt.Errorf("failed to decode PNG: %w", err)
+	}
+
+	decoded, err := decode(img)
+	if err != nil {
+		return fmt.Errorf("failed to read QR code: %w", err)
+	}
+
+	return p.Check(decoded)
+}
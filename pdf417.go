@@ -0,0 +1,592 @@
+package qrverify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"math"
+	"sort"
+)
+
+// PDF417 symbol limits (ISO/IEC 15438).
+const (
+	pdfMaxColumns   = 30
+	pdfMinRows      = 3
+	pdfMaxRows      = 90
+	pdfMaxCodewords = 928
+	pdfMaxSegments  = 99999
+)
+
+// pdfQuietZone is the quiet zone in modules around a PDF417 symbol.
+const pdfQuietZone = 2
+
+// Start and stop patterns, most significant bit first, with set bits for
+// bars. The stop pattern is one module wider than a codeword.
+const (
+	pdfStartPattern = 0x1fea8
+	pdfStopPattern  = 0x3fa29
+)
+
+// PDF417Options configures PDF417 generation.
+// Zero values provide sensible defaults.
+type PDF417Options struct {
+	// SecurityLevel sets 2^(level+1) error correction codewords, from 1
+	// to 8. Level 0 only detects errors and is not offered.
+	// Zero value uses the minimum ISO/IEC 15438 recommends for the data
+	// length, 2 to 5.
+	SecurityLevel int
+
+	// Columns is the number of data columns, 1 to 30.
+	// Zero value picks the count whose symbol comes closest to
+	// AspectRatio.
+	Columns int
+
+	// AspectRatio is the preferred width to height ratio of the symbol,
+	// used when Columns is zero.
+	// Zero value uses 3.
+	AspectRatio float64
+
+	// RowHeight is the height of each row in modules, at least 2.
+	// Zero value uses 3, the minimum ISO/IEC 15438 recommends.
+	RowHeight int
+
+	// Macro marks the symbol as one segment of a Macro PDF417 file.
+	// EncodePDF417Macro sets it for each segment.
+	Macro *PDF417Macro
+
+	// ThermalWidth, if set, is the width in printer dots the image is
+	// printed at, such as 384 for a 48 mm label at 203 dpi. Encoding then
+	// additionally downscales the image to that width like a printer
+	// driver and verifies the result, so rows and modules too small to
+	// survive printing fail.
+	ThermalWidth int
+
+	// Size is the image width in pixels. The height follows the symbol's
+	// aspect ratio.
+	// Zero value uses two pixels per module.
+	Size int
+
+	// VerifyOutput additionally decodes the final PNG bytes.
+	VerifyOutput bool
+}
+
+// PDF417Macro identifies one symbol of a Macro PDF417 file, whose data is
+// split across several symbols.
+type PDF417Macro struct {
+	// FileID identifies the file; all its segments share it. It is
+	// decimal digits in groups of three, each group below 900, such as
+	// "017342".
+	FileID string
+
+	// SegmentIndex is the zero-based position of this symbol's data in
+	// the file, up to 99998.
+	SegmentIndex int
+
+	// SegmentCount is the number of symbols in the file, or zero if not
+	// recorded.
+	SegmentCount int
+}
+
+// EncodePDF417 generates a verified PDF417 PNG image. Digit runs use
+// numeric compaction, printable ASCII text compaction and other bytes,
+// such as UTF-8 sequences, byte compaction. Verification decodes the
+// symbol with the package's built-in PDF417 reader, including Reed-Solomon
+// error correction, and compares the text byte for byte, and for macro
+// symbols the macro control block. The reader only handles upright
+// symbols as rendered here, not rotated or skewed photographs.
+// Result.Symbol reports the symbol size and codewords; Result.Recovery
+// does not apply, see PDF417Options.SecurityLevel.
+func EncodePDF417(data string, opts *PDF417Options) (*Result, error) {
+	return EncodePDF417Context(context.Background(), data, opts)
+}
+
+// EncodePDF417Context is like EncodePDF417 but honors cancellation and
+// deadlines of ctx.
+func EncodePDF417Context(ctx context.Context, data string, opts *PDF417Options) (*Result, error) {
+	if opts == nil {
+		opts = &PDF417Options{}
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := canceled(ctx, "render"); err != nil {
+		return nil, err
+	}
+	plan, err := planPDF417(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	modules := plan.modules(opts.rowHeight())
+
+	b := modules.Bounds()
+	size := 2 * b.Dx()
+	if opts.Size > 0 {
+		size = opts.Size
+	}
+	height := size * b.Dy() / b.Dx()
+	img, err := encodeSymbol(ctx, data, modules, pdf417Symbology(opts.Macro), size, height, opts.VerifyOutput, encodeHooks{})
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ThermalWidth > 0 {
+		if err := verifyThermal(ctx, img, data, b.Dx(), opts); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Result{
+		Image:  img,
+		Data:   data,
+		Size:   size,
+		Symbol: plan.info(),
+	}, nil
+}
+
+// EncodePDF417Macro splits data into segments symbols of a Macro PDF417
+// file and encodes each like EncodePDF417. data is split at character
+// boundaries into parts of roughly equal length. If segments is zero, it
+// uses the fewest symbols that hold data. The file ID is taken from
+// opts.Macro if set, and derived from data otherwise.
+func EncodePDF417Macro(data string, segments int, opts *PDF417Options) ([]*Result, error) {
+	return EncodePDF417MacroContext(context.Background(), data, segments, opts)
+}
+
+// EncodePDF417MacroContext is like EncodePDF417Macro but honors
+// cancellation and deadlines of ctx.
+func EncodePDF417MacroContext(ctx context.Context, data string, segments int, opts *PDF417Options) ([]*Result, error) {
+	var o PDF417Options
+	if opts != nil {
+		o = *opts
+	}
+	sum := crc32.ChecksumIEEE([]byte(data))
+	fileID := fmt.Sprintf("%03d%03d", sum%900, sum/900%900)
+	if o.Macro != nil && o.Macro.FileID != "" {
+		fileID = o.Macro.FileID
+	}
+	o.Macro = &PDF417Macro{FileID: fileID}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	if data == "" {
+		return nil, errors.New("failed to create PDF417: data is empty")
+	}
+	if segments < 0 || segments > pdfMaxSegments {
+		return nil, fmt.Errorf("failed to create PDF417: %d segments out of range 1-%d", segments, pdfMaxSegments)
+	}
+
+	split := func(n int) ([]string, error) {
+		parts := splitLayers(data, n)
+		if len(parts) != n {
+			return nil, fmt.Errorf("failed to create PDF417: %d bytes can not be split into %d segments", len(data), n)
+		}
+		for i, part := range parts {
+			o.Macro = &PDF417Macro{FileID: fileID, SegmentIndex: i, SegmentCount: n}
+			if _, err := planPDF417(part, &o); err != nil {
+				return nil, fmt.Errorf("segment %d: %w", i, err)
+			}
+		}
+		return parts, nil
+	}
+
+	var parts []string
+	var err error
+	if segments > 0 {
+		parts, err = split(segments)
+	} else {
+		// Each symbol holds at most pdfMaxCodewords codewords
+		first := max(1, len(pdf417Compact(data))/pdfMaxCodewords)
+		for n := first; n <= min(len(data), pdfMaxSegments); n++ {
+			if parts, err = split(n); err == nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*Result, len(parts))
+	for i, part := range parts {
+		o.Macro = &PDF417Macro{FileID: fileID, SegmentIndex: i, SegmentCount: len(parts)}
+		results[i], err = EncodePDF417Context(ctx, part, &o)
+		if err != nil {
+			return nil, fmt.Errorf("segment %d: %w", i, err)
+		}
+	}
+	return results, nil
+}
+
+// DecodePDF417 reads a PDF417 symbol (PNG bytes) and returns its text and
+// its macro control block, or nil if the symbol is not part of a macro
+// file. Like verification, it reads upright symbols only.
+func DecodePDF417(qrImage []byte) (string, *PDF417Macro, error) {
+	img, err := png.Decode(bytes.NewReader(qrImage))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode PNG: %w", err)
+	}
+	text, macro, err := readPDF417(img)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode PDF417: %w", err)
+	}
+	return text, macro, nil
+}
+
+// DecodePDF417Macro reads the symbols of a Macro PDF417 file (PNG bytes)
+// in any order and returns the joined data. All segments must be present
+// once and share a file ID; repeated symbols are ignored.
+func DecodePDF417Macro(images [][]byte) (string, error) {
+	segments := make(map[int]string)
+	var first *PDF417Macro
+	for i, img := range images {
+		text, macro, err := DecodePDF417(img)
+		if err != nil {
+			return "", fmt.Errorf("symbol %d: %w", i, err)
+		}
+		switch {
+		case macro == nil:
+			return "", fmt.Errorf("symbol %d: not a macro PDF417 symbol", i)
+		case first == nil:
+			first = macro
+		case macro.FileID != first.FileID:
+			return "", fmt.Errorf("symbol %d: file ID %s differs from %s", i, macro.FileID, first.FileID)
+		}
+		if prev, ok := segments[macro.SegmentIndex]; ok && prev != text {
+			return "", fmt.Errorf("symbol %d: segment %d read twice with different data", i, macro.SegmentIndex)
+		}
+		segments[macro.SegmentIndex] = text
+		if macro.SegmentCount > first.SegmentCount {
+			first.SegmentCount = macro.SegmentCount
+		}
+	}
+	if first == nil {
+		return "", errors.New("failed to decode macro PDF417: no symbols")
+	}
+
+	indexes := make([]int, 0, len(segments))
+	for i := range segments {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	count := first.SegmentCount
+	if count == 0 || len(indexes) != count || indexes[count-1] != count-1 {
+		return "", fmt.Errorf("failed to decode macro PDF417: have %d of %d segments", len(indexes), count)
+	}
+	var b bytes.Buffer
+	for _, i := range indexes {
+		b.WriteString(segments[i])
+	}
+	return b.String(), nil
+}
+
+// VerifyPDF417 checks that qrImage (PNG bytes) holds a PDF417 symbol that
+// decodes to expectedData. For macro symbols only the segment's data is
+// compared.
+func VerifyPDF417(qrImage []byte, expectedData string) error {
+	img, err := png.Decode(bytes.NewReader(qrImage))
+	if err != nil {
+		return fmt.Errorf("failed to decode PNG: %w", err)
+	}
+	return verifySymbol(context.Background(), img, expectedData, pdf417Symbology(nil), nil)
+}
+
+// pdf417Symbology reads PDF417 symbols. If macro is set, the symbol must
+// carry an equal macro control block.
+func pdf417Symbology(macro *PDF417Macro) symbology {
+	return symbology{
+		name: "PDF417",
+		decode: func(img image.Image) (string, error) {
+			text, got, err := readPDF417(img)
+			if err != nil {
+				return "", fmt.Errorf("failed to decode PDF417: %w", err)
+			}
+			if macro != nil && (got == nil || *got != *macro) {
+				return "", fmt.Errorf("verification failed: macro control block %+v, want %+v", got, *macro)
+			}
+			return text, nil
+		},
+	}
+}
+
+// validate checks opts for values out of range.
+func (opts *PDF417Options) validate() error {
+	switch {
+	case opts.SecurityLevel < 0 || opts.SecurityLevel > 8:
+		return fmt.Errorf("failed to create PDF417: security level %d out of range 1-8", opts.SecurityLevel)
+	case opts.Columns < 0 || opts.Columns > pdfMaxColumns:
+		return fmt.Errorf("failed to create PDF417: %d columns out of range 1-%d", opts.Columns, pdfMaxColumns)
+	case opts.AspectRatio < 0 || math.IsNaN(opts.AspectRatio) || math.IsInf(opts.AspectRatio, 0):
+		return fmt.Errorf("failed to create PDF417: invalid aspect ratio %v", opts.AspectRatio)
+	case opts.RowHeight < 0 || opts.RowHeight == 1:
+		return fmt.Errorf("failed to create PDF417: row height %d below 2 modules", opts.RowHeight)
+	case opts.ThermalWidth < 0:
+		return fmt.Errorf("failed to create PDF417: invalid thermal width %d", opts.ThermalWidth)
+	}
+	if m := opts.Macro; m != nil {
+		if m.SegmentIndex < 0 || m.SegmentIndex >= pdfMaxSegments || m.SegmentCount < 0 || m.SegmentCount > pdfMaxSegments ||
+			(m.SegmentCount > 0 && m.SegmentIndex >= m.SegmentCount) {
+			return fmt.Errorf("failed to create PDF417: invalid macro segment %d of %d", m.SegmentIndex, m.SegmentCount)
+		}
+		if len(m.FileID) == 0 || len(m.FileID)%3 != 0 || pdfDigitRun(m.FileID) != len(m.FileID) {
+			return fmt.Errorf("failed to create PDF417: macro file ID %q is not groups of three digits", m.FileID)
+		}
+		for i := 0; i < len(m.FileID); i += 3 {
+			if m.FileID[i] == '9' {
+				return fmt.Errorf("failed to create PDF417: macro file ID group %s exceeds 899", m.FileID[i:i+3])
+			}
+		}
+	}
+	return nil
+}
+
+// rowHeight returns the row height in modules.
+func (opts *PDF417Options) rowHeight() int {
+	if opts.RowHeight > 0 {
+		return opts.RowHeight
+	}
+	return 3
+}
+
+// pdf417Plan is the codeword layout of a symbol.
+type pdf417Plan struct {
+	codewords  []int // Length descriptor, data, padding and error correction
+	level      int
+	rows, cols int
+	eccCount   int
+	dataCount  int
+}
+
+// planPDF417 encodes data into codewords and picks the symbol dimensions.
+func planPDF417(data string, opts *PDF417Options) (*pdf417Plan, error) {
+	if data == "" {
+		return nil, errors.New("failed to create PDF417: data is empty")
+	}
+	words := pdf417Compact(data)
+	if opts.Macro != nil {
+		words = append(words, pdf417MacroWords(opts.Macro)...)
+	}
+	n := 1 + len(words) // Symbol length descriptor
+
+	level := opts.SecurityLevel
+	if level == 0 {
+		switch {
+		case n <= 40:
+			level = 2
+		case n <= 160:
+			level = 3
+		case n <= 320:
+			level = 4
+		default:
+			level = 5
+		}
+	}
+	k := 2 << level
+
+	aspect := 3.0
+	if opts.AspectRatio > 0 {
+		aspect = opts.AspectRatio
+	}
+	rows, cols := 0, 0
+	best := math.Inf(1)
+	for c := 1; c <= pdfMaxColumns; c++ {
+		if opts.Columns != 0 && c != opts.Columns {
+			continue
+		}
+		r := max((n+k+c-1)/c, pdfMinRows)
+		if r > pdfMaxRows || r*c > pdfMaxCodewords {
+			continue
+		}
+		ratio := float64(17*c+69) / float64(r*opts.rowHeight())
+		if d := math.Abs(math.Log(ratio / aspect)); d < best {
+			best, rows, cols = d, r, c
+		}
+	}
+	if rows == 0 {
+		target := "largest"
+		if opts.Columns != 0 {
+			target = fmt.Sprintf("%d column", opts.Columns)
+		}
+		return nil, fmt.Errorf("data too large: %d codewords with %d error correction codewords exceed the %s PDF417",
+			n, k, target)
+	}
+
+	dataCount := rows*cols - k
+	codewords := make([]int, 0, rows*cols)
+	codewords = append(codewords, dataCount)
+	codewords = append(codewords, words...)
+	for len(codewords) < dataCount {
+		codewords = append(codewords, pdfTextLatch)
+	}
+	codewords = append(codewords, pdf417ECC(codewords, k)...)
+
+	return &pdf417Plan{
+		codewords: codewords,
+		level:     level,
+		rows:      rows,
+		cols:      cols,
+		eccCount:  k,
+		dataCount: dataCount,
+	}, nil
+}
+
+// info describes the planned symbol.
+func (p *pdf417Plan) info() *SymbolInfo {
+	return &SymbolInfo{
+		Format:         "PDF417",
+		Rows:           p.rows,
+		Columns:        17*p.cols + 69,
+		DataColumns:    p.cols,
+		DataCodewords:  p.dataCount,
+		ErrorCodewords: p.eccCount,
+	}
+}
+
+// modules draws the symbol at one pixel per module width and rowHeight
+// pixels per row, surrounded by the quiet zone.
+func (p *pdf417Plan) modules(rowHeight int) *image.Gray {
+	width := 17*p.cols + 69
+	img := image.NewGray(image.Rect(0, 0, width+2*pdfQuietZone, p.rows*rowHeight+2*pdfQuietZone))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	for r := 0; r < p.rows; r++ {
+		cluster := r % 3
+		left, right := pdf417Indicators(r, p.rows, p.cols, p.level)
+		patterns := make([]uint32, 0, p.cols+3)
+		patterns = append(patterns, pdfStartPattern, pdf417Patterns[cluster][left])
+		for _, w := range p.codewords[r*p.cols : (r+1)*p.cols] {
+			patterns = append(patterns, pdf417Patterns[cluster][w])
+		}
+		patterns = append(patterns, pdf417Patterns[cluster][right])
+
+		y0 := pdfQuietZone + r*rowHeight
+		row := img.Pix[y0*img.Stride : (y0+1)*img.Stride]
+		x := pdfQuietZone
+		for _, bits := range patterns {
+			x = pdfDraw(row, x, bits, 17)
+		}
+		pdfDraw(row, x, pdfStopPattern, 18)
+		for y := y0 + 1; y < y0+rowHeight; y++ {
+			copy(img.Pix[y*img.Stride:(y+1)*img.Stride], row)
+		}
+	}
+	return img
+}
+
+// pdfDraw darkens the set bits of an n module pattern in row from x and
+// returns the position after it.
+func pdfDraw(row []byte, x int, bits uint32, n int) int {
+	for i := n - 1; i >= 0; i-- {
+		if bits>>i&1 == 1 {
+			row[x] = 0
+		}
+		x++
+	}
+	return x
+}
+
+// pdf417Indicators returns the left and right row indicator codewords of
+// row r, which together encode the row count, column count and security
+// level.
+func pdf417Indicators(r, rows, cols, level int) (left, right int) {
+	base := 30 * (r / 3)
+	rowsHigh := (rows - 1) / 3
+	levelRows := 3*level + (rows-1)%3
+	switch r % 3 {
+	case 0:
+		return base + rowsHigh, base + cols - 1
+	case 1:
+		return base + levelRows, base + rowsHigh
+	default:
+		return base + cols - 1, base + levelRows
+	}
+}
+
+// verifyThermal downscales the PNG image like a printer driver printing
+// it ThermalWidth dots wide, and verifies the result still decodes to
+// data. modulesWide is the image width in modules.
+func verifyThermal(ctx context.Context, pngImage []byte, data string, modulesWide int, opts *PDF417Options) error {
+	img, err := png.Decode(bytes.NewReader(pngImage))
+	if err != nil {
+		return fmt.Errorf("failed to decode PNG: %w", err)
+	}
+	gray, ok := img.(*image.Gray)
+	if !ok {
+		return errors.New("thermal check failed: image is not greyscale")
+	}
+	b := gray.Bounds()
+	_, factor, err := symbolRect(modulesWide, 1, b.Dx(), b.Dy())
+	if err != nil {
+		return err
+	}
+
+	dots := float64(opts.ThermalWidth) * float64(factor) / float64(b.Dx())
+	if dots < 1 {
+		return fmt.Errorf("thermal check failed: module width %.2f dots at %d dots is below 1 dot", dots, opts.ThermalWidth)
+	}
+
+	printed := downscale(gray, opts.ThermalWidth, max(1, b.Dy()*opts.ThermalWidth/b.Dx()))
+	if err := verifySymbol(ctx, printed, data, pdf417Symbology(opts.Macro), nil); err != nil {
+		return fmt.Errorf("thermal check failed at %d dots: %w", opts.ThermalWidth, err)
+	}
+	return nil
+}
+
+// downscale resamples src to w x h pixels by area averaging and thresholds
+// the result at half intensity, as a printer without greyscale does.
+func downscale(src *image.Gray, w, h int) *image.Gray {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+
+	type cell struct {
+		first   int       // First source pixel covered
+		weights []float64 // Share of each covered source pixel
+	}
+	// box maps n destination pixels onto m source pixels.
+	box := func(m, n int) []cell {
+		scale := float64(m) / float64(n)
+		cells := make([]cell, n)
+		for i := range cells {
+			lo, hi := float64(i)*scale, float64(i+1)*scale
+			cells[i].first = int(lo)
+			for s := int(lo); s < m && float64(s) < hi; s++ {
+				cover := math.Min(hi, float64(s+1)) - math.Max(lo, float64(s))
+				cells[i].weights = append(cells[i].weights, cover/scale)
+			}
+		}
+		return cells
+	}
+
+	cols, rows := box(sw, w), box(sh, h)
+	tmp := make([]float64, w*sh)
+	for y := 0; y < sh; y++ {
+		line := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+		for x, c := range cols {
+			var v float64
+			for i, weight := range c.weights {
+				v += float64(line[c.first+i]) * weight
+			}
+			tmp[y*w+x] = v
+		}
+	}
+
+	dst := image.NewGray(image.Rect(0, 0, w, h))
+	for y, c := range rows {
+		for x := 0; x < w; x++ {
+			var v float64
+			for i, weight := range c.weights {
+				v += tmp[(c.first+i)*w+x] * weight
+			}
+			if v >= 128 {
+				dst.Pix[y*dst.Stride+x] = 0xff
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,602 @@
+package qrverify
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// PDF417 mode and control codewords (ISO/IEC 15438).
+const (
+	pdfTextLatch   = 900 // Latch to text compaction, also the pad codeword
+	pdfByteLatch   = 901 // Latch to byte compaction
+	pdfNumLatch    = 902 // Latch to numeric compaction
+	pdfByteShift   = 913 // Shift one byte within text compaction
+	pdfMacroLast   = 922 // Terminates the last segment of a macro file
+	pdfMacroField  = 923 // Introduces an optional macro field
+	pdfByteLatch6  = 924 // Latch to byte compaction of whole 6-byte groups
+	pdfMacroHeader = 928 // Starts a macro control block
+)
+
+// pdfModulus is the prime of the codeword field; codewords are 0-928.
+const pdfModulus = 929
+
+// Compaction thresholds: shorter runs are cheaper in the current mode.
+const (
+	pdfMinNumeric = 13
+	pdfMinText    = 5
+)
+
+// pdfMacroSegmentCount is the optional macro field holding the number of
+// segments.
+const pdfMacroSegmentCount = 1
+
+// Text compaction submodes.
+const (
+	pdfAlpha = iota
+	pdfLower
+	pdfMixed
+	pdfPunct
+)
+
+// Text compaction values 27-29 switch submodes; their meaning depends on
+// the current submode. Mixed and punctuation characters are listed by
+// value.
+const (
+	pdfMixedChars = "0123456789&\r\t,:#-.$/+%*=^"
+	pdfPunctChars = ";<>@[\\]_`~!\r\t,:\n-.$/\"|*()?{}'"
+)
+
+// pdf417Compact returns the data codewords of data, choosing numeric
+// compaction for long digit runs, text compaction for printable ASCII and
+// byte compaction for the rest.
+func pdf417Compact(data string) []int {
+	var out []int
+	mode := pdfTextLatch // Symbols start in text compaction
+	for i := 0; i < len(data); {
+		if n := pdfDigitRun(data[i:]); n >= pdfMinNumeric {
+			out = append(out, pdfNumLatch)
+			out = pdfNumericWords(out, data[i:i+n])
+			mode = pdfNumLatch
+			i += n
+			continue
+		}
+		if n := pdfTextRun(data[i:]); n >= pdfMinText || (n > 0 && i+n == len(data)) {
+			if mode != pdfTextLatch {
+				out = append(out, pdfTextLatch)
+			}
+			out = pdfTextWords(out, data[i:i+n])
+			mode = pdfTextLatch
+			i += n
+			continue
+		}
+
+		j := i + 1
+		for j < len(data) && pdfDigitRun(data[j:]) < pdfMinNumeric && pdfTextRun(data[j:]) < pdfMinText {
+			j++
+		}
+		if j-i == 1 && mode == pdfTextLatch {
+			// Text compaction resumes after a latch, which resets the
+			// submode and any pending shift.
+			out = append(out, pdfByteShift, int(data[i]))
+			mode = pdfByteShift
+		} else {
+			out = pdfByteWords(out, data[i:j])
+			mode = pdfByteLatch
+		}
+		i = j
+	}
+	return out
+}
+
+// pdfDigitRun returns the number of leading ASCII digits of s.
+func pdfDigitRun(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return n
+}
+
+// pdfTextRun returns the number of leading characters of s that text
+// compaction can encode, ending before a digit run numeric compaction
+// stores more compactly.
+func pdfTextRun(s string) int {
+	n := 0
+	for n < len(s) && pdfIsText(s[n]) {
+		if isDigit(s[n]) && pdfDigitRun(s[n:]) >= pdfMinNumeric {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// pdfIsText reports whether text compaction can encode c.
+func pdfIsText(c byte) bool {
+	return c >= ' ' && c <= '~' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// pdfTextValue returns the value of c in the text compaction submode sub.
+func pdfTextValue(sub int, c byte) (int, bool) {
+	switch {
+	case sub == pdfAlpha && c >= 'A' && c <= 'Z':
+		return int(c - 'A'), true
+	case sub == pdfLower && c >= 'a' && c <= 'z':
+		return int(c - 'a'), true
+	case sub != pdfPunct && c == ' ':
+		return 26, true
+	case sub == pdfMixed:
+		i := strings.IndexByte(pdfMixedChars, c)
+		return i, i >= 0
+	case sub == pdfPunct:
+		i := strings.IndexByte(pdfPunctChars, c)
+		return i, i >= 0
+	}
+	return 0, false
+}
+
+// pdfPunctOnly reports whether c is only in the punctuation submode.
+func pdfPunctOnly(c byte) bool {
+	return strings.IndexByte(pdfPunctChars, c) >= 0 && strings.IndexByte(pdfMixedChars, c) < 0
+}
+
+// pdfTextWords appends the text compaction codewords of s, which must
+// hold only characters accepted by pdfIsText. Each codeword packs two
+// values; an odd count is padded with a punctuation shift.
+func pdfTextWords(out []int, s string) []int {
+	var v []int
+	sub := pdfAlpha
+	for i := 0; i < len(s); {
+		c := s[i]
+		next := byte(0)
+		if i+1 < len(s) {
+			next = s[i+1]
+		}
+		if val, ok := pdfTextValue(sub, c); ok {
+			v = append(v, val)
+			i++
+			continue
+		}
+		switch {
+		case sub == pdfPunct:
+			v = append(v, 29) // Latch to alpha
+			sub = pdfAlpha
+		case sub == pdfLower && c >= 'A' && c <= 'Z':
+			if next >= 'A' && next <= 'Z' {
+				v = append(v, 28, 28) // Latch to alpha via mixed
+				sub = pdfAlpha
+			} else {
+				v = append(v, 27, int(c-'A')) // Shift to alpha
+				i++
+			}
+		case pdfPunctOnly(c):
+			if pdfPunctOnly(next) {
+				if sub != pdfMixed {
+					v = append(v, 28) // Latch to mixed
+				}
+				v = append(v, 25) // Latch to punctuation
+				sub = pdfPunct
+			} else {
+				v = append(v, 29, strings.IndexByte(pdfPunctChars, c)) // Shift to punctuation
+				i++
+			}
+		case c >= 'a' && c <= 'z':
+			v = append(v, 27) // Latch to lower from alpha or mixed
+			sub = pdfLower
+		case c >= 'A' && c <= 'Z':
+			v = append(v, 28) // Latch to alpha from mixed
+			sub = pdfAlpha
+		default:
+			v = append(v, 28) // Latch to mixed from alpha or lower
+			sub = pdfMixed
+		}
+	}
+	if len(v)%2 == 1 {
+		v = append(v, 29)
+	}
+	for i := 0; i < len(v); i += 2 {
+		out = append(out, 30*v[i]+v[i+1])
+	}
+	return out
+}
+
+// pdfByteWords appends a byte compaction latch and the codewords of s:
+// five codewords per six bytes, then one per remaining byte.
+func pdfByteWords(out []int, s string) []int {
+	if len(s)%6 == 0 {
+		out = append(out, pdfByteLatch6)
+	} else {
+		out = append(out, pdfByteLatch)
+	}
+	i := 0
+	for ; i+6 <= len(s); i += 6 {
+		var v uint64
+		for j := 0; j < 6; j++ {
+			v = v<<8 | uint64(s[i+j])
+		}
+		var group [5]int
+		for j := 4; j >= 0; j-- {
+			group[j] = int(v % 900)
+			v /= 900
+		}
+		out = append(out, group[:]...)
+	}
+	for ; i < len(s); i++ {
+		out = append(out, int(s[i]))
+	}
+	return out
+}
+
+// pdfNumericWords appends the numeric compaction codewords of digits, in
+// groups of up to 44 digits read as a base 900 number with a leading 1.
+func pdfNumericWords(out []int, digits string) []int {
+	for len(digits) > 0 {
+		n := min(len(digits), 44)
+		v, _ := new(big.Int).SetString("1"+digits[:n], 10)
+		var group []int
+		base, m := big.NewInt(900), new(big.Int)
+		for v.Sign() > 0 {
+			v.DivMod(v, base, m)
+			group = append(group, int(m.Int64()))
+		}
+		for i := len(group) - 1; i >= 0; i-- {
+			out = append(out, group[i])
+		}
+		digits = digits[n:]
+	}
+	return out
+}
+
+// pdfNumericValue decodes one numeric compaction group.
+func pdfNumericValue(words []int) (string, error) {
+	v, base := new(big.Int), big.NewInt(900)
+	for _, w := range words {
+		v.Mul(v, base).Add(v, big.NewInt(int64(w)))
+	}
+	s := v.String()
+	if s[0] != '1' {
+		return "", errors.New("invalid numeric compaction group")
+	}
+	return s[1:], nil
+}
+
+// pdf417MacroWords returns the macro control block of m.
+func pdf417MacroWords(m *PDF417Macro) []int {
+	out := []int{pdfMacroHeader}
+	out = pdfNumericWords(out, fmt.Sprintf("%05d", m.SegmentIndex))
+	for i := 0; i+3 <= len(m.FileID); i += 3 {
+		v, _ := strconv.Atoi(m.FileID[i : i+3])
+		out = append(out, v)
+	}
+	if m.SegmentCount > 0 {
+		out = append(out, pdfMacroField, pdfMacroSegmentCount)
+		out = pdfNumericWords(out, strconv.Itoa(m.SegmentCount))
+		if m.SegmentIndex == m.SegmentCount-1 {
+			out = append(out, pdfMacroLast)
+		}
+	}
+	return out
+}
+
+// pdf417Decompact decodes data codewords, without the length descriptor,
+// into the encoded bytes and the macro control block, if any.
+func pdf417Decompact(words []int) (string, *PDF417Macro, error) {
+	var b strings.Builder
+	sub, shift := pdfAlpha, -1
+	text := func(v int) {
+		cur, shifted := sub, shift >= 0
+		if shifted {
+			cur, shift = shift, -1
+		}
+		switch {
+		case cur == pdfAlpha && v < 26:
+			b.WriteByte('A' + byte(v))
+		case cur == pdfLower && v < 26:
+			b.WriteByte('a' + byte(v))
+		case cur == pdfMixed && v < 25:
+			b.WriteByte(pdfMixedChars[v])
+		case cur == pdfPunct && v < 29:
+			b.WriteByte(pdfPunctChars[v])
+		case cur != pdfPunct && v == 26:
+			b.WriteByte(' ')
+		case shifted:
+			// Mode switches are not valid after a shift
+		case v == 29 && cur == pdfPunct:
+			sub = pdfAlpha
+		case v == 29:
+			shift = pdfPunct
+		case v == 25:
+			sub = pdfPunct
+		case v == 27 && cur == pdfLower:
+			shift = pdfAlpha
+		case v == 27:
+			sub = pdfLower
+		case v == 28 && cur == pdfMixed:
+			sub = pdfAlpha
+		case v == 28:
+			sub = pdfMixed
+		}
+	}
+
+	// run returns the codewords from i up to the next mode codeword.
+	run := func(i int) []int {
+		j := i
+		for j < len(words) && words[j] < pdfTextLatch {
+			j++
+		}
+		return words[i:j]
+	}
+
+	for i := 0; i < len(words); {
+		w := words[i]
+		i++
+		switch w {
+		case pdfTextLatch:
+			sub, shift = pdfAlpha, -1
+		case pdfByteShift:
+			if i == len(words) || words[i] > 0xff {
+				return "", nil, errors.New("invalid byte shift")
+			}
+			b.WriteByte(byte(words[i]))
+			i++
+		case pdfByteLatch, pdfByteLatch6:
+			r := run(i)
+			i += len(r)
+			j := 0
+			for ; j+5 < len(r) || (w == pdfByteLatch6 && j+5 == len(r)); j += 5 {
+				var v uint64
+				for _, x := range r[j : j+5] {
+					v = v*900 + uint64(x)
+				}
+				for k := 5; k >= 0; k-- {
+					b.WriteByte(byte(v >> (8 * k)))
+				}
+			}
+			for ; j < len(r); j++ {
+				if r[j] > 0xff {
+					return "", nil, fmt.Errorf("invalid byte codeword %d", r[j])
+				}
+				b.WriteByte(byte(r[j]))
+			}
+			sub, shift = pdfAlpha, -1
+		case pdfNumLatch:
+			r := run(i)
+			i += len(r)
+			for len(r) > 0 {
+				n := min(len(r), 15)
+				digits, err := pdfNumericValue(r[:n])
+				if err != nil {
+					return "", nil, err
+				}
+				b.WriteString(digits)
+				r = r[n:]
+			}
+			sub, shift = pdfAlpha, -1
+		case pdfMacroHeader:
+			m, err := pdf417DecodeMacro(words[i:])
+			if err != nil {
+				return "", nil, err
+			}
+			return b.String(), m, nil
+		default:
+			if w >= pdfTextLatch {
+				return "", nil, fmt.Errorf("unsupported codeword %d", w)
+			}
+			text(w / 30)
+			text(w % 30)
+		}
+	}
+	return b.String(), nil, nil
+}
+
+// pdf417DecodeMacro decodes a macro control block after its header
+// codeword. Pad codewords may follow the block.
+func pdf417DecodeMacro(words []int) (*PDF417Macro, error) {
+	if len(words) < 2 {
+		return nil, errors.New("truncated macro control block")
+	}
+	index, err := pdfNumericValue(words[:2])
+	if err != nil {
+		return nil, err
+	}
+	m := &PDF417Macro{}
+	m.SegmentIndex, _ = strconv.Atoi(index)
+
+	i := 2
+	var id strings.Builder
+	for ; i < len(words) && words[i] < pdfTextLatch; i++ {
+		fmt.Fprintf(&id, "%03d", words[i])
+	}
+	m.FileID = id.String()
+
+	last := false
+	for i < len(words) {
+		switch words[i] {
+		case pdfMacroField:
+			if i+1 == len(words) {
+				return nil, errors.New("truncated macro field")
+			}
+			field := words[i+1]
+			j := i + 2
+			for j < len(words) && words[j] < pdfTextLatch {
+				j++
+			}
+			if field == pdfMacroSegmentCount {
+				count, err := pdfNumericValue(words[i+2 : j])
+				if err != nil {
+					return nil, err
+				}
+				m.SegmentCount, _ = strconv.Atoi(count)
+			}
+			i = j
+		case pdfMacroLast:
+			last = true
+			i++
+		case pdfTextLatch:
+			for ; i < len(words); i++ {
+				if words[i] != pdfTextLatch {
+					return nil, errors.New("data after macro control block")
+				}
+			}
+		default:
+			return nil, fmt.Errorf("invalid macro codeword %d", words[i])
+		}
+	}
+	if last && m.SegmentCount == 0 {
+		m.SegmentCount = m.SegmentIndex + 1
+	}
+	return m, nil
+}
+
+// pdf417ECC returns the k error correction codewords of data, the
+// negated remainder of data(x)·x^k divided by (x-3)(x-3²)…(x-3^k).
+func pdf417ECC(data []int, k int) []int {
+	g := []int{1}
+	for i, a := 1, 1; i <= k; i++ {
+		a = a * 3 % pdfModulus
+		next := make([]int, len(g)+1)
+		for j, c := range g {
+			next[j] = (next[j] + c) % pdfModulus
+			next[j+1] = (next[j+1] + pdfModulus - c*a%pdfModulus) % pdfModulus
+		}
+		g = next
+	}
+
+	msg := make([]int, len(data)+k)
+	copy(msg, data)
+	for i := range data {
+		c := msg[i]
+		if c == 0 {
+			continue
+		}
+		for j := 1; j <= k; j++ {
+			msg[i+j] = (msg[i+j] + pdfModulus - c*g[j]%pdfModulus) % pdfModulus
+		}
+	}
+	ecc := msg[len(data):]
+	for i, r := range ecc {
+		ecc[i] = (pdfModulus - r) % pdfModulus
+	}
+	return ecc
+}
+
+// pdf417Correct corrects up to k/2 errors in place in codewords, whose
+// last k are error correction, and returns the number corrected.
+func pdf417Correct(codewords []int, k int) (int, error) {
+	n := len(codewords)
+	syndromes := make([]int, k)
+	clean := true
+	for i, a := 0, 3; i < k; i, a = i+1, a*3%pdfModulus {
+		syndromes[i] = pdfEval(codewords, a)
+		clean = clean && syndromes[i] == 0
+	}
+	if clean {
+		return 0, nil
+	}
+
+	// Berlekamp-Massey finds the error locator, lowest degree first
+	locator, prev := []int{1}, []int{1}
+	errs, shift, scale := 0, 1, 1
+	for r := 0; r < k; r++ {
+		d := syndromes[r]
+		for i := 1; i <= errs && i < len(locator); i++ {
+			d = (d + locator[i]*syndromes[r-i]) % pdfModulus
+		}
+		if d == 0 {
+			shift++
+			continue
+		}
+		coef := d * pdfInverse(scale) % pdfModulus
+		saved := append([]int(nil), locator...)
+		for len(locator) < len(prev)+shift {
+			locator = append(locator, 0)
+		}
+		for i, c := range prev {
+			locator[i+shift] = (locator[i+shift] + pdfModulus - coef*c%pdfModulus) % pdfModulus
+		}
+		if 2*errs <= r {
+			errs, prev, scale, shift = r+1-errs, saved, d, 1
+		} else {
+			shift++
+		}
+	}
+	if errs > k/2 {
+		return 0, errors.New("too many errors")
+	}
+
+	// Evaluator: syndromes(x)·locator(x) mod x^k
+	evaluator := make([]int, k)
+	for i := range evaluator {
+		for j := 0; j <= i && j < len(locator); j++ {
+			evaluator[i] = (evaluator[i] + syndromes[i-j]*locator[j]) % pdfModulus
+		}
+	}
+
+	found := 0
+	for e, inv := 0, 1; e < n; e, inv = e+1, inv*pdfInverse(3)%pdfModulus {
+		if pdfEvalLow(locator, inv) != 0 {
+			continue
+		}
+		var deriv int
+		for i := 1; i < len(locator); i++ {
+			deriv = (deriv + i*locator[i]%pdfModulus*pdfPow(inv, i-1)) % pdfModulus
+		}
+		if deriv == 0 {
+			return 0, errors.New("uncorrectable errors")
+		}
+		// Forney: the error value is -evaluator(1/X) / locator'(1/X)
+		value := pdfEvalLow(evaluator, inv) * pdfInverse(deriv) % pdfModulus
+		p := n - 1 - e
+		codewords[p] = (codewords[p] + value) % pdfModulus
+		found++
+	}
+	if found != errs {
+		return 0, errors.New("uncorrectable errors")
+	}
+	for i, a := 0, 3; i < k; i, a = i+1, a*3%pdfModulus {
+		if pdfEval(codewords, a) != 0 {
+			return 0, errors.New("uncorrectable errors")
+		}
+	}
+	return found, nil
+}
+
+// pdfEval evaluates the polynomial with coefficients p, highest degree
+// first, at x.
+func pdfEval(p []int, x int) int {
+	v := 0
+	for _, c := range p {
+		v = (v*x + c) % pdfModulus
+	}
+	return v
+}
+
+// pdfEvalLow evaluates the polynomial with coefficients p, lowest degree
+// first, at x.
+func pdfEvalLow(p []int, x int) int {
+	v := 0
+	for i := len(p) - 1; i >= 0; i-- {
+		v = (v*x + p[i]) % pdfModulus
+	}
+	return v
+}
+
+// pdfPow returns a^e modulo pdfModulus.
+func pdfPow(a, e int) int {
+	v := 1
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			v = v * a % pdfModulus
+		}
+		a = a * a % pdfModulus
+	}
+	return v
+}
+
+// pdfInverse returns the multiplicative inverse of a modulo pdfModulus.
+func pdfInverse(a int) int {
+	return pdfPow(a, pdfModulus-2)
+}
@@ -0,0 +1,261 @@
+package qrverify
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+)
+
+// pdfCode is a codeword read from a bar-space pattern.
+type pdfCode struct {
+	cluster int // Row cluster: 0, 1 or 2 for clusters 0, 3 and 6
+	value   int
+}
+
+// pdfCodes maps each codeword pattern to its cluster and value.
+var pdfCodes = func() map[uint32]pdfCode {
+	m := make(map[uint32]pdfCode, 3*len(pdf417Patterns[0]))
+	for c := range pdf417Patterns {
+		for v, p := range pdf417Patterns[c] {
+			m[p] = pdfCode{cluster: c, value: v}
+		}
+	}
+	return m
+}()
+
+// Element widths in modules of the start pattern and of the first eight
+// elements of the stop pattern, both 17 modules.
+var (
+	pdfStartWidths = [8]int{8, 1, 1, 1, 1, 1, 1, 3}
+	pdfStopWidths  = [8]int{7, 1, 1, 3, 1, 1, 1, 2}
+)
+
+// pdfVotes counts readings of one value.
+type pdfVotes map[int]int
+
+// best returns the most frequent reading.
+func (v pdfVotes) best() (int, bool) {
+	value, count := 0, 0
+	for k, n := range v {
+		if n > count || n == count && k < value {
+			value, count = k, n
+		}
+	}
+	return value, count > 0
+}
+
+// readPDF417 decodes an upright PDF417 symbol in img. Every pixel row is
+// scanned for a start pattern followed by codewords; the left row
+// indicator places a scan in its symbol row, and the codewords of all
+// scans of a row are combined by majority before error correction.
+func readPDF417(img image.Image) (string, *PDF417Macro, error) {
+	lum := luminance(img)
+	defer putLum(lum)
+	pix := *lum
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	cells := make(map[[2]int]pdfVotes)
+	var rowsHigh, levelRows, cols = pdfVotes{}, pdfVotes{}, pdfVotes{}
+	maxRow := -1
+	runs := make([]int, 0, w)
+	for y := 0; y < h; y++ {
+		runs = pdfRuns(runs[:0], pix[y*w:(y+1)*w])
+		codes, complete := pdfScanRow(runs)
+		if len(codes) == 0 {
+			continue
+		}
+
+		left := codes[0]
+		row := 3*(left.value/30) + left.cluster
+		indicators := [3]pdfVotes{rowsHigh, levelRows, cols}
+		indicators[left.cluster][left.value%30]++
+		data := codes[1:]
+		if complete && len(codes) > 1 {
+			right := codes[len(codes)-1]
+			if right.cluster == left.cluster {
+				indicators[(left.cluster+2)%3][right.value%30]++
+				// Only rows read end to end extend the symbol beyond
+				// its row indicators
+				maxRow = max(maxRow, row)
+			}
+			data = codes[1 : len(codes)-1]
+		}
+		for c, code := range data {
+			if code.cluster != left.cluster {
+				continue
+			}
+			cell := [2]int{row, c}
+			if cells[cell] == nil {
+				cells[cell] = pdfVotes{}
+			}
+			cells[cell][code.value]++
+		}
+	}
+	if len(cells) == 0 {
+		return "", nil, errors.New("no PDF417 symbol found")
+	}
+
+	high, ok1 := rowsHigh.best()
+	lr, ok2 := levelRows.best()
+	c, ok3 := cols.best()
+	if !ok1 || !ok2 || !ok3 {
+		return "", nil, errors.New("row indicators unreadable")
+	}
+	numCols, level := c+1, lr/3
+	numRows := max(3*high+lr%3+1, maxRow+1)
+	if numRows < pdfMinRows || numRows > pdfMaxRows || numCols > pdfMaxColumns || level > 8 {
+		return "", nil, fmt.Errorf("invalid symbol of %d rows, %d columns and security level %d", numRows, numCols, level)
+	}
+
+	codewords := make([]int, numRows*numCols)
+	missing := 0
+	for r := 0; r < numRows; r++ {
+		for c := 0; c < numCols; c++ {
+			v, ok := cells[[2]int{r, c}].best()
+			if !ok {
+				missing++
+			}
+			codewords[r*numCols+c] = v
+		}
+	}
+	k := 2 << level
+	if missing > k/2 {
+		return "", nil, fmt.Errorf("%d of %d codewords unreadable", missing, len(codewords))
+	}
+	if _, err := pdf417Correct(codewords, k); err != nil {
+		return "", nil, fmt.Errorf("error correction failed: %w", err)
+	}
+
+	n := codewords[0]
+	if n < 1 || n > len(codewords)-k {
+		return "", nil, fmt.Errorf("invalid symbol length descriptor %d", n)
+	}
+	return pdf417Decompact(codewords[1:n])
+}
+
+// pdfRuns appends to runs the widths of alternating dark and light runs in
+// line, starting with the first dark pixel.
+func pdfRuns(runs []int, line []byte) []int {
+	x := 0
+	for x < len(line) && line[x] >= 128 {
+		x++
+	}
+	for x < len(line) {
+		start, dark := x, line[x] < 128
+		for x < len(line) && (line[x] < 128) == dark {
+			x++
+		}
+		runs = append(runs, x-start)
+	}
+	return runs
+}
+
+// pdfScanRow reads the codewords following the first start pattern in
+// runs, beginning with the left row indicator. complete reports whether
+// the stop pattern ends the row, making the last codeword the right row
+// indicator.
+func pdfScanRow(runs []int) (codes []pdfCode, complete bool) {
+	i := 0
+	for ; i+8 <= len(runs); i += 2 {
+		if widths, _ := pdfWidths(runs[i : i+8]); widths == pdfStartWidths {
+			break
+		}
+	}
+	for i += 8; i+8 <= len(runs); i += 8 {
+		widths, exact := pdfWidths(runs[i : i+8])
+		if widths == pdfStopWidths {
+			return codes, true
+		}
+		code, ok := pdfCodes[pdfBits(widths)]
+		if !ok && len(codes) > 0 {
+			code, ok = pdfNearest(widths, exact, codes[0].cluster)
+		}
+		if !ok {
+			break
+		}
+		codes = append(codes, code)
+	}
+	return codes, false
+}
+
+// pdfNearest returns the codeword of the row's cluster closest to the
+// measured widths among those one module away from the rounded widths.
+// Downscaled images often round one element the wrong way.
+func pdfNearest(widths [8]int, exact [8]float64, cluster int) (pdfCode, bool) {
+	var best pdfCode
+	bestErr := -1.0
+	for i := range widths {
+		for j := range widths {
+			if i == j || widths[i] == 6 || widths[j] == 1 {
+				continue
+			}
+			w := widths
+			w[i]++
+			w[j]--
+			code, ok := pdfCodes[pdfBits(w)]
+			if !ok || code.cluster != cluster {
+				continue
+			}
+			var e float64
+			for k := range w {
+				e += math.Abs(exact[k] - float64(w[k]))
+			}
+			if bestErr < 0 || e < bestErr {
+				best, bestErr = code, e
+			}
+		}
+	}
+	return best, bestErr >= 0
+}
+
+// pdfBits returns the pattern of element widths, bars first.
+func pdfBits(widths [8]int) uint32 {
+	var bits uint32
+	for e, n := range widths {
+		bits <<= n
+		if e%2 == 0 {
+			bits |= 1<<n - 1
+		}
+	}
+	return bits
+}
+
+// pdfWidths scales eight run widths to element widths in modules that
+// total 17, rounding so that the total is kept. It also returns the
+// unrounded widths.
+func pdfWidths(runs []int) ([8]int, [8]float64) {
+	var widths [8]int
+	var exact [8]float64
+	total := 0
+	for _, r := range runs {
+		total += r
+	}
+	sum := 0
+	for i, r := range runs {
+		exact[i] = float64(r) * 17 / float64(total)
+		widths[i] = max(1, int(exact[i]+0.5))
+		sum += widths[i]
+	}
+	for sum != 17 {
+		// Adjust the element rounded furthest in the wrong direction
+		best := -1
+		for i := range widths {
+			switch {
+			case sum < 17 && (best < 0 || exact[i]-float64(widths[i]) > exact[best]-float64(widths[best])):
+				best = i
+			case sum > 17 && widths[i] > 1 && (best < 0 || exact[i]-float64(widths[i]) < exact[best]-float64(widths[best])):
+				best = i
+			}
+		}
+		if sum < 17 {
+			widths[best]++
+			sum++
+		} else {
+			widths[best]--
+			sum--
+		}
+	}
+	return widths, exact
+}
@@ -0,0 +1,326 @@
+package qrverify
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	bcpdf417 "github.com/boombuler/barcode/pdf417"
+)
+
+func TestEncodePDF417(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		opts    *PDF417Options
+		columns int // Zero to skip
+		ecc     int
+	}{
+		{"text", "SHIP TO: Jane Doe, 42 Harbour Rd; Portsmouth", nil, 0, 8},
+		{"numeric", "0123456789012345678901234567890123456789012345", nil, 0, 8},
+		{"binary", "Grüße aus Köln ☃\x00\x01\xff", nil, 0, 8},
+		{"security level", "ID 4711", &PDF417Options{SecurityLevel: 8}, 0, 512},
+		{"columns", "ID 4711", &PDF417Options{Columns: 12}, 12, 8},
+		{"row height", "ID 4711", &PDF417Options{RowHeight: 6, VerifyOutput: true}, 0, 8},
+		{"large", strings.Repeat("DL:D12345678;DOB:19700101;", 40), nil, 0, 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := EncodePDF417(tt.data, tt.opts)
+			if err != nil {
+				t.Fatalf("EncodePDF417 failed: %v", err)
+			}
+			s := result.Symbol
+			if s == nil || s.Format != "PDF417" || s.ErrorCodewords != tt.ecc || s.Columns != 17*s.DataColumns+69 {
+				t.Errorf("Symbol = %+v", s)
+			}
+			if tt.columns != 0 && s.DataColumns != tt.columns {
+				t.Errorf("DataColumns = %d, want %d", s.DataColumns, tt.columns)
+			}
+			if err := VerifyPDF417(result.Image, tt.data); err != nil {
+				t.Errorf("VerifyPDF417 failed: %v", err)
+			}
+			text, macro, err := DecodePDF417(result.Image)
+			if err != nil || text != tt.data || macro != nil {
+				t.Errorf("DecodePDF417() = %q, %v, %v", text, macro, err)
+			}
+		})
+	}
+}
+
+func TestEncodePDF417AspectRatio(t *testing.T) {
+	data := strings.Repeat("aspect ratio ", 20)
+	wide, err := EncodePDF417(data, &PDF417Options{AspectRatio: 8})
+	if err != nil {
+		t.Fatalf("EncodePDF417 failed: %v", err)
+	}
+	tall, err := EncodePDF417(data, &PDF417Options{AspectRatio: 0.5})
+	if err != nil {
+		t.Fatalf("EncodePDF417 failed: %v", err)
+	}
+	if wide.Symbol.DataColumns <= tall.Symbol.DataColumns || wide.Symbol.Rows >= tall.Symbol.Rows {
+		t.Errorf("wide %+v, tall %+v", wide.Symbol, tall.Symbol)
+	}
+}
+
+func TestEncodePDF417Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		opts *PDF417Options
+	}{
+		{"empty", "", nil},
+		{"security level", "A", &PDF417Options{SecurityLevel: 9}},
+		{"columns", "A", &PDF417Options{Columns: 31}},
+		{"aspect ratio", "A", &PDF417Options{AspectRatio: -1}},
+		{"row height", "A", &PDF417Options{RowHeight: 1}},
+		{"too large", strings.Repeat("\xff", 1200), nil},
+		{"too large for columns", strings.Repeat("\xff", 500), &PDF417Options{Columns: 1}},
+		{"macro file ID", "A", &PDF417Options{Macro: &PDF417Macro{FileID: "9999"}}},
+		{"macro file ID group", "A", &PDF417Options{Macro: &PDF417Macro{FileID: "950"}}},
+		{"macro segment", "A", &PDF417Options{Macro: &PDF417Macro{FileID: "001", SegmentIndex: 2, SegmentCount: 2}}},
+	}
+	for _, tt := range tests {
+		if _, err := EncodePDF417(tt.data, tt.opts); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestVerifyPDF417Mismatch(t *testing.T) {
+	result, err := EncodePDF417("PARCEL 1 OF 2", nil)
+	if err != nil {
+		t.Fatalf("EncodePDF417 failed: %v", err)
+	}
+	var verr *VerificationError
+	if err := VerifyPDF417(result.Image, "PARCEL 2 OF 2"); !errors.As(err, &verr) {
+		t.Errorf("VerifyPDF417() = %v, want *VerificationError", err)
+	}
+
+	qr, err := Encode("PARCEL 1 OF 2", nil)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := VerifyPDF417(qr, "PARCEL 1 OF 2"); err == nil {
+		t.Error("Expected error verifying a QR code as PDF417")
+	}
+}
+
+func TestEncodePDF417Macro(t *testing.T) {
+	data := strings.Repeat("Manifest line 0042: 12 cartons, 340 kg; ", 100)
+	results, err := EncodePDF417Macro(data, 0, nil)
+	if err != nil {
+		t.Fatalf("EncodePDF417Macro failed: %v", err)
+	}
+	if len(results) < 2 {
+		t.Fatalf("got %d segments, want several", len(results))
+	}
+
+	var images [][]byte
+	for i := len(results) - 1; i >= 0; i-- {
+		_, macro, err := DecodePDF417(results[i].Image)
+		if err != nil {
+			t.Fatalf("DecodePDF417 failed: %v", err)
+		}
+		if macro == nil || macro.SegmentIndex != i || macro.SegmentCount != len(results) {
+			t.Errorf("segment %d: macro = %+v", i, macro)
+		}
+		images = append(images, results[i].Image)
+	}
+	got, err := DecodePDF417Macro(images)
+	if err != nil {
+		t.Fatalf("DecodePDF417Macro failed: %v", err)
+	}
+	if got != data {
+		t.Error("reassembled data differs")
+	}
+	if _, err := DecodePDF417Macro(images[1:]); err == nil {
+		t.Error("Expected error for missing segment")
+	}
+}
+
+func TestEncodePDF417MacroSegments(t *testing.T) {
+	opts := &PDF417Options{Macro: &PDF417Macro{FileID: "123456"}}
+	results, err := EncodePDF417Macro("ab€cdefg", 3, opts)
+	if err != nil {
+		t.Fatalf("EncodePDF417Macro failed: %v", err)
+	}
+	var images [][]byte
+	for i, r := range results {
+		_, macro, err := DecodePDF417(r.Image)
+		if err != nil {
+			t.Fatalf("DecodePDF417 failed: %v", err)
+		}
+		want := PDF417Macro{FileID: "123456", SegmentIndex: i, SegmentCount: 3}
+		if *macro != want {
+			t.Errorf("macro = %+v, want %+v", *macro, want)
+		}
+		images = append(images, r.Image)
+	}
+	if got, err := DecodePDF417Macro(images); err != nil || got != "ab€cdefg" {
+		t.Errorf("DecodePDF417Macro() = %q, %v", got, err)
+	}
+
+	other, err := EncodePDF417Macro("other file", 1, nil)
+	if err != nil {
+		t.Fatalf("EncodePDF417Macro failed: %v", err)
+	}
+	if _, err := DecodePDF417Macro(append(images, other[0].Image)); err == nil {
+		t.Error("Expected error mixing files")
+	}
+	if _, err := EncodePDF417Macro("ab", 3, nil); err == nil {
+		t.Error("Expected error for more segments than characters")
+	}
+}
+
+func TestEncodePDF417Thermal(t *testing.T) {
+	data := "1Z999AA10123456784 GROUND"
+	if _, err := EncodePDF417(data, &PDF417Options{Size: 1000, ThermalWidth: 384}); err != nil {
+		t.Errorf("EncodePDF417 at 384 dots failed: %v", err)
+	}
+	// Below one dot per module
+	if _, err := EncodePDF417(data, &PDF417Options{Size: 1000, ThermalWidth: 100}); err == nil {
+		t.Error("Expected error for modules narrower than a dot")
+	}
+	// About 1.2 dots per module blurs the bars
+	if _, err := EncodePDF417(data, &PDF417Options{Size: 1000, ThermalWidth: 130}); err == nil {
+		t.Error("Expected error for unreadable printed symbol")
+	}
+}
+
+// TestReadPDF417Foreign reads symbols from another encoder.
+func TestReadPDF417Foreign(t *testing.T) {
+	data := "Hello from another PDF417 encoder 0123456789"
+	for level := byte(0); level <= 5; level++ {
+		bc, err := bcpdf417.Encode(data, level)
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		b := bc.Bounds()
+		img, err := render(bc, 3*b.Dx()+12, 3*b.Dy()+12)
+		if err != nil {
+			t.Fatalf("render failed: %v", err)
+		}
+		if got, _, err := readPDF417(img); err != nil || got != data {
+			t.Errorf("level %d: readPDF417() = %q, %v", level, got, err)
+		}
+	}
+}
+
+// TestReadPDF417ForeignModes reads symbols from another encoder that use
+// each compaction mode.
+func TestReadPDF417ForeignModes(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"numeric", "9780201379624"},                             // 902, 13 digits
+		{"numeric in text", "Invoice 12345678901234567890 paid"}, // 902, 900
+		{"byte", "Größe: 日本"},                                    // 901, 14 bytes
+		{"byte multiple of six", "Größe: 日本語"},                   // 924, 18 bytes
+		{"text then byte", "Lot 2026-0042 日本製"},                  // 901 after text
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc, err := bcpdf417.Encode(tt.data, 2)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			b := bc.Bounds()
+			img, err := render(bc, 3*b.Dx()+12, 3*b.Dy()+12)
+			if err != nil {
+				t.Fatalf("render failed: %v", err)
+			}
+			if got, macro, err := readPDF417(img); err != nil || got != tt.data || macro != nil {
+				t.Errorf("readPDF417() = %q, %v, %v", got, macro, err)
+			}
+		})
+	}
+}
+
+// TestPDF417DecompactMacro decodes the Macro PDF417 examples of ISO/IEC
+// 15438 Annex H, as also used by ZXing.
+func TestPDF417DecompactMacro(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []int
+		want  PDF417Macro
+	}{
+		{
+			// Segment count, sender "CEN BE" and addressee "ISO CH" fields
+			"first segment",
+			[]int{928, 111, 100, 17, 53, 923, 1, 111, 104, 923, 3, 64, 416, 34, 923, 4, 258, 446, 67},
+			PDF417Macro{FileID: "017053", SegmentIndex: 0, SegmentCount: 4},
+		},
+		{
+			"last segment",
+			[]int{928, 111, 103, 17, 53, 923, 1, 111, 104, 922},
+			PDF417Macro{FileID: "017053", SegmentIndex: 3, SegmentCount: 4},
+		},
+		{
+			"no optional fields",
+			[]int{928, 111, 100, 100, 200, 300},
+			PDF417Macro{FileID: "100200300", SegmentIndex: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, macro, err := pdf417Decompact(tt.words)
+			if err != nil || got != "" || macro == nil || *macro != tt.want {
+				t.Errorf("pdf417Decompact() = %q, %+v, %v, want %+v", got, macro, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPDF417Correct(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		k := 2 << r.Intn(8)
+		data := make([]int, r.Intn(100)+1)
+		for i := range data {
+			data[i] = r.Intn(pdfModulus)
+		}
+		want := append(data, pdf417ECC(data, k)...)
+		got := append([]int(nil), want...)
+		errs := r.Intn(k/2 + 1)
+		for i := 0; i < errs; i++ {
+			got[r.Intn(len(got))] = r.Intn(pdfModulus)
+		}
+		if _, err := pdf417Correct(got, k); err != nil {
+			t.Fatalf("%d errors, k %d: pdf417Correct failed: %v", errs, k, err)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("%d errors, k %d: codeword %d not corrected", errs, k, i)
+			}
+		}
+	}
+}
+
+func TestPDF417Compaction(t *testing.T) {
+	chars := "AZaz 09;<>@[]_`~!\r\t\n,:-.$/\"|*()?{}'&#+%=^\x00\x80\xff"
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 2000; n++ {
+		b := make([]byte, r.Intn(60)+1)
+		for i := range b {
+			switch r.Intn(3) {
+			case 0:
+				b[i] = '0' + byte(r.Intn(10))
+			default:
+				b[i] = chars[r.Intn(len(chars))]
+			}
+		}
+		words := pdf417Compact(string(b))
+		got, macro, err := pdf417Decompact(words)
+		if err != nil || got != string(b) || macro != nil {
+			t.Fatalf("pdf417Decompact(pdf417Compact(%q)) = %q, %v, %v", b, got, macro, err)
+		}
+	}
+
+	// 44 digits pack into one numeric group of 15 codewords
+	if words := pdf417Compact(strings.Repeat("9", 44)); len(words) != 16 || words[0] != pdfNumLatch {
+		t.Errorf("numeric compaction = %v", words)
+	}
+}
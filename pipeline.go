@@ -0,0 +1,274 @@
+package qrverify
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxInflatedBytes bounds zlib output when inverting a pipeline, so a
+// hostile code cannot expand into unbounded memory.
+const maxInflatedBytes = 4 << 20
+
+// Transform is a reversible stage of a Pipeline.
+type Transform interface {
+	Apply(data []byte) ([]byte, error)
+	Invert(data []byte) ([]byte, error)
+}
+
+// Pipeline is a sequence of transforms turning binary data into the text
+// stored in a QR code. Verification inverts the stages in reverse order
+// and compares the original bytes.
+type Pipeline []Transform
+
+// Base45Pipeline returns the zlib, Base45 and prefix pipeline used by
+// health certificates such as the EU Digital COVID Certificate with the
+// prefix "HC1:". The output is alphanumeric, so it encodes in the denser
+// alphanumeric mode. An empty prefix adds no prefix stage.
+func Base45Pipeline(prefix string) Pipeline {
+	p := Pipeline{Zlib{}, Base45{}}
+	if prefix != "" {
+		p = append(p, Prefix(prefix))
+	}
+	return p
+}
+
+// Apply runs the stages in order.
+func (p Pipeline) Apply(data []byte) ([]byte, error) {
+	out, _, err := p.apply(data)
+	return out, err
+}
+
+// Invert runs the inverse stages in reverse order.
+func (p Pipeline) Invert(data []byte) ([]byte, error) {
+	for i := len(p) - 1; i >= 0; i-- {
+		var err error
+		if data, err = p[i].Invert(data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// apply runs the stages in order and returns size statistics.
+func (p Pipeline) apply(data []byte) ([]byte, *PipelineStats, error) {
+	stats := &PipelineStats{InputBytes: len(data), CompressedBytes: len(data)}
+	for _, t := range p {
+		var err error
+		if data, err = t.Apply(data); err != nil {
+			return nil, nil, err
+		}
+		switch t.(type) {
+		case Zlib, *Zlib:
+			stats.CompressedBytes = len(data)
+		}
+	}
+
+	if !utf8.Valid(data) {
+		return nil, nil, errors.New("pipeline output is not text: end with an encoding stage such as Base45")
+	}
+
+	mode := encodingMode(string(data))
+	stats.TransportBytes = len(data)
+	stats.Mode = mode.String()
+	if stats.CompressedBytes > 0 {
+		stats.CompressionRatio = float64(stats.InputBytes) / float64(stats.CompressedBytes)
+	}
+	if n := len(data); n > 0 {
+		stats.ModeSavings = 1 - float64(modeBits(mode, n))/float64(modeBits(modeByte, n))
+	}
+	return data, stats, nil
+}
+
+// PipelineStats reports the size effect of a Pipeline. Mode is the mode
+// tried first: the decoder misreads about one alphanumeric Base45 text in
+// 200, which is then stored in byte mode like Encode does, if it fits.
+type PipelineStats struct {
+	InputBytes       int     // Original data size
+	CompressedBytes  int     // Size after compression, InputBytes without a Zlib stage
+	TransportBytes   int     // Size of the text stored in the QR code
+	CompressionRatio float64 // InputBytes / CompressedBytes
+	Mode             string  // QR encoding mode of the text: numeric, alphanumeric or byte
+	ModeSavings      float64 // Fraction of data bits Mode saves over byte mode
+}
+
+// Zlib compresses data with zlib (RFC 1950).
+type Zlib struct {
+	// Level is the compress/flate level.
+	// Zero value uses zlib.BestCompression.
+	Level int
+}
+
+// Apply compresses data.
+func (z Zlib) Apply(data []byte) ([]byte, error) {
+	level := z.Level
+	if level == 0 {
+		level = zlib.BestCompression
+	}
+
+	var buf bytes.Buffer
+	w, err := zlib.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, fmt.Errorf("zlib: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("zlib: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("zlib: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Invert decompresses data, failing if the output exceeds 4 MiB.
+func (z Zlib) Invert(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("zlib: %w", err)
+	}
+	defer r.Close()
+
+	out, err := io.ReadAll(io.LimitReader(r, maxInflatedBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("zlib: %w", err)
+	}
+	if len(out) > maxInflatedBytes {
+		return nil, errors.New("zlib: decompressed data exceeds 4 MiB")
+	}
+	return out, nil
+}
+
+// base45Alphabet is the Base45 alphabet (RFC 9285), which is the QR
+// alphanumeric character set in the same order.
+const base45Alphabet = alphanumericChars
+
+// Base45 encodes binary data as QR alphanumeric text (RFC 9285).
+type Base45 struct{}
+
+// Apply encodes each two bytes as three characters and a final odd byte as
+// two characters.
+func (Base45) Apply(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data)/2*3+2)
+	for i := 0; i+1 < len(data); i += 2 {
+		n := int(data[i])<<8 | int(data[i+1])
+		out = append(out, base45Alphabet[n%45], base45Alphabet[n/45%45], base45Alphabet[n/2025])
+	}
+	if len(data)%2 == 1 {
+		n := int(data[len(data)-1])
+		out = append(out, base45Alphabet[n%45], base45Alphabet[n/45])
+	}
+	return out, nil
+}
+
+// Invert decodes Base45 text.
+func (Base45) Invert(data []byte) ([]byte, error) {
+	if len(data)%3 == 1 {
+		return nil, errors.New("base45: invalid length")
+	}
+
+	values := make([]int, len(data))
+	for i, c := range data {
+		v := strings.IndexByte(base45Alphabet, c)
+		if v < 0 {
+			return nil, errors.New("base45: invalid character")
+		}
+		values[i] = v
+	}
+
+	out := make([]byte, 0, len(data)/3*2+1)
+	for i := 0; i < len(values); i += 3 {
+		if i+2 < len(values) {
+			n := values[i] + values[i+1]*45 + values[i+2]*2025
+			if n > 0xffff {
+				return nil, errors.New("base45: value out of range")
+			}
+			out = append(out, byte(n>>8), byte(n))
+			continue
+		}
+		n := values[i] + values[i+1]*45
+		if n > 0xff {
+			return nil, errors.New("base45: value out of range")
+		}
+		out = append(out, byte(n))
+	}
+	return out, nil
+}
+
+// Prefix adds a fixed context identifier such as "HC1:".
+type Prefix string
+
+// Apply prepends the prefix.
+func (p Prefix) Apply(data []byte) ([]byte, error) {
+	return append([]byte(p), data...), nil
+}
+
+// Invert removes the prefix, failing if it is missing.
+func (p Prefix) Invert(data []byte) ([]byte, error) {
+	rest, ok := bytes.CutPrefix(data, []byte(p))
+	if !ok {
+		return nil, fmt.Errorf("missing %q prefix", string(p))
+	}
+	return rest, nil
+}
+
+// EncodePipeline generates a verified QR code holding data transformed by
+// p. Verification inverts p on the decoded text and compares the result
+// with data byte for byte. Result.Data is the transformed text and
+// Result.Pipeline reports the compression and mode savings.
+func EncodePipeline(data []byte, p Pipeline, opts *EncodeOptions) (*Result, error) {
+	return EncodePipelineContext(context.Background(), data, p, opts)
+}
+
+// EncodePipelineContext is like EncodePipeline but honors cancellation and
+// deadlines of ctx.
+func EncodePipelineContext(ctx context.Context, data []byte, p Pipeline, opts *EncodeOptions) (*Result, error) {
+	text, stats, err := p.apply(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := encodeDetailed(ctx, string(text), opts, encodeHooks{
+		check: func(decoded string) error {
+			return checkPipeline(decoded, data, p)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Pipeline = stats
+	return result, nil
+}
+
+// VerifyPipeline checks that qrImage (PNG bytes) decodes to text that
+// inverts through p to data.
+func VerifyPipeline(qrImage []byte, data []byte, p Pipeline) error {
+	img, err := png.Decode(bytes.NewReader(qrImage))
+	if err != nil {
+		return fmt.Errorf("failed to decode PNG: %w", err)
+	}
+
+	decoded, err := decode(img)
+	if err != nil {
+		return fmt.Errorf("failed to read QR code: %w", err)
+	}
+
+	return checkPipeline(decoded, data, p)
+}
+
+// checkPipeline inverts p on decoded and compares the result with data.
+func checkPipeline(decoded string, data []byte, p Pipeline) error {
+	got, err := p.Invert([]byte(decoded))
+	if err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
+	if !bytes.Equal(got, data) {
+		return &VerificationError{Original: string(data), Decoded: string(got)}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package qrverify
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestBase45(t *testing.T) {
+	// Test vectors from RFC 9285
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"AB", "BB8"},
+		{"Hello!!", "%69 VD92EX0"},
+		{"base-45", "UJCLQE7W581"},
+		{"ietf!", "QED8WEX0"},
+	}
+	for _, tt := range tests {
+		got, err := Base45{}.Apply([]byte(tt.input))
+		if err != nil {
+			t.Fatalf("Apply(%q) failed: %v", tt.input, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Apply(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+
+		back, err := Base45{}.Invert(got)
+		if err != nil {
+			t.Fatalf("Invert(%q) failed: %v", got, err)
+		}
+		if string(back) != tt.input {
+			t.Errorf("Invert(%q) = %q, want %q", got, back, tt.input)
+		}
+	}
+
+	for _, s := range []string{"A", "GGW", "ab", "FGW0"} {
+		if _, err := (Base45{}).Invert([]byte(s)); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestPipelineRoundTrip(t *testing.T) {
+	data := []byte(strings.Repeat(`{"name":"Jane Doe","vaccine":"EU/1/20/1528","dose":2}`, 5))
+	p := Base45Pipeline("HC1:")
+
+	text, err := p.Apply(data)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !strings.HasPrefix(string(text), "HC1:") || strings.Trim(string(text), alphanumericChars) != "" {
+		t.Errorf("Apply() = %q, want HC1: prefixed alphanumeric text", text)
+	}
+
+	back, err := p.Invert(text)
+	if err != nil {
+		t.Fatalf("Invert failed: %v", err)
+	}
+	if !bytes.Equal(back, data) {
+		t.Errorf("Invert() = %q, want %q", back, data)
+	}
+
+	if _, err := p.Invert([]byte("HC2:" + string(text[4:]))); err == nil {
+		t.Error("Expected error for wrong prefix")
+	}
+	if _, err := (Pipeline{Zlib{}}).Invert([]byte("not zlib")); err == nil {
+		t.Error("Expected error for invalid zlib data")
+	}
+}
+
+func TestPipelineRejectsBinaryOutput(t *testing.T) {
+	_, err := EncodePipeline([]byte("hello hello hello"), Pipeline{Zlib{}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "not text") {
+		t.Errorf("Expected error for binary pipeline output, got: %v", err)
+	}
+}
+
+func TestZlibInvertLimit(t *testing.T) {
+	bomb, err := Zlib{}.Apply(make([]byte, maxInflatedBytes+1))
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if _, err := (Zlib{}).Invert(bomb); err == nil {
+		t.Error("Expected error for output over the limit")
+	}
+}
+
+func TestEncodePipeline(t *testing.T) {
+	data := []byte(strings.Repeat("certificate field value; ", 40))
+	p := Base45Pipeline("HC1:")
+
+	result, err := EncodePipeline(data, p, nil)
+	if err != nil {
+		t.Fatalf("EncodePipeline failed: %v", err)
+	}
+
+	stats := result.Pipeline
+	if stats == nil {
+		t.Fatal("Expected pipeline stats")
+	}
+	if stats.InputBytes != len(data) || stats.TransportBytes != len(result.Data) {
+		t.Errorf("stats = %+v, want InputBytes %d, TransportBytes %d", stats, len(data), len(result.Data))
+	}
+	if stats.CompressionRatio <= 5 {
+		t.Errorf("CompressionRatio = %.2f, want > 5 for repetitive data", stats.CompressionRatio)
+	}
+	if stats.Mode != "alphanumeric" || stats.ModeSavings < 0.3 || stats.ModeSavings > 0.32 {
+		t.Errorf("Mode = %s, ModeSavings = %.3f, want alphanumeric, about 0.31", stats.Mode, stats.ModeSavings)
+	}
+
+	if err := VerifyPipeline(result.Image, data, p); err != nil {
+		t.Errorf("VerifyPipeline failed: %v", err)
+	}
+
+	err = VerifyPipeline(result.Image, []byte("other data"), p)
+	var verr *VerificationError
+	if !errors.As(err, &verr) {
+		t.Errorf("Expected VerificationError for different data, got: %v", err)
+	}
+	if err := VerifyPipeline(result.Image, data, Base45Pipeline("")); err == nil {
+		t.Error("Expected VerifyPipeline to fail without the prefix stage")
+	}
+}
+
+func TestEncodePipelineByteModeFallback(t *testing.T) {
+	// The alphanumeric mode symbol of this transport text does not decode
+	data := []byte(`{"id":341894,"name":"item-761","qty":46}`)
+	p := Base45Pipeline("HC1:")
+	result, err := EncodePipeline(data, p, nil)
+	if err != nil {
+		t.Fatalf("EncodePipeline failed: %v", err)
+	}
+	if err := VerifyPipeline(result.Image, data, p); err != nil {
+		t.Errorf("VerifyPipeline failed: %v", err)
+	}
+}
+
+func TestEncodePipelineTooLarge(t *testing.T) {
+	// Random data does not compress, and Base45 grows it by half beyond
+	// the alphanumeric limit
+	data := make([]byte, 2500)
+	rand.New(rand.NewSource(1)).Read(data)
+	if _, err := EncodePipeline(data, Base45Pipeline(""), nil); err == nil {
+		t.Error("Expected error for data too large")
+	}
+}
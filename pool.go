@@ -0,0 +1,92 @@
+package qrverify
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"sync"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// Pools for allocations repeated on every encode and verify.
+var (
+	readerPool = sync.Pool{New: func() any { return qrcode.NewQRCodeReader() }}
+	lumPool    = sync.Pool{New: func() any { return new([]byte) }}
+	grayPool   = sync.Pool{New: func() any { return new(image.Gray) }}
+	bufPool    = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+	pngEncoder = png.Encoder{BufferPool: &pngBufferPool{}}
+)
+
+// pngBufferPool implements png.EncoderBufferPool with a sync.Pool.
+type pngBufferPool struct {
+	pool sync.Pool
+}
+
+func (p *pngBufferPool) Get() *png.EncoderBuffer {
+	b, _ := p.pool.Get().(*png.EncoderBuffer)
+	return b
+}
+
+func (p *pngBufferPool) Put(b *png.EncoderBuffer) {
+	p.pool.Put(b)
+}
+
+// getReader returns a pooled QR code reader.
+func getReader() gozxing.Reader {
+	return readerPool.Get().(gozxing.Reader)
+}
+
+// putReader returns r to the pool.
+func putReader(r gozxing.Reader) {
+	readerPool.Put(r)
+}
+
+// getLum returns a pooled luminance buffer of length n.
+func getLum(n int) *[]byte {
+	p := lumPool.Get().(*[]byte)
+	if cap(*p) < n {
+		*p = make([]byte, n)
+	}
+	*p = (*p)[:n]
+	return p
+}
+
+// putLum returns p to the pool.
+func putLum(p *[]byte) {
+	lumPool.Put(p)
+}
+
+// getGray returns a pooled greyscale image with bounds r.
+// Pixel contents are undefined.
+func getGray(r image.Rectangle) *image.Gray {
+	img := grayPool.Get().(*image.Gray)
+	n := r.Dx() * r.Dy()
+	if cap(img.Pix) < n {
+		img.Pix = make([]byte, n)
+	}
+	img.Pix = img.Pix[:n]
+	img.Stride = r.Dx()
+	img.Rect = r
+	return img
+}
+
+// putGray returns img to the pool.
+func putGray(img *image.Gray) {
+	grayPool.Put(img)
+}
+
+// encodePNG encodes img as PNG into a newly allocated slice, reusing
+// pooled encoder and buffer state.
+func encodePNG(img image.Image) ([]byte, error) {
+	buf := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(buf)
+	buf.Reset()
+
+	if err := pngEncoder.Encode(buf, img); err != nil {
+		return nil, err
+	}
+
+	return bytes.Clone(buf.Bytes()), nil
+}
@@ -0,0 +1,282 @@
+package qrverify
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Sealed payload header layout.
+const (
+	sealedVersion       = 1
+	sealedFlagPassword  = 1 << 0 // Key derived from a passphrase; salt and iterations follow the key ID
+	sealedSaltSize      = 16
+	sealedCheckSize     = 4
+	sealedMaxIterations = 10_000_000
+)
+
+// DefaultSealIterations is the PBKDF2-HMAC-SHA256 iteration count used
+// for passphrase keys when SealKey.Iterations is zero.
+const DefaultSealIterations = 600_000
+
+// SealKey is the key for sealed payloads: either a raw AES key or a
+// passphrase.
+type SealKey struct {
+	ID  string // Stored in the header, printable ASCII without ':', optional
+	Key []byte // AES-128, AES-192 or AES-256 key
+
+	// Passphrase, if set, is used instead of Key. Each payload derives its
+	// key with PBKDF2-HMAC-SHA256 and a random salt.
+	Passphrase string
+
+	// Iterations is the PBKDF2 iteration count for new payloads.
+	// Zero value uses DefaultSealIterations.
+	Iterations int
+}
+
+// Sealed is a payload encrypted with AES-GCM. The binary form is a
+// versioned header with the key ID, a key check value and the nonce,
+// followed by the ciphertext; the header is authenticated as additional
+// data. It is stored as Base45 text, because decoders guess the character
+// set of raw byte mode data and cannot return arbitrary binary reliably.
+type Sealed struct {
+	Data []byte    // Plaintext
+	Key  *SealKey  // Required
+	Rand io.Reader // Source of nonces and salts, nil uses crypto/rand
+}
+
+// Encode returns the Base45 sealed payload. A fresh nonce is used for
+// every call, so the output differs each time.
+func (s *Sealed) Encode(limit int) (string, error) {
+	if s.Key == nil {
+		return "", errors.New("invalid sealed payload: key is required")
+	}
+	random := s.Rand
+	if random == nil {
+		random = rand.Reader
+	}
+
+	sealed, err := seal(s.Data, s.Key, random)
+	if err != nil {
+		return "", err
+	}
+	text, _ := Base45{}.Apply(sealed)
+	return checkLimit("sealed payload", string(text), limit)
+}
+
+// Check decrypts decoded with s.Key and compares the plaintext with s.Data.
+func (s *Sealed) Check(decoded string) error {
+	plaintext, err := OpenSealed(decoded, s.Key)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(plaintext, s.Data) {
+		return &VerificationError{Original: string(s.Data), Decoded: string(plaintext)}
+	}
+	return nil
+}
+
+// OpenSealed decrypts Base45 sealed payload text with key. It returns
+// *WrongKeyError if the payload was sealed with a different key and
+// *TamperError if the payload was modified.
+func OpenSealed(text string, key *SealKey) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New("invalid sealed payload: key is required")
+	}
+	data, err := Base45{}.Invert([]byte(text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid sealed payload: %w", err)
+	}
+
+	h, err := parseSealedHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if h.keyID != key.ID {
+		return nil, &WrongKeyError{KeyID: h.keyID}
+	}
+
+	aesKey, err := key.derive(h.salt, h.iterations)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(keyCheck(aesKey), h.check) {
+		return nil, &WrongKeyError{KeyID: h.keyID}
+	}
+
+	aead, err := newGCM(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, h.nonce, data[h.size:], data[:h.size])
+	if err != nil {
+		return nil, &TamperError{KeyID: h.keyID}
+	}
+	return plaintext, nil
+}
+
+// VerifySealed checks that qrImage (PNG bytes) holds a sealed payload
+// that decrypts and authenticates with key, and returns the plaintext.
+func VerifySealed(qrImage []byte, key *SealKey) ([]byte, error) {
+	img, err := png.Decode(bytes.NewReader(qrImage))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode PNG: %w", err)
+	}
+
+	decoded, err := decode(img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read QR code: %w", err)
+	}
+
+	return OpenSealed(decoded, key)
+}
+
+// seal encrypts plaintext and returns the header followed by the
+// ciphertext.
+func seal(plaintext []byte, key *SealKey, random io.Reader) ([]byte, error) {
+	if key.ID != "" {
+		if err := validateKeyID(key.ID); err != nil {
+			return nil, fmt.Errorf("invalid sealed payload: %w", err)
+		}
+	}
+
+	header := []byte{sealedVersion, 0, byte(len(key.ID))}
+	header = append(header, key.ID...)
+
+	var salt []byte
+	iterations := 0
+	if key.Passphrase != "" {
+		iterations = key.Iterations
+		if iterations == 0 {
+			iterations = DefaultSealIterations
+		}
+		if iterations < 1 || iterations > sealedMaxIterations {
+			return nil, fmt.Errorf("invalid sealed payload: iterations must be 1-%d", sealedMaxIterations)
+		}
+		salt = make([]byte, sealedSaltSize)
+		if _, err := io.ReadFull(random, salt); err != nil {
+			return nil, fmt.Errorf("sealed payload: %w", err)
+		}
+		header[1] |= sealedFlagPassword
+		header = append(header, salt...)
+		header = binary.BigEndian.AppendUint32(header, uint32(iterations))
+	}
+
+	aesKey, err := key.derive(salt, iterations)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(aesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header = append(header, keyCheck(aesKey)...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(random, nonce); err != nil {
+		return nil, fmt.Errorf("sealed payload: %w", err)
+	}
+	header = append(header, nonce...)
+
+	return aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// sealedHeader is a parsed sealed payload header.
+type sealedHeader struct {
+	keyID      string
+	salt       []byte // Nil for raw keys
+	iterations int
+	check      []byte
+	nonce      []byte
+	size       int // Header length in bytes
+}
+
+// parseSealedHeader parses the header at the start of data.
+func parseSealedHeader(data []byte) (*sealedHeader, error) {
+	if len(data) < 3 {
+		return nil, errors.New("invalid sealed payload: too short")
+	}
+	if data[0] != sealedVersion {
+		return nil, errors.New("invalid sealed payload: unsupported version")
+	}
+	flags := data[1]
+	if flags&^sealedFlagPassword != 0 {
+		return nil, errors.New("invalid sealed payload: unknown flags")
+	}
+
+	h := &sealedHeader{}
+	n := 3 + int(data[2])
+	if len(data) < n {
+		return nil, errors.New("invalid sealed payload: too short")
+	}
+	h.keyID = string(data[3:n])
+
+	if flags&sealedFlagPassword != 0 {
+		if len(data) < n+sealedSaltSize+4 {
+			return nil, errors.New("invalid sealed payload: too short")
+		}
+		h.salt = data[n : n+sealedSaltSize]
+		h.iterations = int(binary.BigEndian.Uint32(data[n+sealedSaltSize:]))
+		if h.iterations < 1 || h.iterations > sealedMaxIterations {
+			return nil, errors.New("invalid sealed payload: iteration count out of range")
+		}
+		n += sealedSaltSize + 4
+	}
+
+	const nonceSize = 12
+	if len(data) < n+sealedCheckSize+nonceSize+16 {
+		return nil, errors.New("invalid sealed payload: too short")
+	}
+	h.check = data[n : n+sealedCheckSize]
+	n += sealedCheckSize
+	h.nonce = data[n : n+nonceSize]
+	h.size = n + nonceSize
+	return h, nil
+}
+
+// derive returns the AES key: k.Key, or the PBKDF2 derivation of
+// k.Passphrase if salt is set.
+func (k *SealKey) derive(salt []byte, iterations int) ([]byte, error) {
+	if salt == nil {
+		if k.Passphrase != "" {
+			return nil, &WrongKeyError{KeyID: k.ID}
+		}
+		switch len(k.Key) {
+		case 16, 24, 32:
+			return k.Key, nil
+		default:
+			return nil, errors.New("invalid sealed payload: AES key must be 16, 24 or 32 bytes")
+		}
+	}
+	if k.Passphrase == "" {
+		return nil, &WrongKeyError{KeyID: k.ID}
+	}
+	return pbkdf2.Key([]byte(k.Passphrase), salt, iterations, 32, sha256.New), nil
+}
+
+// newGCM returns AES-GCM with the standard 12 byte nonce.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("sealed payload: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// keyCheck returns a short value identifying key without revealing it, so
+// a wrong key can be told apart from a modified payload.
+func keyCheck(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("qrverify sealed key check"))
+	return mac.Sum(nil)[:sealedCheckSize]
+}
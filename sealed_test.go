@@ -0,0 +1,169 @@
+package qrverify
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testSealKey returns a raw AES-256 key for tests.
+func testSealKey(id string, b byte) *SealKey {
+	return &SealKey{ID: id, Key: bytes.Repeat([]byte{b}, 32)}
+}
+
+func TestSealKeyDerive(t *testing.T) {
+	// Test vector from RFC 7914 section 11, first 32 bytes
+	k := &SealKey{Passphrase: "passwd"}
+	got, err := k.derive([]byte("salt"), 1)
+	if err != nil {
+		t.Fatalf("derive failed: %v", err)
+	}
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc"
+	if hex.EncodeToString(got) != want {
+		t.Errorf("derive() = %x, want %s", got, want)
+	}
+}
+
+func TestSealedRoundTrip(t *testing.T) {
+	keys := []*SealKey{
+		testSealKey("route-2026", 7),
+		{Key: bytes.Repeat([]byte{1}, 16)},
+		{ID: "pw", Passphrase: "correct horse battery staple", Iterations: 1000},
+	}
+	plaintext := []byte("dock=7;bay=C;priority=high")
+
+	for _, key := range keys {
+		s := &Sealed{Data: plaintext, Key: key}
+		text, err := s.Encode(MaxBytesMedium)
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		if strings.Contains(text, "dock") || strings.Trim(text, alphanumericChars) != "" {
+			t.Errorf("Encode() = %q, want alphanumeric ciphertext", text)
+		}
+
+		got, err := OpenSealed(text, key)
+		if err != nil {
+			t.Fatalf("OpenSealed failed: %v", err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("OpenSealed() = %q, want %q", got, plaintext)
+		}
+		if err := s.Check(text); err != nil {
+			t.Errorf("Check failed: %v", err)
+		}
+
+		again, _ := s.Encode(MaxBytesMedium)
+		if again == text {
+			t.Error("Expected a fresh nonce for each Encode")
+		}
+	}
+}
+
+func TestSealedEncodeInvalid(t *testing.T) {
+	invalid := []*Sealed{
+		{Data: []byte("x")},
+		{Data: []byte("x"), Key: &SealKey{Key: []byte("short")}},
+		{Data: []byte("x"), Key: &SealKey{ID: "a:b", Key: make([]byte, 32)}},
+		{Data: []byte("x"), Key: &SealKey{Passphrase: "pw", Iterations: -1}},
+	}
+	for _, s := range invalid {
+		if _, err := s.Encode(MaxBytesMedium); err == nil {
+			t.Errorf("Expected error for %+v", s.Key)
+		}
+	}
+}
+
+func TestOpenSealedErrors(t *testing.T) {
+	key := testSealKey("k1", 7)
+	raw, err := seal([]byte("secret routing data"), key, strings.NewReader(strings.Repeat("n", 64)))
+	if err != nil {
+		t.Fatalf("seal failed: %v", err)
+	}
+	encode := func(b []byte) string {
+		text, _ := Base45{}.Apply(b)
+		return string(text)
+	}
+
+	wrongKey := []struct {
+		name string
+		key  *SealKey
+	}{
+		{"different key", testSealKey("k1", 8)},
+		{"different key ID", testSealKey("k2", 7)},
+		{"passphrase instead of key", &SealKey{ID: "k1", Passphrase: "pw"}},
+	}
+	for _, tt := range wrongKey {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OpenSealed(encode(raw), tt.key)
+			var keyErr *WrongKeyError
+			if !errors.As(err, &keyErr) || keyErr.KeyID != "k1" {
+				t.Errorf("Expected WrongKeyError for k1, got: %v", err)
+			}
+		})
+	}
+
+	tampered := []struct {
+		name  string
+		index int // Byte to flip, negative counts from the end
+	}{
+		{"ciphertext", -20},
+		{"tag", -1},
+		{"nonce", 3 + 2 + sealedCheckSize},
+	}
+	for _, tt := range tampered {
+		t.Run(tt.name, func(t *testing.T) {
+			b := bytes.Clone(raw)
+			i := tt.index
+			if i < 0 {
+				i += len(b)
+			}
+			b[i] ^= 0x01
+
+			_, err := OpenSealed(encode(b), key)
+			var tamperErr *TamperError
+			if !errors.As(err, &tamperErr) {
+				t.Errorf("Expected TamperError, got: %v", err)
+			}
+			if strings.Contains(err.Error(), "secret") {
+				t.Errorf("Error() exposes plaintext: %s", err.Error())
+			}
+		})
+	}
+
+	malformed := []string{"", "A", "00", encode([]byte{9, 0, 0}), encode(raw[:20])}
+	for _, s := range malformed {
+		_, err := OpenSealed(s, key)
+		var keyErr *WrongKeyError
+		var tamperErr *TamperError
+		if err == nil || errors.As(err, &keyErr) || errors.As(err, &tamperErr) {
+			t.Errorf("Expected malformed payload error for %q, got: %v", s, err)
+		}
+	}
+}
+
+func TestVerifySealed(t *testing.T) {
+	key := testSealKey("ops", 3)
+	s := &Sealed{Data: []byte(`{"route":"A12","zone":4}`), Key: key}
+
+	result, err := EncodePayload(s, nil)
+	if err != nil {
+		t.Fatalf("EncodePayload failed: %v", err)
+	}
+
+	got, err := VerifySealed(result.Image, key)
+	if err != nil {
+		t.Fatalf("VerifySealed failed: %v", err)
+	}
+	if !bytes.Equal(got, s.Data) {
+		t.Errorf("VerifySealed() = %q, want %q", got, s.Data)
+	}
+
+	_, err = VerifySealed(result.Image, testSealKey("ops", 4))
+	var keyErr *WrongKeyError
+	if !errors.As(err, &keyErr) {
+		t.Errorf("Expected WrongKeyError, got: %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package qrverify
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"strings"
+)
+
+// signedPrefix marks a signed payload and its format version.
+const signedPrefix = "S1:"
+
+// maxKeyIDLength bounds key IDs so they stay compact.
+const maxKeyIDLength = 32
+
+// KeySet maps key IDs to the public keys trusted for signed payloads.
+type KeySet map[string]ed25519.PublicKey
+
+// SignedData is the authenticated content of a signed payload.
+type SignedData struct {
+	KeyID string // Key that signed Data
+	Data  string // Authenticated payload text
+}
+
+// Signed is a payload authenticated with an Ed25519 signature, encoded as
+// S1:<key ID>:<base64url signature>:<data>. The signature covers the key
+// ID and data, so neither can be changed without detection.
+type Signed struct {
+	Data  string             // Payload text
+	KeyID string             // Identifies Key in the verifier's KeySet, printable ASCII without ':'
+	Key   ed25519.PrivateKey // Signing key
+}
+
+// Encode returns the signed payload text. Ed25519 signatures are
+// deterministic, so the same input always encodes identically.
+func (s *Signed) Encode(limit int) (string, error) {
+	if err := validateKeyID(s.KeyID); err != nil {
+		return "", err
+	}
+	if len(s.Key) != ed25519.PrivateKeySize {
+		return "", errors.New("invalid signed payload: malformed private key")
+	}
+
+	sig := ed25519.Sign(s.Key, signedMessage(s.KeyID, s.Data))
+	text := signedPrefix + s.KeyID + ":" + base64.RawURLEncoding.EncodeToString(sig) + ":" + s.Data
+	return checkLimit("signed payload", text, limit)
+}
+
+// Check authenticates decoded with the public half of s.Key and compares
+// the key ID and data with s.
+func (s *Signed) Check(decoded string) error {
+	if len(s.Key) != ed25519.PrivateKeySize {
+		return errors.New("invalid signed payload: malformed private key")
+	}
+	got, err := OpenSigned(decoded, KeySet{s.KeyID: s.Key.Public().(ed25519.PublicKey)})
+	if err != nil {
+		return err
+	}
+	if err := checkField("Signed.KeyID", s.KeyID, got.KeyID); err != nil {
+		return err
+	}
+	return checkField("Signed.Data", s.Data, got.Data)
+}
+
+// OpenSigned authenticates signed payload text against keys. It returns
+// *UnknownKeyError if the key ID is not in keys and *SignatureError if the
+// signature does not match.
+func OpenSigned(s string, keys KeySet) (*SignedData, error) {
+	body, ok := strings.CutPrefix(s, signedPrefix)
+	if !ok {
+		return nil, errors.New("invalid signed payload: missing S1: prefix")
+	}
+	parts := strings.SplitN(body, ":", 3)
+	if len(parts) != 3 {
+		return nil, errors.New("invalid signed payload: missing fields")
+	}
+	keyID, encodedSig, data := parts[0], parts[1], parts[2]
+	if err := validateKeyID(keyID); err != nil {
+		return nil, err
+	}
+
+	key, ok := keys[keyID]
+	if !ok {
+		return nil, &UnknownKeyError{KeyID: keyID}
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid key set: malformed public key for %q", keyID)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || len(sig) != ed25519.SignatureSize || !ed25519.Verify(key, signedMessage(keyID, data), sig) {
+		return nil, &SignatureError{KeyID: keyID}
+	}
+	return &SignedData{KeyID: keyID, Data: data}, nil
+}
+
+// VerifySigned checks that qrImage (PNG bytes) holds a signed payload
+// issued by one of keys and returns its authenticated content.
+func VerifySigned(qrImage []byte, keys KeySet) (*SignedData, error) {
+	img, err := png.Decode(bytes.NewReader(qrImage))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode PNG: %w", err)
+	}
+
+	decoded, err := decode(img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read QR code: %w", err)
+	}
+
+	return OpenSigned(decoded, keys)
+}
+
+// signedMessage returns the bytes covered by the signature. The key ID is
+// included so a signature cannot be replayed under another key ID.
+func signedMessage(keyID, data string) []byte {
+	return []byte(signedPrefix + keyID + ":" + data)
+}
+
+// validateKeyID checks id is 1 to 32 printable ASCII characters without ':'.
+func validateKeyID(id string) error {
+	if id == "" || len(id) > maxKeyIDLength {
+		return fmt.Errorf("invalid signed payload: key ID must be 1-%d characters", maxKeyIDLength)
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' || id[i] == ':' {
+			return errors.New("invalid signed payload: key ID must be printable ASCII without ':'")
+		}
+	}
+	return nil
+}
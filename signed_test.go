@@ -0,0 +1,147 @@
+package qrverify
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testSigningKey returns a deterministic key pair for tests.
+func testSigningKey(seed byte) (ed25519.PublicKey, ed25519.PrivateKey) {
+	priv := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+	return priv.Public().(ed25519.PublicKey), priv
+}
+
+func TestSignedEncode(t *testing.T) {
+	pub, priv := testSigningKey(1)
+	s := &Signed{Data: "ticket:1234:row=5:seat=12", KeyID: "2026-a", Key: priv}
+
+	text, err := s.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.HasPrefix(text, "S1:2026-a:") || !strings.HasSuffix(text, ":ticket:1234:row=5:seat=12") {
+		t.Errorf("Encode() = %q", text)
+	}
+
+	again, _ := s.Encode(MaxBytesMedium)
+	if again != text {
+		t.Error("Encode is not deterministic")
+	}
+
+	got, err := OpenSigned(text, KeySet{"2026-a": pub})
+	if err != nil {
+		t.Fatalf("OpenSigned failed: %v", err)
+	}
+	if got.KeyID != s.KeyID || got.Data != s.Data {
+		t.Errorf("OpenSigned() = %+v", got)
+	}
+	if err := s.Check(text); err != nil {
+		t.Errorf("Check failed: %v", err)
+	}
+}
+
+func TestSignedEncodeInvalid(t *testing.T) {
+	_, priv := testSigningKey(1)
+	invalid := []Signed{
+		{Data: "x", Key: priv},
+		{Data: "x", KeyID: "a:b", Key: priv},
+		{Data: "x", KeyID: "a b", Key: priv},
+		{Data: "x", KeyID: strings.Repeat("k", 33), Key: priv},
+		{Data: "x", KeyID: "k", Key: priv[:10]},
+	}
+	for _, s := range invalid {
+		if _, err := s.Encode(MaxBytesMedium); err == nil {
+			t.Errorf("Expected error for key ID %q", s.KeyID)
+		}
+	}
+}
+
+func TestOpenSignedErrors(t *testing.T) {
+	pub, priv := testSigningKey(1)
+	otherPub, otherPriv := testSigningKey(2)
+	keys := KeySet{"k1": pub, "k2": otherPub}
+
+	text, err := (&Signed{Data: "amount=10", KeyID: "k1", Key: priv}).Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	sig := strings.Split(text, ":")[2]
+
+	tampered := []struct {
+		name string
+		text string
+	}{
+		{"data changed", strings.Replace(text, "amount=10", "amount=99", 1)},
+		{"key ID swapped", strings.Replace(text, "S1:k1:", "S1:k2:", 1)},
+		{"signature changed", strings.Replace(text, sig, strings.Repeat("A", len(sig)), 1)},
+		{"signature truncated", strings.Replace(text, sig, sig[:20], 1)},
+	}
+	for _, tt := range tampered {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OpenSigned(tt.text, keys)
+			var sigErr *SignatureError
+			if !errors.As(err, &sigErr) {
+				t.Errorf("Expected SignatureError, got: %v", err)
+			}
+			if strings.Contains(err.Error(), "amount") {
+				t.Errorf("Error() exposes data: %s", err.Error())
+			}
+		})
+	}
+
+	// Signed by a key the verifier does not trust
+	foreign, err := (&Signed{Data: "amount=10", KeyID: "k3", Key: otherPriv}).Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	_, err = OpenSigned(foreign, keys)
+	var keyErr *UnknownKeyError
+	if !errors.As(err, &keyErr) || keyErr.KeyID != "k3" {
+		t.Errorf("Expected UnknownKeyError for k3, got: %v", err)
+	}
+
+	for _, s := range []string{"", "amount=10", "S1:k1", "S1::sig:data"} {
+		_, err := OpenSigned(s, keys)
+		var sigErr *SignatureError
+		if err == nil || errors.As(err, &sigErr) || errors.As(err, &keyErr) {
+			t.Errorf("Expected malformed payload error for %q, got: %v", s, err)
+		}
+	}
+}
+
+func TestVerifySigned(t *testing.T) {
+	pub, priv := testSigningKey(1)
+	s := &Signed{Data: "https://example.com/asset/42", KeyID: "issuer", Key: priv}
+
+	result, err := EncodePayload(s, nil)
+	if err != nil {
+		t.Fatalf("EncodePayload failed: %v", err)
+	}
+
+	got, err := VerifySigned(result.Image, KeySet{"issuer": pub})
+	if err != nil {
+		t.Fatalf("VerifySigned failed: %v", err)
+	}
+	if got.Data != s.Data || got.KeyID != "issuer" {
+		t.Errorf("VerifySigned() = %+v", got)
+	}
+
+	otherPub, _ := testSigningKey(2)
+	_, err = VerifySigned(result.Image, KeySet{"issuer": otherPub})
+	var sigErr *SignatureError
+	if !errors.As(err, &sigErr) {
+		t.Errorf("Expected SignatureError for wrong public key, got: %v", err)
+	}
+
+	// An unsigned code is not authenticated
+	plain, err := Encode(s.Data, nil)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if _, err := VerifySigned(plain, KeySet{"issuer": pub}); err == nil {
+		t.Error("Expected VerifySigned to fail for unsigned code")
+	}
+}
@@ -0,0 +1,332 @@
+package qrverify
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxCharsSwissQRBill is the maximum length of a Swiss QR-bill payload.
+const MaxCharsSwissQRBill = 997
+
+// SwissAddress is a structured (type S) address in a Swiss QR-bill.
+type SwissAddress struct {
+	Name           string // Required, at most 70 characters
+	Street         string // At most 70 characters
+	BuildingNumber string // At most 16 characters
+	PostalCode     string // Required, at most 16 characters
+	Town           string // Required, at most 35 characters
+	Country        string // Required ISO 3166-1 alpha-2 code
+}
+
+// isZero reports whether no address fields are set.
+func (a SwissAddress) isZero() bool {
+	return a == SwissAddress{}
+}
+
+// lines returns the seven address lines, all empty for the zero address.
+func (a SwissAddress) lines() []string {
+	if a.isZero() {
+		return make([]string, 7)
+	}
+	return []string{"S", a.Name, a.Street, a.BuildingNumber, a.PostalCode, a.Town, a.Country}
+}
+
+// validate checks a against the QR-bill address constraints.
+func (a SwissAddress) validate(role string) error {
+	if a.Name == "" || a.PostalCode == "" || a.Town == "" {
+		return fmt.Errorf("%s name, postal code and town are required", role)
+	}
+	if len(a.Country) != 2 || !isUpperAlpha(a.Country) {
+		return fmt.Errorf("%s country must be a two letter code", role)
+	}
+	limits := []struct {
+		value string
+		max   int
+	}{
+		{a.Name, 70}, {a.Street, 70}, {a.BuildingNumber, 16}, {a.PostalCode, 16}, {a.Town, 35},
+	}
+	for _, l := range limits {
+		if utf8.RuneCountInString(l.value) > l.max {
+			return fmt.Errorf("%s field exceeds %d characters", role, l.max)
+		}
+	}
+	return nil
+}
+
+// SwissQRBill is a Swiss Payments Code (SPC) payload for a QR-bill.
+// The reference type is derived from IBAN and Reference: a QR-IBAN
+// requires a QR reference (QRR), an RF creditor reference is SCOR, and
+// no reference is NON. The standard mandates Medium recovery and a
+// Swiss cross in the center, which EncodePayload draws.
+type SwissQRBill struct {
+	IBAN        string       // Creditor IBAN or QR-IBAN (CH or LI); spaces are ignored
+	Creditor    SwissAddress // Required
+	AmountCents int64        // Amount in cents, zero leaves the amount open
+	Currency    string       // CHF or EUR
+	Debtor      SwissAddress // Optional ultimate debtor
+	Reference   string       // 27 digit QR reference or RF creditor reference
+	Message     string       // Unstructured message, at most 140 characters
+	BillInfo    string       // Structured billing information, at most 140 characters
+}
+
+// RequiredRecovery returns Medium, the level mandated for QR-bills.
+func (b *SwissQRBill) RequiredRecovery() Recovery {
+	return Medium
+}
+
+// referenceType returns QRR, SCOR or NON for b.Reference.
+func (b *SwissQRBill) referenceType() string {
+	switch {
+	case b.Reference == "":
+		return "NON"
+	case strings.HasPrefix(b.Reference, "RF"):
+		return "SCOR"
+	default:
+		return "QRR"
+	}
+}
+
+// Encode returns the SPC payload.
+func (b *SwissQRBill) Encode(limit int) (string, error) {
+	if err := b.validate(); err != nil {
+		return "", err
+	}
+
+	amount := ""
+	if b.AmountCents > 0 {
+		amount = formatCents(b.AmountCents)
+	}
+
+	lines := []string{"SPC", "0200", "1", normalizeIBAN(b.IBAN)}
+	lines = append(lines, b.Creditor.lines()...)
+	lines = append(lines, make([]string, 7)...) // Ultimate creditor, reserved
+	lines = append(lines, amount, b.Currency)
+	lines = append(lines, b.Debtor.lines()...)
+	lines = append(lines, b.referenceType(), b.Reference, b.Message, "EPD")
+	if b.BillInfo != "" {
+		lines = append(lines, b.BillInfo)
+	}
+
+	s := strings.Join(lines, "\n")
+	if n := utf8.RuneCountInString(s); n > MaxCharsSwissQRBill {
+		return "", fmt.Errorf("data too large: QR-bill is %d characters, exceeds %d character limit",
+			n, MaxCharsSwissQRBill)
+	}
+	return checkLimit("QR-bill", s, limit)
+}
+
+// Check parses decoded as an SPC payload and compares its fields with b.
+func (b *SwissQRBill) Check(decoded string) error {
+	got, err := ParseSwissQRBill(decoded)
+	if err != nil {
+		return err
+	}
+
+	fields := []struct {
+		name           string
+		original, read string
+	}{
+		{"SwissQRBill.IBAN", normalizeIBAN(b.IBAN), got.IBAN},
+		{"SwissQRBill.AmountCents", strconv.FormatInt(b.AmountCents, 10), strconv.FormatInt(got.AmountCents, 10)},
+		{"SwissQRBill.Currency", b.Currency, got.Currency},
+		{"SwissQRBill.Reference", b.Reference, got.Reference},
+		{"SwissQRBill.Message", b.Message, got.Message},
+		{"SwissQRBill.BillInfo", b.BillInfo, got.BillInfo},
+	}
+	for _, f := range fields {
+		if err := checkField(f.name, f.original, f.read); err != nil {
+			return err
+		}
+	}
+	if err := checkList("SwissQRBill.Creditor", b.Creditor.lines(), got.Creditor.lines()); err != nil {
+		return err
+	}
+	return checkList("SwissQRBill.Debtor", b.Debtor.lines(), got.Debtor.lines())
+}
+
+// validate checks b against the QR-bill field constraints.
+func (b *SwissQRBill) validate() error {
+	iban := normalizeIBAN(b.IBAN)
+	if err := validateIBAN(iban); err != nil {
+		return fmt.Errorf("invalid QR-bill: %w", err)
+	}
+	if !strings.HasPrefix(iban, "CH") && !strings.HasPrefix(iban, "LI") {
+		return errors.New("invalid QR-bill: IBAN must be a CH or LI account")
+	}
+	if err := b.Creditor.validate("creditor"); err != nil {
+		return fmt.Errorf("invalid QR-bill: %w", err)
+	}
+	if !b.Debtor.isZero() {
+		if err := b.Debtor.validate("debtor"); err != nil {
+			return fmt.Errorf("invalid QR-bill: %w", err)
+		}
+	}
+	if b.AmountCents < 0 || b.AmountCents > 99999999999 {
+		return errors.New("invalid QR-bill: amount must be between 0.01 and 999999999.99")
+	}
+	if b.Currency != "CHF" && b.Currency != "EUR" {
+		return errors.New("invalid QR-bill: currency must be CHF or EUR")
+	}
+
+	switch b.referenceType() {
+	case "QRR":
+		if !isQRIBAN(iban) {
+			return errors.New("invalid QR-bill: QR reference requires a QR-IBAN")
+		}
+		if err := validateQRReference(b.Reference); err != nil {
+			return fmt.Errorf("invalid QR-bill: %w", err)
+		}
+	case "SCOR":
+		if isQRIBAN(iban) {
+			return errors.New("invalid QR-bill: QR-IBAN requires a QR reference")
+		}
+		if err := validateCreditorReference(b.Reference); err != nil {
+			return fmt.Errorf("invalid QR-bill: %w", err)
+		}
+	case "NON":
+		if isQRIBAN(iban) {
+			return errors.New("invalid QR-bill: QR-IBAN requires a QR reference")
+		}
+	}
+
+	if utf8.RuneCountInString(b.Message) > 140 || utf8.RuneCountInString(b.BillInfo) > 140 {
+		return errors.New("invalid QR-bill: message and billing information are limited to 140 characters")
+	}
+
+	texts := []string{b.Message, b.BillInfo, b.Reference}
+	texts = append(texts, b.Creditor.lines()...)
+	texts = append(texts, b.Debtor.lines()...)
+	for _, t := range texts {
+		if !isSwissCharset(t) {
+			return errors.New("invalid QR-bill: text contains characters outside the permitted character set")
+		}
+	}
+	return nil
+}
+
+// ParseSwissQRBill parses an SPC payload.
+func ParseSwissQRBill(s string) (*SwissQRBill, error) {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if len(lines) < 31 || len(lines) > 34 {
+		return nil, errors.New("invalid QR-bill: unexpected number of lines")
+	}
+	if lines[0] != "SPC" || lines[1] != "0200" || lines[2] != "1" {
+		return nil, errors.New("invalid QR-bill: unsupported header")
+	}
+	if lines[30] != "EPD" {
+		return nil, errors.New("invalid QR-bill: missing EPD trailer")
+	}
+
+	address := func(l []string) (SwissAddress, error) {
+		switch l[0] {
+		case "":
+			return SwissAddress{}, nil
+		case "S":
+			return SwissAddress{Name: l[1], Street: l[2], BuildingNumber: l[3], PostalCode: l[4], Town: l[5], Country: l[6]}, nil
+		default:
+			return SwissAddress{}, errors.New("invalid QR-bill: unsupported address type")
+		}
+	}
+
+	b := &SwissQRBill{IBAN: lines[3], Currency: lines[19], Reference: lines[28], Message: lines[29]}
+	var err error
+	if b.Creditor, err = address(lines[4:11]); err != nil {
+		return nil, err
+	}
+	if b.Debtor, err = address(lines[20:27]); err != nil {
+		return nil, err
+	}
+	if lines[18] != "" {
+		if b.AmountCents, err = parseCents(lines[18]); err != nil {
+			return nil, fmt.Errorf("invalid QR-bill: %w", err)
+		}
+	}
+	if len(lines) > 31 {
+		b.BillInfo = lines[31]
+	}
+
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	if b.referenceType() != lines[27] {
+		return nil, errors.New("invalid QR-bill: reference type does not match reference")
+	}
+	return b, nil
+}
+
+// isQRIBAN reports whether iban is a QR-IBAN, identified by an institution
+// ID in the range 30000-31999.
+func isQRIBAN(iban string) bool {
+	if len(iban) < 9 {
+		return false
+	}
+	iid, err := strconv.Atoi(iban[4:9])
+	return err == nil && iid >= 30000 && iid <= 31999
+}
+
+// validateQRReference checks a 27 digit QR reference and its recursive
+// modulo 10 check digit.
+func validateQRReference(ref string) error {
+	if len(ref) != 27 || !isDigits(ref) {
+		return errors.New("QR reference must be 27 digits")
+	}
+	if mod10Recursive(ref[:26]) != int(ref[26]-'0') {
+		return errors.New("QR reference check digit mismatch")
+	}
+	return nil
+}
+
+// mod10Recursive returns the recursive modulo 10 check digit of digits.
+func mod10Recursive(digits string) int {
+	table := [10]int{0, 9, 4, 6, 8, 2, 7, 1, 3, 5}
+	carry := 0
+	for _, r := range digits {
+		carry = table[(carry+int(r-'0'))%10]
+	}
+	return (10 - carry) % 10
+}
+
+// isSwissCharset reports whether s only uses the QR-bill character set:
+// printable Basic Latin, Latin-1 Supplement and Latin Extended-A.
+func isSwissCharset(s string) bool {
+	for _, r := range s {
+		if (r < 0x20 || r > 0x7e) && (r < 0xa0 || r > 0x17f) {
+			return false
+		}
+	}
+	return utf8.ValidString(s)
+}
+
+// Decorate draws the Swiss cross in the center of the symbol, scaled from
+// the 7 x 7 mm logo on the 46 x 46 mm QR-bill code.
+func (b *SwissQRBill) Decorate(img *image.Gray, symbol image.Rectangle) {
+	side := symbol.Dx() * 7 / 46
+	c := image.Pt((symbol.Min.X+symbol.Max.X)/2, (symbol.Min.Y+symbol.Max.Y)/2)
+	square := func(size int) image.Rectangle {
+		return image.Rect(c.X-size/2, c.Y-size/2, c.X-size/2+size, c.Y-size/2+size)
+	}
+
+	// White border, black square, then a white cross with arms 6/32 wide
+	// and 20/32 long as in the Swiss flag.
+	fillGray(img, square(side), 0xff)
+	inner := square(side * 12 / 14)
+	fillGray(img, inner, 0x00)
+	arm, length := inner.Dx()*6/32, inner.Dx()*20/32
+	fillGray(img, image.Rect(c.X-arm/2, c.Y-length/2, c.X-arm/2+arm, c.Y-length/2+length), 0xff)
+	fillGray(img, image.Rect(c.X-length/2, c.Y-arm/2, c.X-length/2+length, c.Y-arm/2+arm), 0xff)
+}
+
+// fillGray sets every pixel of r in img to v.
+func fillGray(img *image.Gray, r image.Rectangle, v uint8) {
+	r = r.Intersect(img.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(r.Min.X, y):img.PixOffset(r.Max.X, y)]
+		for i := range row {
+			row[i] = v
+		}
+	}
+}
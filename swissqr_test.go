@@ -0,0 +1,173 @@
+package qrverify
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// testSwissQRBill returns a QR-bill using a QR-IBAN and QR reference.
+func testSwissQRBill() SwissQRBill {
+	return SwissQRBill{
+		IBAN: "CH44 3199 9123 0008 8901 2",
+		Creditor: SwissAddress{
+			Name:           "Robert Schneider AG",
+			Street:         "Rue du Lac",
+			BuildingNumber: "1268",
+			PostalCode:     "2501",
+			Town:           "Biel",
+			Country:        "CH",
+		},
+		AmountCents: 194975,
+		Currency:    "CHF",
+		Debtor: SwissAddress{
+			Name:       "Pia-Maria Rutschmann-Schnyder",
+			Street:     "Grosse Marktgasse",
+			PostalCode: "9400",
+			Town:       "Rorschach",
+			Country:    "CH",
+		},
+		Reference: "210000000003139471430009017",
+		Message:   "Auftrag vom 15.06.2020",
+	}
+}
+
+func TestSwissQRBillEncode(t *testing.T) {
+	b := testSwissQRBill()
+
+	text, err := b.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	lines := strings.Split(text, "\n")
+	if len(lines) != 31 {
+		t.Fatalf("Expected 31 lines, got %d", len(lines))
+	}
+	if lines[3] != "CH4431999123000889012" || lines[18] != "1949.75" || lines[27] != "QRR" || lines[30] != "EPD" {
+		t.Errorf("Unexpected payload: %q", text)
+	}
+
+	parsed, err := ParseSwissQRBill(text)
+	if err != nil {
+		t.Fatalf("ParseSwissQRBill failed: %v", err)
+	}
+	b.IBAN = normalizeIBAN(b.IBAN)
+	if *parsed != b {
+		t.Errorf("ParseSwissQRBill() = %+v, want %+v", *parsed, b)
+	}
+}
+
+func TestSwissQRBillEncodeLimit(t *testing.T) {
+	b := testSwissQRBill()
+
+	// The byte limit of the symbol is below the QR-bill character limit
+	_, err := b.Encode(100)
+	if err == nil || !strings.Contains(err.Error(), "100 byte limit") {
+		t.Errorf("Expected byte limit error, got: %v", err)
+	}
+}
+
+func TestSwissQRBillReferenceTypes(t *testing.T) {
+	b := testSwissQRBill()
+	b.IBAN = "CH9300762011623852957"
+	b.Reference = "RF18539007547034"
+	b.Debtor = SwissAddress{}
+	b.AmountCents = 0
+	b.BillInfo = "//S1/10/10201409/11/200701"
+
+	text, err := b.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.Contains(text, "\nSCOR\nRF18539007547034\n") {
+		t.Errorf("Expected SCOR reference, got %q", text)
+	}
+	if err := b.Check(text); err != nil {
+		t.Errorf("Check failed: %v", err)
+	}
+
+	b.Reference = ""
+	text, err = b.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.Contains(text, "\nNON\n") {
+		t.Errorf("Expected NON reference, got %q", text)
+	}
+}
+
+func TestSwissQRBillInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(b *SwissQRBill)
+	}{
+		{"non-Swiss IBAN", func(b *SwissQRBill) { b.IBAN = "DE89370400440532013000" }},
+		{"QR-IBAN without QR reference", func(b *SwissQRBill) { b.Reference = "" }},
+		{"QR reference with normal IBAN", func(b *SwissQRBill) { b.IBAN = "CH9300762011623852957" }},
+		{"bad QR reference check digit", func(b *SwissQRBill) { b.Reference = "210000000003139471430009018" }},
+		{"bad currency", func(b *SwissQRBill) { b.Currency = "USD" }},
+		{"missing creditor town", func(b *SwissQRBill) { b.Creditor.Town = "" }},
+		{"bad country", func(b *SwissQRBill) { b.Creditor.Country = "Switzerland" }},
+		{"message too long", func(b *SwissQRBill) { b.Message = strings.Repeat("x", 141) }},
+		{"invalid character", func(b *SwissQRBill) { b.Message = "Danke 🙏" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := testSwissQRBill()
+			tt.modify(&b)
+			if _, err := b.Encode(MaxBytesMedium); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestSwissQRBillDecorate(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 460, 460))
+	symbol := img.Rect
+	b := testSwissQRBill()
+	b.Decorate(img, symbol)
+
+	// 7/46 of 460 pixels is a 70 pixel logo centered at (230, 230)
+	if img.GrayAt(230, 230).Y != 0xff {
+		t.Error("Expected white cross at center")
+	}
+	if img.GrayAt(200, 200).Y != 0x00 {
+		t.Error("Expected black square around cross")
+	}
+	if img.GrayAt(196, 196).Y != 0xff {
+		t.Error("Expected white border around square")
+	}
+	if img.GrayAt(190, 190).Y != 0x00 {
+		t.Error("Pixels outside the logo should not change")
+	}
+}
+
+func TestEncodePayloadSwissQRBill(t *testing.T) {
+	b := testSwissQRBill()
+
+	result, err := EncodePayload(&b, &EncodeOptions{Size: 512})
+	if err != nil {
+		t.Fatalf("EncodePayload failed: %v", err)
+	}
+	if result.Recovery != Medium {
+		t.Errorf("Recovery = %v, want Medium", result.Recovery)
+	}
+	if err := VerifyPayload(result.Image, &b); err != nil {
+		t.Errorf("VerifyPayload failed: %v", err)
+	}
+
+	// The cross is present in the returned image
+	img, err := png.Decode(bytes.NewReader(result.Image))
+	if err != nil {
+		t.Fatalf("failed to decode PNG: %v", err)
+	}
+	r, _, _, _ := img.At(256, 256).RGBA()
+	if r != 0xffff {
+		t.Error("Expected white cross at image center")
+	}
+}
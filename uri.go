@@ -0,0 +1,412 @@
+package qrverify
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Geo is a geographic location in the geo: URI format (RFC 5870).
+type Geo struct {
+	Latitude  float64 // Degrees, -90 to 90
+	Longitude float64 // Degrees, -180 to 180
+	Query     string  // Optional place name or search, as used by Android maps
+}
+
+// Encode returns the geo: URI.
+func (g *Geo) Encode(limit int) (string, error) {
+	if math.IsNaN(g.Latitude) || g.Latitude < -90 || g.Latitude > 90 {
+		return "", errors.New("invalid geo payload: latitude must be between -90 and 90")
+	}
+	if math.IsNaN(g.Longitude) || g.Longitude < -180 || g.Longitude > 180 {
+		return "", errors.New("invalid geo payload: longitude must be between -180 and 180")
+	}
+
+	s := "geo:" + formatDegrees(g.Latitude) + "," + formatDegrees(g.Longitude)
+	if g.Query != "" {
+		s += "?q=" + uriEscape(g.Query)
+	}
+	return checkLimit("geo URI", s, limit)
+}
+
+// Check parses decoded as a geo: URI and compares its fields with g.
+func (g *Geo) Check(decoded string) error {
+	got, err := ParseGeo(decoded)
+	if err != nil {
+		return err
+	}
+	if err := checkField("Geo.Latitude", formatDegrees(g.Latitude), formatDegrees(got.Latitude)); err != nil {
+		return err
+	}
+	if err := checkField("Geo.Longitude", formatDegrees(g.Longitude), formatDegrees(got.Longitude)); err != nil {
+		return err
+	}
+	return checkField("Geo.Query", g.Query, got.Query)
+}
+
+// formatDegrees formats v with the fewest digits that parse back to v.
+func formatDegrees(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// ParseGeo parses a geo: URI. Altitude and parameters other than q are
+// ignored.
+func ParseGeo(s string) (*Geo, error) {
+	body, ok := cutScheme(s, "geo")
+	if !ok {
+		return nil, errors.New("invalid geo payload: missing geo: scheme")
+	}
+	coords, query, _ := strings.Cut(body, "?")
+	coords, _, _ = strings.Cut(coords, ";")
+
+	parts := strings.Split(coords, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, errors.New("invalid geo payload: malformed coordinates")
+	}
+	g := &Geo{}
+	var err error
+	if g.Latitude, err = strconv.ParseFloat(parts[0], 64); err != nil {
+		return nil, errors.New("invalid geo payload: malformed latitude")
+	}
+	if g.Longitude, err = strconv.ParseFloat(parts[1], 64); err != nil {
+		return nil, errors.New("invalid geo payload: malformed longitude")
+	}
+
+	if query != "" {
+		q, err := url.ParseQuery(query)
+		if err != nil {
+			return nil, errors.New("invalid geo payload: malformed query")
+		}
+		g.Query = q.Get("q")
+	}
+	return g, nil
+}
+
+// Phone is a telephone number in the tel: URI format (RFC 3966).
+type Phone struct {
+	Number string // Digits with optional leading +; spaces and -.() are visual separators
+}
+
+// Encode returns the tel: URI with spaces removed.
+func (p *Phone) Encode(limit int) (string, error) {
+	if err := validatePhone(p.Number); err != nil {
+		return "", fmt.Errorf("invalid phone payload: %w", err)
+	}
+	return checkLimit("tel URI", "tel:"+strings.ReplaceAll(p.Number, " ", ""), limit)
+}
+
+// Check parses decoded as a tel: URI and compares the dialable digits with p.
+func (p *Phone) Check(decoded string) error {
+	got, err := ParsePhone(decoded)
+	if err != nil {
+		return err
+	}
+	return checkField("Phone.Number", dialable(p.Number), dialable(got.Number))
+}
+
+// ParsePhone parses a tel: URI. URI parameters such as ;ext= are ignored.
+func ParsePhone(s string) (*Phone, error) {
+	body, ok := cutScheme(s, "tel")
+	if !ok {
+		return nil, errors.New("invalid phone payload: missing tel: scheme")
+	}
+	number, _, _ := strings.Cut(body, ";")
+	number, err := url.PathUnescape(number)
+	if err != nil {
+		return nil, errors.New("invalid phone payload: malformed escape")
+	}
+	if err := validatePhone(number); err != nil {
+		return nil, fmt.Errorf("invalid phone payload: %w", err)
+	}
+	return &Phone{Number: number}, nil
+}
+
+// validatePhone checks number has at least one digit and only digits,
+// visual separators and an optional leading +.
+func validatePhone(number string) error {
+	digits := strings.TrimPrefix(number, "+")
+	if strings.Trim(digits, "0123456789 -.()") != "" || dialable(digits) == "" {
+		return errors.New("number may only contain digits, a leading + and -.() separators")
+	}
+	return nil
+}
+
+// dialable returns number with visual separators removed.
+func dialable(number string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '+' || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, number)
+}
+
+// SMSFormat specifies the text format of an SMS payload.
+type SMSFormat int
+
+const (
+	SMSTO  SMSFormat = iota // SMSTO:<number>:<message> (default)
+	SMSURI                  // sms:<number>?body=<message> (RFC 5724)
+)
+
+// SMS is a text message payload.
+type SMS struct {
+	Number  string // Recipient, required
+	Message string // Optional body
+	Format  SMSFormat
+}
+
+// Encode returns the SMS payload in s.Format.
+func (s *SMS) Encode(limit int) (string, error) {
+	if err := validatePhone(s.Number); err != nil {
+		return "", fmt.Errorf("invalid SMS payload: %w", err)
+	}
+	number := strings.ReplaceAll(s.Number, " ", "")
+
+	var text string
+	switch s.Format {
+	case SMSTO:
+		text = "SMSTO:" + number + ":" + s.Message
+	case SMSURI:
+		text = "sms:" + number
+		if s.Message != "" {
+			text += "?body=" + uriEscape(s.Message)
+		}
+	default:
+		return "", fmt.Errorf("invalid SMS payload: unknown format %d", s.Format)
+	}
+	return checkLimit("SMS payload", text, limit)
+}
+
+// Check parses decoded as an SMS payload and compares its fields with s.
+func (s *SMS) Check(decoded string) error {
+	got, err := ParseSMS(decoded)
+	if err != nil {
+		return err
+	}
+	if err := checkField("SMS.Number", dialable(s.Number), dialable(got.Number)); err != nil {
+		return err
+	}
+	return checkField("SMS.Message", s.Message, got.Message)
+}
+
+// ParseSMS parses an SMSTO: or sms: payload.
+func ParseSMS(s string) (*SMS, error) {
+	sms := &SMS{}
+	if body, ok := cutScheme(s, "smsto"); ok {
+		sms.Number, sms.Message, _ = strings.Cut(body, ":")
+	} else if body, ok := cutScheme(s, "sms"); ok {
+		sms.Format = SMSURI
+		number, query, _ := strings.Cut(body, "?")
+		// Only the first of several comma-separated recipients is kept
+		number, _, _ = strings.Cut(number, ",")
+		var err error
+		if sms.Number, err = url.PathUnescape(number); err != nil {
+			return nil, errors.New("invalid SMS payload: malformed escape")
+		}
+		q, err := url.ParseQuery(query)
+		if err != nil {
+			return nil, errors.New("invalid SMS payload: malformed query")
+		}
+		sms.Message = q.Get("body")
+	} else {
+		return nil, errors.New("invalid SMS payload: missing SMSTO: or sms: scheme")
+	}
+
+	if err := validatePhone(sms.Number); err != nil {
+		return nil, fmt.Errorf("invalid SMS payload: %w", err)
+	}
+	return sms, nil
+}
+
+// Email is a message draft in the mailto: URI format (RFC 6068).
+type Email struct {
+	To      string // Recipient address, required
+	Subject string
+	Body    string // Line breaks are encoded as CRLF
+}
+
+// Encode returns the mailto: URI.
+func (e *Email) Encode(limit int) (string, error) {
+	if err := validateEmail(e.To); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("mailto:")
+	b.WriteString(url.PathEscape(e.To))
+	sep := "?"
+	if e.Subject != "" {
+		b.WriteString(sep + "subject=" + uriEscape(e.Subject))
+		sep = "&"
+	}
+	if e.Body != "" {
+		body := strings.ReplaceAll(strings.ReplaceAll(e.Body, "\r\n", "\n"), "\n", "\r\n")
+		b.WriteString(sep + "body=" + uriEscape(body))
+	}
+	return checkLimit("mailto URI", b.String(), limit)
+}
+
+// Check parses decoded as a mailto: URI and compares its fields with e.
+// Addresses compare case-insensitively; body line endings are normalized.
+func (e *Email) Check(decoded string) error {
+	got, err := ParseEmail(decoded)
+	if err != nil {
+		return err
+	}
+	if err := checkField("Email.To", strings.ToLower(e.To), strings.ToLower(got.To)); err != nil {
+		return err
+	}
+	if err := checkField("Email.Subject", e.Subject, got.Subject); err != nil {
+		return err
+	}
+	normalize := func(s string) string {
+		return strings.ReplaceAll(s, "\r\n", "\n")
+	}
+	return checkField("Email.Body", normalize(e.Body), normalize(got.Body))
+}
+
+// ParseEmail parses a mailto: URI. Only the first recipient is kept.
+func ParseEmail(s string) (*Email, error) {
+	body, ok := cutScheme(s, "mailto")
+	if !ok {
+		return nil, errors.New("invalid email payload: missing mailto: scheme")
+	}
+	to, query, _ := strings.Cut(body, "?")
+	to, _, _ = strings.Cut(to, ",")
+
+	e := &Email{}
+	var err error
+	if e.To, err = url.PathUnescape(to); err != nil {
+		return nil, errors.New("invalid email payload: malformed escape")
+	}
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, errors.New("invalid email payload: malformed query")
+	}
+	if e.To == "" {
+		e.To = q.Get("to")
+	}
+	e.Subject = q.Get("subject")
+	e.Body = q.Get("body")
+
+	if err := validateEmail(e.To); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// validateEmail checks addr has the shape local@domain.
+func validateEmail(addr string) error {
+	local, domain, ok := strings.Cut(addr, "@")
+	if !ok || local == "" || domain == "" || strings.ContainsAny(addr, " \r\n,") {
+		return errors.New("invalid email payload: malformed address")
+	}
+	return nil
+}
+
+// URL is an http or https link.
+type URL struct {
+	Address string // Absolute http or https URL, required
+
+	// Uppercase writes the scheme and host in uppercase, which are
+	// case-insensitive. If the rest of the URL only uses the QR
+	// alphanumeric character set (0-9, A-Z, space and $%*+-./:), the whole
+	// URL can then be encoded in the denser alphanumeric mode.
+	// Zero value writes the URL unchanged.
+	Uppercase bool
+}
+
+// Encode returns the URL, optionally with uppercase scheme and host.
+func (u *URL) Encode(limit int) (string, error) {
+	parsed, err := parseHTTPURL(u.Address)
+	if err != nil {
+		return "", err
+	}
+
+	s := u.Address
+	if u.Uppercase {
+		// Uppercase by position so userinfo, path and query keep their case
+		n := len(parsed.Scheme) + len("://")
+		rest := s[n:]
+		end := strings.IndexAny(rest, "/?#")
+		if end < 0 {
+			end = len(rest)
+		}
+		host := strings.LastIndex(rest[:end], "@") + 1
+		s = strings.ToUpper(s[:n]) + rest[:host] + strings.ToUpper(rest[host:end]) + rest[end:]
+	}
+	return checkLimit("URL", s, limit)
+}
+
+// Check parses decoded as a URL and compares it with u. Scheme and host
+// compare case-insensitively; all other components must match exactly.
+func (u *URL) Check(decoded string) error {
+	want, err := parseHTTPURL(u.Address)
+	if err != nil {
+		return err
+	}
+	got, err := parseHTTPURL(decoded)
+	if err != nil {
+		return err
+	}
+
+	if err := checkField("URL.Scheme", want.Scheme, got.Scheme); err != nil {
+		return err
+	}
+	if err := checkField("URL.Host", want.Host, got.Host); err != nil {
+		return err
+	}
+	want.Scheme, want.Host, got.Scheme, got.Host = "", "", "", ""
+	return checkField("URL.Address", want.String(), got.String())
+}
+
+// parseHTTPURL parses an absolute http or https URL, lowercasing its
+// scheme and host.
+func parseHTTPURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, errors.New("invalid URL payload: malformed URL")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("invalid URL payload: scheme must be http or https")
+	}
+	if u.Host == "" {
+		return nil, errors.New("invalid URL payload: host is required")
+	}
+	u.Host = strings.ToLower(u.Host)
+	return u, nil
+}
+
+// cutScheme returns s without its scheme and colon if the scheme matches
+// case-insensitively.
+func cutScheme(s, scheme string) (string, bool) {
+	if len(s) <= len(scheme) || s[len(scheme)] != ':' || !strings.EqualFold(s[:len(scheme)], scheme) {
+		return "", false
+	}
+	return s[len(scheme)+1:], true
+}
+
+// uriEscape percent-encodes s for a URI path segment or query value,
+// encoding spaces as %20 as RFC 6068, RFC 5724 and authenticator apps expect.
+func uriEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// checkLimit returns s, or an error naming kind if s exceeds limit bytes.
+// Text the encoder stores in numeric or alphanumeric mode is measured
+// against the character capacity of that mode instead.
+func checkLimit(kind, s string, limit int) (string, error) {
+	mode := encodingMode(s)
+	if n := maxChars(mode, limit); len(s) > n {
+		if mode == modeByte {
+			return "", fmt.Errorf("data too large: %s is %d bytes, exceeds %d byte limit", kind, len(s), n)
+		}
+		return "", fmt.Errorf("data too large: %s is %d characters, exceeds %d %v character limit", kind, len(s), n, mode)
+	}
+	return s, nil
+}
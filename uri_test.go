@@ -0,0 +1,176 @@
+package qrverify
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/boombuler/barcode/qr"
+)
+
+func TestURIPayloadEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload Payload
+		want    string
+	}{
+		{"geo", &Geo{Latitude: 47.3769, Longitude: 8.5417}, "geo:47.3769,8.5417"},
+		{"geo query", &Geo{Latitude: -33.8568, Longitude: 151.2153, Query: "Opera House & Co"}, "geo:-33.8568,151.2153?q=Opera%20House%20%26%20Co"},
+		{"tel", &Phone{Number: "+41 44 668 18 00"}, "tel:+41446681800"},
+		{"tel separators", &Phone{Number: "+1-(555)-0100"}, "tel:+1-(555)-0100"},
+		{"SMSTO", &SMS{Number: "+15550100", Message: "Vote: yes"}, "SMSTO:+15550100:Vote: yes"},
+		{"sms URI", &SMS{Number: "+15550100", Message: "Hi there?", Format: SMSURI}, "sms:+15550100?body=Hi%20there%3F"},
+		{"mailto", &Email{To: "info@example.com"}, "mailto:info@example.com"},
+		{
+			"mailto subject and body",
+			&Email{To: "a+b@example.com", Subject: "Hello & welcome", Body: "Line 1\nLine 2"},
+			"mailto:a+b@example.com?subject=Hello%20%26%20welcome&body=Line%201%0D%0ALine%202",
+		},
+		{"URL", &URL{Address: "https://Example.com/Path?q=A"}, "https://Example.com/Path?q=A"},
+		{"URL uppercase", &URL{Address: "https://example.com/ABC", Uppercase: true}, "HTTPS://EXAMPLE.COM/ABC"},
+		{
+			"URL uppercase keeps userinfo and path",
+			&URL{Address: "http://user@example.com:8080/example.com?x=y#frag", Uppercase: true},
+			"HTTP://user@EXAMPLE.COM:8080/example.com?x=y#frag",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.payload.Encode(MaxBytesMedium)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+			if err := tt.payload.Check(got); err != nil {
+				t.Errorf("Check failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestURIPayloadEncodeInvalid(t *testing.T) {
+	invalid := []Payload{
+		&Geo{Latitude: 91},
+		&Geo{Longitude: -180.5},
+		&Phone{},
+		&Phone{Number: "+"},
+		&Phone{Number: "555-CALL"},
+		&SMS{Number: "12", Format: SMSFormat(7)},
+		&SMS{Number: "abc"},
+		&Email{To: "nobody"},
+		&Email{To: "a@b, c@d"},
+		&URL{Address: "ftp://example.com"},
+		&URL{Address: "https://"},
+		&URL{Address: "example.com"},
+	}
+	for _, p := range invalid {
+		if _, err := p.Encode(MaxBytesMedium); err == nil {
+			t.Errorf("Expected error for %+v", p)
+		}
+	}
+
+	if _, err := (&URL{Address: "https://example.com/" + strings.Repeat("a", 100)}).Encode(50); err == nil ||
+		!strings.Contains(err.Error(), "data too large") {
+		t.Errorf("Expected data too large error, got: %v", err)
+	}
+}
+
+func TestURIPayloadCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload Payload
+		decoded string
+		field   string // Empty if Check should pass
+	}{
+		{"geo altitude ignored", &Geo{Latitude: 1.5, Longitude: 2}, "GEO:1.5,2,100;u=10", ""},
+		{"geo moved", &Geo{Latitude: 1.5, Longitude: 2}, "geo:1.5,2.1", "Geo.Longitude"},
+		{"tel separators ignored", &Phone{Number: "+41 44 668 18 00"}, "tel:+41-44-668-18-00", ""},
+		{"tel wrong digit", &Phone{Number: "+41446681800"}, "tel:+41446681801", "Phone.Number"},
+		{"sms scheme case", &SMS{Number: "123", Message: "hi"}, "smsto:123:hi", ""},
+		{"sms message", &SMS{Number: "123", Message: "hi", Format: SMSURI}, "sms:123?body=ho", "SMS.Message"},
+		{"mailto case", &Email{To: "Info@Example.com"}, "MAILTO:info@example.com", ""},
+		{"mailto to param", &Email{To: "a@example.com", Subject: "x"}, "mailto:?to=a%40example.com&subject=x", ""},
+		{"mailto subject", &Email{To: "a@example.com", Subject: "x"}, "mailto:a@example.com?subject=y", "Email.Subject"},
+		{"URL host case", &URL{Address: "https://example.com/a"}, "HTTPS://EXAMPLE.COM/a", ""},
+		{"URL path case", &URL{Address: "https://example.com/a"}, "https://example.com/A", "URL.Address"},
+		{"URL host", &URL{Address: "https://example.com/a"}, "https://example.org/a", "URL.Host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.payload.Check(tt.decoded)
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("Check failed: %v", err)
+				}
+				return
+			}
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) || fieldErr.Field != tt.field {
+				t.Errorf("Expected FieldError for %s, got: %v", tt.field, err)
+			}
+		})
+	}
+}
+
+func TestURIPayloadParseInvalid(t *testing.T) {
+	if _, err := ParseGeo("geo:1"); err == nil {
+		t.Error("Expected error for geo without longitude")
+	}
+	if _, err := ParseGeo("geo:x,1"); err == nil {
+		t.Error("Expected error for malformed latitude")
+	}
+	if _, err := ParsePhone("tel:"); err == nil {
+		t.Error("Expected error for empty tel")
+	}
+	if _, err := ParseSMS("mms:123"); err == nil {
+		t.Error("Expected error for unknown SMS scheme")
+	}
+	if _, err := ParseEmail("mailto:?subject=x"); err == nil {
+		t.Error("Expected error for mailto without recipient")
+	}
+}
+
+func TestEncodePayloadURLUppercase(t *testing.T) {
+	u := &URL{Address: "https://example.com/ABC123", Uppercase: true}
+
+	upper, err := EncodePayload(u, nil)
+	if err != nil {
+		t.Fatalf("EncodePayload failed: %v", err)
+	}
+	if err := VerifyPayload(upper.Image, u); err != nil {
+		t.Errorf("VerifyPayload failed: %v", err)
+	}
+	if err := VerifyPayload(upper.Image, &URL{Address: "https://example.com/ABC123"}); err != nil {
+		t.Errorf("VerifyPayload failed for lowercase host: %v", err)
+	}
+
+	// The uppercase URL fits alphanumeric mode and a smaller symbol
+	size := func(s string) int {
+		bc, err := qr.Encode(s, qr.M, qr.Auto)
+		if err != nil {
+			t.Fatalf("qr.Encode failed: %v", err)
+		}
+		return bc.Bounds().Dx()
+	}
+	long := &URL{Address: "https://example.com/ABCDEFGHIJ/KLMNOPQRST1234", Uppercase: true}
+	text, err := long.Encode(MaxBytesMedium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if got, plain := size(text), size(long.Address); got >= plain {
+		t.Errorf("Uppercase URL symbol is %d modules, want fewer than %d", got, plain)
+	}
+
+	// Alphanumeric URLs may exceed the byte limit
+	path := strings.Repeat("ABCDEFGHIJ/", 250)
+	if _, err := (&URL{Address: "https://example.com/" + path, Uppercase: true}).Encode(MaxBytesMedium); err != nil {
+		t.Errorf("Encode failed for long uppercase URL: %v", err)
+	}
+	if _, err := (&URL{Address: "https://example.com/" + strings.ToLower(path)}).Encode(MaxBytesMedium); err == nil {
+		t.Error("Expected error for long lowercase URL")
+	}
+}
@@ -2,50 +2,110 @@ package qrverify
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"image"
 	"image/png"
+	"io"
 
 	"github.com/makiuchi-d/gozxing"
-	"github.com/makiuchi-d/gozxing/qrcode"
 )
 
+// symbology decodes one barcode format for the shared encode and verify
+// core.
+type symbology struct {
+	name   string                                // Used in error messages, such as "QR code"
+	decode func(img image.Image) (string, error) // Reads the symbol's text from an image
+}
+
+// qrSymbology reads QR codes.
+var qrSymbology = symbology{name: "QR code", decode: decode}
+
 // decode reads a QR code from an image. Internal use only.
 // Always uses TRY_HARDER hint for maximum accuracy.
 func decode(img image.Image) (string, error) {
-	// Convert image to BinaryBitmap
-	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	reader := getReader()
+	defer putReader(reader)
+	result, err := readBarcode(img, reader, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode QR code: %w", err)
+	}
+
+	return result.GetText(), nil
+}
+
+// readBarcode decodes img with reader, adding the TRY_HARDER hint to hints.
+func readBarcode(img image.Image, reader gozxing.Reader, hints map[gozxing.DecodeHintType]interface{}) (*gozxing.Result, error) {
+	// Convert image to BinaryBitmap backed by a pooled luminance buffer
+	lum := luminance(img)
+	defer putLum(lum)
+
+	b := img.Bounds()
+	src, err := gozxing.NewPlanarYUVLuminanceSource(*lum, b.Dx(), b.Dy(), 0, 0, b.Dx(), b.Dy(), false)
 	if err != nil {
-		return "", fmt.Errorf("failed to create bitmap: %w", err)
+		return nil, fmt.Errorf("failed to create bitmap: %w", err)
+	}
+	bmp, err := gozxing.NewBinaryBitmap(gozxing.NewHybridBinarizer(src))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bitmap: %w", err)
 	}
 
 	// Set up hints with TRY_HARDER
-	hints := make(map[gozxing.DecodeHintType]interface{})
+	if hints == nil {
+		hints = make(map[gozxing.DecodeHintType]interface{})
+	}
 	hints[gozxing.DecodeHintType_TRY_HARDER] = true
 
-	// Decode
-	reader := qrcode.NewQRCodeReader()
-	result, err := reader.Decode(bmp, hints)
-	if err != nil {
-		return "", fmt.Errorf("failed to decode QR code: %w", err)
+	return reader.Decode(bmp, hints)
+}
+
+// luminance converts img to 8-bit luminance in a pooled buffer, matching
+// gozxing.NewLuminanceSourceFromImage. Greyscale images are copied directly.
+func luminance(img image.Image) *[]byte {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	lum := getLum(w * h)
+	buf := *lum
+
+	if g, ok := img.(*image.Gray); ok {
+		for y := 0; y < h; y++ {
+			off := g.PixOffset(b.Min.X, b.Min.Y+y)
+			copy(buf[y*w:(y+1)*w], g.Pix[off:off+w])
+		}
+		return lum
 	}
 
-	return result.GetText(), nil
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := img.At(x, y).RGBA()
+			l := (r + 2*g + bl) * 255 / (4 * 0xffff)
+			buf[i] = byte((l*a + (0xffff-a)*255) / 0xffff)
+			i++
+		}
+	}
+	return lum
 }
 
-// Verify checks that qrImage (PNG bytes) decodes to expectedData.
-// Returns nil on success, VerificationError if mismatch, or error if decode fails.
-func Verify(qrImage []byte, expectedData string) error {
-	// Decode PNG
-	img, err := png.Decode(bytes.NewReader(qrImage))
-	if err != nil {
-		return fmt.Errorf("failed to decode PNG: %w", err)
+// verifyImage checks that img decodes to expectedData. If check is non-nil,
+// it is also called with the decoded text for semantic verification.
+func verifyImage(ctx context.Context, img image.Image, expectedData string, check func(decoded string) error) error {
+	return verifySymbol(ctx, img, expectedData, qrSymbology, check)
+}
+
+// verifySymbol is like verifyImage for any symbology.
+func verifySymbol(ctx context.Context, img image.Image, expectedData string, sym symbology, check func(decoded string) error) error {
+	if err := canceled(ctx, "decode"); err != nil {
+		return err
 	}
 
-	// Decode QR
-	decoded, err := decode(img)
+	decoded, err := sym.decode(img)
 	if err != nil {
-		return fmt.Errorf("failed to read QR code: %w", err)
+		return fmt.Errorf("failed to read %s: %w", sym.name, err)
+	}
+
+	if err := canceled(ctx, "verify"); err != nil {
+		return err
 	}
 
 	// Strict byte-for-byte comparison
@@ -56,5 +116,48 @@ func Verify(qrImage []byte, expectedData string) error {
 		}
 	}
 
+	if check != nil {
+		return check(decoded)
+	}
+
 	return nil
 }
+
+// Verify checks that qrImage (PNG bytes) decodes to expectedData.
+// Returns nil on success, VerificationError if mismatch, or error if decode fails.
+func Verify(qrImage []byte, expectedData string) error {
+	return VerifyContext(context.Background(), qrImage, expectedData)
+}
+
+// VerifyContext is like Verify but honors cancellation and deadlines of ctx.
+// If ctx is done before decoding completes, the returned error wraps ctx.Err().
+func VerifyContext(ctx context.Context, qrImage []byte, expectedData string) error {
+	if err := canceled(ctx, "verify"); err != nil {
+		return err
+	}
+
+	return verifyReader(ctx, bytes.NewReader(qrImage), expectedData, nil)
+}
+
+// VerifyFrom checks that the PNG image read from r decodes to expectedData.
+// Returns nil on success, VerificationError if mismatch, or error if decode fails.
+func VerifyFrom(r io.Reader, expectedData string) error {
+	return verifyReader(context.Background(), r, expectedData, nil)
+}
+
+// verifyReader decodes a PNG image from r and verifies it. check is passed
+// to verifyImage.
+func verifyReader(ctx context.Context, r io.Reader, expectedData string, check func(string) error) error {
+	return verifySymbolReader(ctx, r, expectedData, qrSymbology, check)
+}
+
+// verifySymbolReader is like verifyReader for any symbology.
+func verifySymbolReader(ctx context.Context, r io.Reader, expectedData string, sym symbology, check func(string) error) error {
+	// Decode PNG
+	img, err := png.Decode(r)
+	if err != nil {
+		return fmt.Errorf("failed to decode PNG: %w", err)
+	}
+
+	return verifySymbol(ctx, img, expectedData, sym, check)
+}
@@ -2,6 +2,7 @@ package qrverify
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"image"
 	"image/color"
@@ -369,3 +370,49 @@ func TestVerify_EdgeCases(t *testing.T) {
 		}
 	})
 }
+
+// TestVerifyContext tests that VerifyContext honors cancellation
+func TestVerifyContext(t *testing.T) {
+	png, err := generateTestQR("context data", qr.M, 256)
+	if err != nil {
+		t.Fatalf("failed to generate test QR: %v", err)
+	}
+
+	if err := VerifyContext(context.Background(), png, "context data"); err != nil {
+		t.Errorf("VerifyContext failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = VerifyContext(ctx, png, "context data")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got: %v", err)
+	}
+
+	var verErr *VerificationError
+	if errors.As(err, &verErr) {
+		t.Error("Cancellation should not be reported as VerificationError")
+	}
+}
+
+// TestVerifyFrom tests verification from an io.Reader
+func TestVerifyFrom(t *testing.T) {
+	png, err := generateTestQR("reader data", qr.M, 256)
+	if err != nil {
+		t.Fatalf("failed to generate test QR: %v", err)
+	}
+
+	if err := VerifyFrom(bytes.NewReader(png), "reader data"); err != nil {
+		t.Errorf("VerifyFrom failed: %v", err)
+	}
+
+	var verErr *VerificationError
+	if err := VerifyFrom(bytes.NewReader(png), "other data"); !errors.As(err, &verErr) {
+		t.Errorf("Expected VerificationError, got: %v", err)
+	}
+
+	if err := VerifyFrom(bytes.NewReader([]byte("not a PNG image")), "reader data"); err == nil {
+		t.Error("Expected error for invalid PNG, got nil")
+	}
+}
@@ -0,0 +1,185 @@
+package qrverify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// WiFiAuth specifies the Wi-Fi network authentication type.
+type WiFiAuth int
+
+const (
+	WPA    WiFiAuth = iota // WPA/WPA2/WPA3 personal (default)
+	WEP                    // WEP
+	NoPass                 // Open network
+)
+
+// String returns the auth type as written in the WIFI: payload.
+func (a WiFiAuth) String() string {
+	switch a {
+	case WPA:
+		return "WPA"
+	case WEP:
+		return "WEP"
+	case NoPass:
+		return "nopass"
+	default:
+		return "WiFiAuth(unknown)"
+	}
+}
+
+// WiFi is a Wi-Fi network configuration payload in the
+// WIFI:T:<auth>;S:<ssid>;P:<password>;H:<hidden>;; format.
+type WiFi struct {
+	SSID     string   // Network name, required
+	Password string   // Ignored for NoPass
+	Auth     WiFiAuth // Authentication type
+	Hidden   bool     // Network does not broadcast its SSID
+}
+
+// Encode returns the escaped WIFI: payload.
+func (w *WiFi) Encode(limit int) (string, error) {
+	if w.SSID == "" {
+		return "", errors.New("invalid WiFi payload: SSID is required")
+	}
+	if w.Auth < WPA || w.Auth > NoPass {
+		return "", fmt.Errorf("invalid WiFi payload: unknown auth type %d", w.Auth)
+	}
+	if w.Auth != NoPass && w.Password == "" {
+		return "", fmt.Errorf("invalid WiFi payload: password is required for %v", w.Auth)
+	}
+
+	var b strings.Builder
+	b.WriteString("WIFI:T:")
+	b.WriteString(w.Auth.String())
+	b.WriteString(";S:")
+	b.WriteString(wifiEscape(w.SSID))
+	b.WriteString(";")
+	if w.Auth != NoPass {
+		b.WriteString("P:")
+		b.WriteString(wifiEscape(w.Password))
+		b.WriteString(";")
+	}
+	if w.Hidden {
+		b.WriteString("H:true;")
+	}
+	b.WriteString(";")
+	return b.String(), nil
+}
+
+// Check parses decoded as a WIFI: payload and compares its fields with w.
+func (w *WiFi) Check(decoded string) error {
+	got, err := ParseWiFi(decoded)
+	if err != nil {
+		return err
+	}
+
+	if err := checkField("WiFi.SSID", w.SSID, got.SSID); err != nil {
+		return err
+	}
+	if err := checkField("WiFi.Auth", w.Auth.String(), got.Auth.String()); err != nil {
+		return err
+	}
+	if w.Auth != NoPass {
+		if err := checkField("WiFi.Password", w.Password, got.Password); err != nil {
+			return err
+		}
+	}
+	return checkField("WiFi.Hidden", fmt.Sprint(w.Hidden), fmt.Sprint(got.Hidden))
+}
+
+// ParseWiFi parses a WIFI: payload.
+func ParseWiFi(s string) (*WiFi, error) {
+	body, ok := strings.CutPrefix(s, "WIFI:")
+	if !ok {
+		return nil, errors.New("invalid WiFi payload: missing WIFI: prefix")
+	}
+
+	fields, err := splitEscaped(body, ';')
+	if err != nil {
+		return nil, fmt.Errorf("invalid WiFi payload: %w", err)
+	}
+
+	w := &WiFi{}
+	seenSSID := false
+	for _, f := range fields {
+		if f == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(f, ":")
+		if !ok {
+			return nil, errors.New("invalid WiFi payload: field without key")
+		}
+		value = unescapeBackslash(value)
+
+		switch key {
+		case "T":
+			switch strings.ToUpper(value) {
+			case "WPA", "WPA2", "WPA3", "SAE":
+				w.Auth = WPA
+			case "WEP":
+				w.Auth = WEP
+			case "NOPASS", "":
+				w.Auth = NoPass
+			default:
+				return nil, errors.New("invalid WiFi payload: unknown auth type")
+			}
+		case "S":
+			w.SSID = value
+			seenSSID = true
+		case "P":
+			w.Password = value
+		case "H":
+			w.Hidden = strings.EqualFold(value, "true")
+		}
+	}
+
+	if !seenSSID {
+		return nil, errors.New("invalid WiFi payload: missing SSID")
+	}
+	return w, nil
+}
+
+// wifiEscaper escapes the characters reserved by the WIFI: format.
+var wifiEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, `:`, `\:`, `"`, `\"`)
+
+// wifiEscape escapes s for use as a WIFI: field value.
+func wifiEscape(s string) string {
+	return wifiEscaper.Replace(s)
+}
+
+// splitEscaped splits s on sep, ignoring separators preceded by a backslash.
+// Escape sequences are kept in the returned fields.
+func splitEscaped(s string, sep byte) ([]string, error) {
+	var fields []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 >= len(s) {
+				return nil, errors.New("dangling escape")
+			}
+			i++
+		case sep:
+			fields = append(fields, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(fields, s[start:]), nil
+}
+
+// unescapeBackslash removes backslash escapes from s.
+func unescapeBackslash(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,120 @@
+package qrverify
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWiFiEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		wifi WiFi
+		want string
+	}{
+		{
+			name: "WPA",
+			wifi: WiFi{SSID: "Guest", Password: "secret123"},
+			want: "WIFI:T:WPA;S:Guest;P:secret123;;",
+		},
+		{
+			name: "special characters escaped",
+			wifi: WiFi{SSID: `Cafe;Bar:1\2`, Password: `p,a"ss`},
+			want: `WIFI:T:WPA;S:Cafe\;Bar\:1\\2;P:p\,a\"ss;;`,
+		},
+		{
+			name: "open hidden network",
+			wifi: WiFi{SSID: "Lobby", Auth: NoPass, Hidden: true},
+			want: "WIFI:T:nopass;S:Lobby;H:true;;",
+		},
+		{
+			name: "WEP",
+			wifi: WiFi{SSID: "Old", Password: "abcde", Auth: WEP},
+			want: "WIFI:T:WEP;S:Old;P:abcde;;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.wifi.Encode(MaxBytesMedium)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+
+			parsed, err := ParseWiFi(got)
+			if err != nil {
+				t.Fatalf("ParseWiFi failed: %v", err)
+			}
+			if *parsed != tt.wifi {
+				t.Errorf("ParseWiFi() = %+v, want %+v", *parsed, tt.wifi)
+			}
+		})
+	}
+}
+
+func TestWiFiEncodeInvalid(t *testing.T) {
+	invalid := []WiFi{
+		{Password: "x"},
+		{SSID: "net"},
+		{SSID: "net", Auth: WiFiAuth(9), Password: "x"},
+	}
+	for _, w := range invalid {
+		if _, err := w.Encode(MaxBytesMedium); err == nil {
+			t.Errorf("Expected error for %+v", w)
+		}
+	}
+}
+
+func TestParseWiFiInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"T:WPA;S:net;;",
+		"WIFI:T:WPA;P:x;;",
+		"WIFI:T:XYZ;S:net;;",
+		`WIFI:S:net\`,
+	}
+	for _, s := range invalid {
+		if _, err := ParseWiFi(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestWiFiCheck(t *testing.T) {
+	w := &WiFi{SSID: "Guest", Password: "hunter2"}
+
+	// Field order and auth spelling do not matter
+	if err := w.Check("WIFI:S:Guest;T:WPA2;P:hunter2;;"); err != nil {
+		t.Errorf("Check failed: %v", err)
+	}
+
+	err := w.Check("WIFI:T:WPA;S:Guest;P:wrong;;")
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "WiFi.Password" {
+		t.Fatalf("Expected FieldError for WiFi.Password, got: %v", err)
+	}
+	if strings.Contains(err.Error(), "hunter2") || strings.Contains(err.Error(), "wrong") {
+		t.Errorf("Error() exposes field content: %s", err.Error())
+	}
+}
+
+func TestEncodePayloadWiFi(t *testing.T) {
+	w := &WiFi{SSID: `My;Net`, Password: `pa:ss\word`}
+
+	result, err := EncodePayload(w, nil)
+	if err != nil {
+		t.Fatalf("EncodePayload failed: %v", err)
+	}
+
+	if err := VerifyPayload(result.Image, w); err != nil {
+		t.Errorf("VerifyPayload failed: %v", err)
+	}
+
+	other := &WiFi{SSID: "My;Net", Password: "different"}
+	if err := VerifyPayload(result.Image, other); err == nil {
+		t.Error("Expected VerifyPayload to fail for different password")
+	}
+}